@@ -118,6 +118,12 @@ func Debug(detail string) {
 	log(LevelDebug, noWrite, fmt.Sprintf("%s%s%s %s%s%s %s", LightWhite("["), LightWhite("?"), LightWhite("]"), LightWhite("["), Yellow(getCallerInfo(2)), LightWhite("]"), detail))
 }
 
+// DebugSkip 与 Debug 相同，调用位置向上多跳过 skip 层，供封装 Debug 的函数使用
+func DebugSkip(skip int, detail string) {
+	noWrite = 1
+	log(LevelDebug, noWrite, fmt.Sprintf("%s%s%s %s%s%s %s", LightWhite("["), LightWhite("?"), LightWhite("]"), LightWhite("["), Yellow(getCallerInfo(2+skip)), LightWhite("]"), detail))
+}
+
 func Verbose(detail string) {
 	noWrite = 1
 	log(LevelVerbose, noWrite, fmt.Sprintf("%s%s%s %s", LightWhite("["), LightCyan("i"), LightWhite("]"), detail))
@@ -0,0 +1,630 @@
+package core
+
+import (
+	log "Sowhp/concert/logger"
+	"Sowhp/scripts"
+	"Sowhp/sowhp"
+	"context"
+	"crypto/subtle"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"maps"
+	"net"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// 任务状态
+const (
+	jobQueued   = "queued"
+	jobRunning  = "running"
+	jobDone     = "done"
+	jobCanceled = "canceled"
+	jobFailed   = "failed"
+)
+
+const (
+	// jobRunName 为任务运行目录的命名模板，运行目录名即任务 ID
+	jobRunName = "job_{date}{index}"
+	// maxFinishedJobs 为内存中保留的已结束任务数，超出后最早结束的任务不再可查询，运行目录保留在磁盘上
+	maxFinishedJobs = 1000
+	// maxJobRequestBytes 为提交任务的请求体大小上限
+	maxJobRequestBytes = 10 << 20
+)
+
+// jobRequest 为提交任务的请求体
+type jobRequest struct {
+	URLs     []string   `json:"urls"`
+	KeepPath bool       `json:"keep_path"`
+	Options  jobOptions `json:"options"`
+}
+
+// jobOptions 为单个任务可覆盖的选项，未指定的项使用启动 api 时的参数；
+// 浏览器池在任务之间共享，代理与 Chrome 参数只能在启动 api 时指定
+type jobOptions struct {
+	Headers     map[string]string `json:"headers"`
+	UserAgent   string            `json:"user_agent"`
+	Viewport    string            `json:"viewport"`
+	Timeout     int               `json:"timeout"`
+	HTTPTimeout int               `json:"http_timeout"`
+	Retry       *int              `json:"retry"`
+	Both        bool              `json:"both"`
+}
+
+// jobStatus 为任务状态接口返回的内容
+type jobStatus struct {
+	ID         string     `json:"id"`
+	State      string     `json:"state"`
+	Total      int        `json:"total"`
+	Processed  int        `json:"processed"`
+	Succeeded  int        `json:"succeeded"`
+	Failed     int        `json:"failed"`
+	OutOfScope int        `json:"out_of_scope"`
+	Duplicates int        `json:"duplicates"`
+	Error      string     `json:"error,omitempty"`
+	CreatedAt  time.Time  `json:"created_at"`
+	StartedAt  *time.Time `json:"started_at,omitempty"`
+	FinishedAt *time.Time `json:"finished_at,omitempty"`
+}
+
+// jobResult 为结果接口返回的单条结果，附带截图的访问地址
+type jobResult struct {
+	scripts.Result
+	ImageURL string `json:"image_url,omitempty"`
+}
+
+type job struct {
+	mu      sync.Mutex
+	status  jobStatus
+	dir     string
+	options sowhp.Options
+	ctx     context.Context
+	cancel  context.CancelFunc
+}
+
+func (j *job) snapshot() jobStatus {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	return j.status
+}
+
+// finish 结束任务，返回 false 表示任务已经结束
+func (j *job) finish(state, message string) bool {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	if j.status.FinishedAt != nil {
+		return false
+	}
+	now := time.Now()
+	j.status.State = state
+	j.status.Error = message
+	j.status.FinishedAt = &now
+	return true
+}
+
+// apiServer 接收截图任务并排队执行，任务结果写入 -output-dir 下以任务 ID 命名的运行目录
+type apiServer struct {
+	app    *App
+	ctx    context.Context
+	cancel context.CancelFunc
+	queue  chan *job
+	wg     sync.WaitGroup
+	mu     sync.Mutex
+	jobs   map[string]*job
+	// reserved 为已占用队列名额、正在创建运行目录的任务数
+	reserved int
+	// finishedIDs 按结束顺序记录任务 ID
+	finishedIDs []string
+}
+
+func newAPIServer(app *App) *apiServer {
+	ctx, cancel := context.WithCancel(context.Background())
+	s := &apiServer{
+		app:    app,
+		ctx:    ctx,
+		cancel: cancel,
+		queue:  make(chan *job, app.config.QueueSize),
+		jobs:   make(map[string]*job),
+	}
+	for i := 0; i < app.config.JobWorkers; i++ {
+		s.wg.Add(1)
+		go s.worker()
+	}
+	return s
+}
+
+// close 取消排队中与执行中的任务，等待执行中的任务写完结果与报告
+func (s *apiServer) close() {
+	s.cancel()
+	s.wg.Wait()
+}
+
+func (s *apiServer) handler() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /api/health", s.handleHealth)
+	mux.HandleFunc("POST /api/jobs", s.handleSubmit)
+	mux.HandleFunc("GET /api/jobs", s.handleJobs)
+	mux.HandleFunc("GET /api/jobs/{id}", s.handleJob)
+	mux.HandleFunc("DELETE /api/jobs/{id}", s.handleCancel)
+	mux.HandleFunc("GET /api/jobs/{id}/results", s.handleResults)
+	mux.HandleFunc("GET /api/jobs/{id}/images/{file}", s.handleImage)
+	return s.authorize(mux)
+}
+
+// authorize 校验 Authorization: Bearer <token>，也接受 ?token= 以便直接在页面中引用截图；/api/health 无需认证
+func (s *apiServer) authorize(next http.Handler) http.Handler {
+	token := s.app.config.Token
+	if token == "" {
+		return next
+	}
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/api/health" {
+			next.ServeHTTP(w, r)
+			return
+		}
+		provided := r.URL.Query().Get("token")
+		if bearer, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer "); ok {
+			provided = strings.TrimSpace(bearer)
+		}
+		if subtle.ConstantTimeCompare([]byte(provided), []byte(token)) != 1 {
+			w.Header().Set("WWW-Authenticate", `Bearer realm="sowhp"`)
+			scripts.WriteError(w, http.StatusUnauthorized, "认证失败")
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+func (s *apiServer) handleHealth(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	counts := make(map[string]int)
+	for _, j := range s.jobs {
+		counts[j.snapshot().State]++
+	}
+	s.mu.Unlock()
+
+	health := map[string]interface{}{
+		"status":      "ok",
+		"queued":      counts[jobQueued],
+		"running":     counts[jobRunning],
+		"queue_limit": cap(s.queue),
+	}
+	if pool, ok := s.app.options.Capturer.(*sowhp.BrowserPool); ok {
+		health["browser_tabs"] = pool.Size()
+	}
+	scripts.WriteJSON(w, http.StatusOK, health)
+}
+
+func (s *apiServer) handleSubmit(w http.ResponseWriter, r *http.Request) {
+	var request jobRequest
+	decoder := json.NewDecoder(http.MaxBytesReader(w, r.Body, maxJobRequestBytes))
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(&request); err != nil {
+		scripts.WriteError(w, http.StatusBadRequest, fmt.Sprintf("请求格式错误: %v", err))
+		return
+	}
+	if len(request.URLs) == 0 {
+		scripts.WriteError(w, http.StatusBadRequest, "urls 不能为空")
+		return
+	}
+	if len(request.URLs) > s.app.config.MaxURLs {
+		scripts.WriteError(w, http.StatusRequestEntityTooLarge, fmt.Sprintf("单个任务最多 %d 个地址", s.app.config.MaxURLs))
+		return
+	}
+
+	options := s.app.options
+	if err := request.Options.apply(&options); err != nil {
+		scripts.WriteError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	// 先占用队列名额再创建运行目录，队列已满时不写入任何文件
+	s.mu.Lock()
+	if len(s.queue)+s.reserved >= cap(s.queue) {
+		s.mu.Unlock()
+		w.Header().Set("Retry-After", "10")
+		scripts.WriteError(w, http.StatusTooManyRequests, "任务队列已满，请稍后重试")
+		return
+	}
+	s.reserved++
+	s.mu.Unlock()
+
+	j, err := s.createJob(request, options)
+	s.mu.Lock()
+	s.reserved--
+	if err != nil {
+		s.mu.Unlock()
+		scripts.WriteError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	// 只有持有名额的请求会写入队列，此处不会阻塞
+	s.queue <- j
+	s.jobs[j.status.ID] = j
+	s.mu.Unlock()
+
+	status := j.snapshot()
+	log.Info(fmt.Sprintf("任务 %s 已提交，共 %d 个地址", status.ID, status.Total))
+	w.Header().Set("Location", "/api/jobs/"+status.ID)
+	scripts.WriteJSON(w, http.StatusAccepted, status)
+}
+
+// apply 将任务选项覆盖到 options 上
+func (o jobOptions) apply(options *sowhp.Options) error {
+	if len(o.Headers) > 0 {
+		headers := maps.Clone(options.Headers)
+		if headers == nil {
+			headers = make(map[string]string)
+		}
+		for name, value := range o.Headers {
+			if strings.TrimSpace(name) == "" {
+				return errors.New("请求头名称不能为空")
+			}
+			headers[name] = value
+		}
+		options.Headers = headers
+	}
+	if o.UserAgent != "" {
+		options.UserAgent = o.UserAgent
+	}
+	if o.Viewport != "" {
+		width, height, err := parseViewport(o.Viewport)
+		if err != nil {
+			return err
+		}
+		options.ViewportWidth, options.ViewportHeight = width, height
+	}
+	if o.Timeout < 0 || o.HTTPTimeout < 0 {
+		return errors.New("超时时间不能为负数")
+	}
+	if o.Timeout > 0 {
+		options.PageTimeout = time.Duration(o.Timeout) * time.Second
+	}
+	if o.HTTPTimeout > 0 {
+		options.HTTPTimeout = time.Duration(o.HTTPTimeout) * time.Second
+	}
+	if o.Retry != nil {
+		if *o.Retry < 0 {
+			return errors.New("重试次数不能为负数")
+		}
+		retry := *options.Retry
+		retry.Retries = *o.Retry
+		options.Retry = &retry
+	}
+	if o.Both {
+		options.CaptureBoth = true
+	}
+	return nil
+}
+
+// createJob 创建任务运行目录并写入去重、范围过滤后的地址列表
+func (s *apiServer) createJob(request jobRequest, options sowhp.Options) (*job, error) {
+	runDir, err := scripts.CreateRunDir(s.app.config.OutputDir, jobRunName)
+	if err != nil {
+		return nil, err
+	}
+
+	stats, err := writeInputs(runDir, request.KeepPath, options.Scope, func(add func(string) error) error {
+		for _, url := range request.URLs {
+			if url = strings.TrimSpace(url); url != "" {
+				if err := add(url); err != nil {
+					return err
+				}
+			}
+		}
+		return nil
+	})
+	if err == nil && stats.total == 0 {
+		err = errors.New("去重与范围过滤后没有可处理的 URL")
+	}
+	if err != nil {
+		os.RemoveAll(runDir)
+		return nil, err
+	}
+
+	id := filepath.Base(runDir)
+	options.OutputDir = runDir
+	options.Name = id
+	if stats.total < options.Threads {
+		options.Threads = stats.total
+	}
+	if stats.total < options.AliveThreads {
+		options.AliveThreads = stats.total
+	}
+
+	ctx, cancel := context.WithCancel(s.ctx)
+	return &job{
+		status: jobStatus{
+			ID:         id,
+			State:      jobQueued,
+			Total:      stats.total,
+			OutOfScope: stats.outOfScope,
+			Duplicates: stats.duplicates,
+			CreatedAt:  time.Now(),
+		},
+		dir:     runDir,
+		options: options,
+		ctx:     ctx,
+		cancel:  cancel,
+	}, nil
+}
+
+func (s *apiServer) worker() {
+	defer s.wg.Done()
+	for {
+		select {
+		case j := <-s.queue:
+			s.runJob(j)
+		case <-s.ctx.Done():
+			return
+		}
+	}
+}
+
+func (s *apiServer) runJob(j *job) {
+	defer s.finished(j)
+
+	j.mu.Lock()
+	if j.status.State != jobQueued {
+		j.mu.Unlock()
+		return
+	}
+	now := time.Now()
+	j.status.State = jobRunning
+	j.status.StartedAt = &now
+	j.mu.Unlock()
+	log.Info(fmt.Sprintf("任务 %s 开始执行", j.status.ID))
+
+	store, err := scripts.OpenResultStore(j.dir)
+	if err != nil {
+		j.finish(jobFailed, err.Error())
+		return
+	}
+
+	client := sowhp.New(j.options)
+	urlChan := make(chan string, j.options.Threads)
+	resultChan := client.CaptureBatches(j.ctx, urlChan)
+	go func() {
+		defer close(urlChan)
+		err := scripts.ScanInputList(j.dir, func(url string) error {
+			select {
+			case urlChan <- url:
+				return nil
+			case <-j.ctx.Done():
+				return j.ctx.Err()
+			}
+		})
+		if err != nil && j.ctx.Err() == nil {
+			log.Warning(fmt.Sprintf("任务 %s 读取地址列表失败: %v", j.status.ID, err))
+		}
+	}()
+
+	for results := range resultChan {
+		records := make([]scripts.Result, 0, len(results))
+		j.mu.Lock()
+		j.status.Processed++
+		for _, result := range results {
+			records = append(records, *result)
+			switch {
+			case result.Success():
+				j.status.Succeeded++
+			case !result.NotProcessed():
+				j.status.Failed++
+			}
+		}
+		j.mu.Unlock()
+
+		if err := store.Append(records...); err != nil {
+			log.Warning(err.Error())
+		}
+	}
+	store.Close()
+
+	if err := scripts.CreateRunReports(j.dir, s.app.formats, s.app.reportOpts); err != nil {
+		log.Warning(fmt.Sprintf("任务 %s 生成报告失败: %v", j.status.ID, err))
+	}
+
+	if j.ctx.Err() != nil {
+		j.finish(jobCanceled, "")
+		log.Warning(fmt.Sprintf("任务 %s 已取消", j.status.ID))
+		return
+	}
+	j.finish(jobDone, "")
+	status := j.snapshot()
+	log.Info(fmt.Sprintf("任务 %s 完成，成功 %d 个，失败 %d 个", status.ID, status.Succeeded, status.Failed))
+}
+
+// finished 记录已结束的任务，超出 maxFinishedJobs 时从内存中移除最早结束的任务
+func (s *apiServer) finished(j *job) {
+	j.cancel()
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.finishedIDs = append(s.finishedIDs, j.status.ID)
+	for len(s.finishedIDs) > maxFinishedJobs {
+		delete(s.jobs, s.finishedIDs[0])
+		s.finishedIDs = s.finishedIDs[1:]
+	}
+}
+
+func (s *apiServer) lookup(w http.ResponseWriter, r *http.Request) *job {
+	s.mu.Lock()
+	j, ok := s.jobs[r.PathValue("id")]
+	s.mu.Unlock()
+	if !ok {
+		scripts.WriteError(w, http.StatusNotFound, "任务不存在")
+		return nil
+	}
+	return j
+}
+
+func (s *apiServer) handleJobs(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	statuses := make([]jobStatus, 0, len(s.jobs))
+	for _, j := range s.jobs {
+		statuses = append(statuses, j.snapshot())
+	}
+	s.mu.Unlock()
+
+	sort.Slice(statuses, func(i, k int) bool {
+		return statuses[i].CreatedAt.After(statuses[k].CreatedAt)
+	})
+	scripts.WriteJSON(w, http.StatusOK, statuses)
+}
+
+func (s *apiServer) handleJob(w http.ResponseWriter, r *http.Request) {
+	if j := s.lookup(w, r); j != nil {
+		scripts.WriteJSON(w, http.StatusOK, j.snapshot())
+	}
+}
+
+// handleCancel 取消任务：排队中的任务直接结束，执行中的任务未处理的地址记录为 NOT_PROCESSED
+func (s *apiServer) handleCancel(w http.ResponseWriter, r *http.Request) {
+	j := s.lookup(w, r)
+	if j == nil {
+		return
+	}
+
+	if j.snapshot().State == jobQueued && j.finish(jobCanceled, "") {
+		log.Warning(fmt.Sprintf("任务 %s 已取消", j.status.ID))
+	}
+	j.cancel()
+	scripts.WriteJSON(w, http.StatusOK, j.snapshot())
+}
+
+// handleResults 返回任务已完成的结果，执行中的任务返回目前为止的结果
+func (s *apiServer) handleResults(w http.ResponseWriter, r *http.Request) {
+	j := s.lookup(w, r)
+	if j == nil {
+		return
+	}
+
+	status := j.snapshot()
+	results := make([]jobResult, 0, status.Processed)
+	err := scripts.ScanResults(j.dir, func(result scripts.Result) error {
+		item := jobResult{Result: result}
+		if name, ok := strings.CutPrefix(result.Screenshot, "data/"); ok {
+			item.ImageURL = fmt.Sprintf("/api/jobs/%s/images/%s", status.ID, name)
+		}
+		results = append(results, item)
+		return nil
+	})
+	if err != nil {
+		scripts.WriteError(w, http.StatusInternalServerError, fmt.Sprintf("读取结果失败: %v", err))
+		return
+	}
+	scripts.WriteJSON(w, http.StatusOK, map[string]interface{}{
+		"job":     status,
+		"results": results,
+	})
+}
+
+func (s *apiServer) handleImage(w http.ResponseWriter, r *http.Request) {
+	j := s.lookup(w, r)
+	if j == nil {
+		return
+	}
+
+	file := r.PathValue("file")
+	if file != filepath.Base(file) || !strings.HasSuffix(file, ".png") {
+		scripts.WriteError(w, http.StatusNotFound, "截图不存在")
+		return
+	}
+	path := filepath.Join(j.dir, "data", file)
+	if _, err := os.Stat(path); err != nil {
+		scripts.WriteError(w, http.StatusNotFound, "截图不存在")
+		return
+	}
+	http.ServeFile(w, r, path)
+}
+
+// defineAPIFlags 注册 api 子命令特有的参数
+func (app *App) defineAPIFlags() {
+	fs := app.flags
+	fs.StringVar(&app.config.Listen, "listen", "127.0.0.1:8090", "监听地址（可选参数，默认值: 127.0.0.1:8090）\n\t\t示例: -listen 0.0.0.0:8090")
+	fs.StringVar(&app.config.Token, "token", "", "接口认证令牌，请求需携带 Authorization: Bearer <令牌>，也可通过环境变量 SOWHP_TOKEN 指定（可选参数）\n\t\t示例: -token s3cret")
+	fs.IntVar(&app.config.QueueSize, "queue", 100, "排队中的任务数上限，队列已满时提交任务返回 429（可选参数，默认值: 100）\n\t\t示例: -queue 20")
+	fs.IntVar(&app.config.JobWorkers, "jobs", 2, "同时执行的任务数，每个任务的截图并发数由 -t 指定（可选参数，默认值: 2）\n\t\t示例: -jobs 4")
+	fs.IntVar(&app.config.MaxURLs, "max-urls", 10000, "单个任务的地址数上限（可选参数，默认值: 10000）\n\t\t示例: -max-urls 1000")
+	fs.IntVar(&app.config.Browsers, "browsers", 2, "本地 Chrome 引擎预先启动并复用的浏览器数量（可选参数，默认值: 2）\n\t\t示例: -browsers 4")
+	fs.IntVar(&app.config.Tabs, "tabs", 4, "每个预启动的浏览器同时打开的标签页数（可选参数，默认值: 4）\n\t\t示例: -tabs 8")
+}
+
+// runAPI 为 api 子命令：启动 REST 接口接收截图任务
+func runAPI(args []string) error {
+	app := newCommandApp("api")
+	if err := app.flags.Parse(args); err != nil {
+		return parseError(err)
+	}
+	if err := app.parseAPIFlags(); err != nil {
+		app.flags.Usage()
+		log.Error(err.Error())
+		return err
+	}
+	if app.config.PrintConfig {
+		app.printConfig()
+		return nil
+	}
+
+	if check := scripts.CheckWritable(app.config.OutputDir); !check.OK {
+		err := fmt.Errorf("%s: %s", check.Name, check.Detail)
+		log.Error(err.Error())
+		return err
+	}
+
+	if _, ok := app.options.Capturer.(sowhp.ChromeCapturer); ok {
+		log.Info(fmt.Sprintf("正在启动浏览器池: %d 个浏览器，每个 %d 个标签页", app.config.Browsers, app.config.Tabs))
+		pool, err := sowhp.NewBrowserPool(app.config.Browsers, app.config.Tabs, app.options)
+		if err != nil {
+			log.Error(fmt.Sprintf("%v，请运行 sowhp doctor 查看详情", err))
+			return err
+		}
+		defer pool.Close()
+		app.options.Capturer = pool
+	}
+
+	if app.config.Token == "" && !isLoopback(app.config.Listen) {
+		log.Warning("未设置 -token，任何能访问该地址的人都可以提交任务")
+	}
+
+	server := newAPIServer(app)
+	defer server.close()
+	return serveUntilSignal(&http.Server{
+		Addr:              app.config.Listen,
+		Handler:           server.handler(),
+		ReadHeaderTimeout: 10 * time.Second,
+	})
+}
+
+func (app *App) parseAPIFlags() error {
+	if err := app.parseFlags(); err != nil || app.config.PrintConfig {
+		return err
+	}
+	switch {
+	case app.config.QueueSize < 1:
+		return errors.New("任务队列上限必须大于 0")
+	case app.config.JobWorkers < 1:
+		return errors.New("同时执行的任务数必须大于 0")
+	case app.config.MaxURLs < 1:
+		return errors.New("单个任务的地址数上限必须大于 0")
+	case app.config.Browsers < 1 || app.config.Tabs < 1:
+		return errors.New("浏览器数量与标签页数量必须大于 0")
+	}
+	return nil
+}
+
+func isLoopback(addr string) bool {
+	host, _, err := net.SplitHostPort(addr)
+	if err != nil {
+		return false
+	}
+	if host == "localhost" {
+		return true
+	}
+	ip := net.ParseIP(host)
+	return ip != nil && ip.IsLoopback()
+}
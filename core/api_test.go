@@ -0,0 +1,49 @@
+package core
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+)
+
+func TestSubmitQueueFull(t *testing.T) {
+	dir := t.TempDir()
+	app := newCommandApp("api")
+	if err := app.flags.Parse([]string{"-output-dir", dir, "-log", "0"}); err != nil {
+		t.Fatal(err)
+	}
+	if err := app.parseAPIFlags(); err != nil {
+		t.Fatal(err)
+	}
+
+	// 不启动执行任务的协程，提交的任务停留在队列中
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	s := &apiServer{app: app, ctx: ctx, cancel: cancel, queue: make(chan *job, 1), jobs: make(map[string]*job)}
+	handler := s.handler()
+
+	submit := func() int {
+		req := httptest.NewRequest(http.MethodPost, "/api/jobs", strings.NewReader(`{"urls":["https://example.com"]}`))
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, req)
+		return rec.Code
+	}
+
+	if code := submit(); code != http.StatusAccepted {
+		t.Fatalf("第一个任务返回 %d，应为 202", code)
+	}
+	if code := submit(); code != http.StatusTooManyRequests {
+		t.Fatalf("队列已满时返回 %d，应为 429", code)
+	}
+
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != 1 {
+		t.Errorf("队列已满时不应创建运行目录，输出目录中有 %d 项", len(entries))
+	}
+}
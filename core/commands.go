@@ -0,0 +1,288 @@
+package core
+
+import (
+	log "Sowhp/concert/logger"
+	"Sowhp/scripts"
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"net/http"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"strings"
+	"syscall"
+	"time"
+)
+
+// defaultLogLevel 为子命令未指定 -log 时的日志级别
+const defaultLogLevel = 3
+
+type command struct {
+	name  string
+	args  string
+	brief string
+	run   func(args []string) error
+}
+
+func commandList() []command {
+	return []command{
+		{name: "scan", args: "-f <地址文件>", brief: "读取地址列表截图并生成报告（默认子命令，可省略）", run: runScan},
+		{name: "report", args: "<运行目录>...", brief: "基于已有运行目录的结果重新生成报告", run: runReport},
+		{name: "diff", args: "<旧运行目录> <新运行目录>", brief: "比较两次运行的结果，列出新增、消失、状态码、标题与截图变化的目标，并生成 HTML 差异报告", run: runDiff},
+		{name: "serve", args: "[运行目录...]", brief: "启动 Web 界面与 JSON 接口，分页浏览、搜索、筛选运行结果并添加标签", run: runServe},
+		{name: "monitor", args: "-f <地址文件>", brief: "按间隔重复截图，记录每个地址的历史，发现新服务、状态码、标题或截图变化时发送通知", run: runMonitor},
+		{name: "api", args: "", brief: "启动 REST 接口，接收截图任务并排队执行，本地 Chrome 引擎复用预先启动的浏览器", run: runAPI},
+		{name: "doctor", args: "", brief: "检查浏览器与输出目录等运行环境", run: runDoctor},
+	}
+}
+
+func lookupCommand(name string) (command, bool) {
+	for _, cmd := range commandList() {
+		if cmd.name == name {
+			return cmd, true
+		}
+	}
+	return command{}, false
+}
+
+// Run 解析子命令并执行，第一个参数以 - 开头或未指定子命令时执行 scan，兼容旧的 sowhp -f urls.txt 用法
+func Run() error {
+	args := os.Args[1:]
+	name := "scan"
+	if len(args) > 0 && !strings.HasPrefix(args[0], "-") {
+		name, args = args[0], args[1:]
+	}
+
+	print(Banner)
+	log.LogLevel = defaultLogLevel
+	if name == "help" {
+		printCommands()
+		return nil
+	}
+
+	cmd, ok := lookupCommand(name)
+	if !ok {
+		printCommands()
+		err := fmt.Errorf("未知的子命令: %s", name)
+		log.Error(err.Error())
+		return err
+	}
+	return cmd.run(args)
+}
+
+func printCommands() {
+	fmt.Fprintln(os.Stderr, "用法: sowhp <子命令> [参数]")
+	fmt.Fprintln(os.Stderr, "\n子命令:")
+	for _, cmd := range commandList() {
+		fmt.Fprintf(os.Stderr, "  %-8s %s\n", cmd.name, cmd.brief)
+	}
+	fmt.Fprintln(os.Stderr, "\n使用 sowhp <子命令> -h 查看各子命令的参数")
+}
+
+// newFlagSet 创建子命令的参数集，帮助信息包含子命令的用法与说明
+func newFlagSet(name string) *flag.FlagSet {
+	fs := flag.NewFlagSet(name, flag.ContinueOnError)
+	fs.Usage = func() {
+		cmd, _ := lookupCommand(name)
+		fmt.Fprintf(fs.Output(), "用法: sowhp %s [参数] %s\n\n%s\n\n参数:\n", name, cmd.args, cmd.brief)
+		fs.PrintDefaults()
+	}
+	return fs
+}
+
+// parseError 处理参数解析错误：-h 正常返回，其他错误已由 flag 包输出用法
+func parseError(err error) error {
+	if errors.Is(err, flag.ErrHelp) {
+		return nil
+	}
+	return err
+}
+
+// usageError 输出子命令用法并记录错误
+func usageError(fs *flag.FlagSet, err error) error {
+	fs.Usage()
+	log.Error(err.Error())
+	return err
+}
+
+func runReport(args []string) error {
+	fs := newFlagSet("report")
+	formats := fs.String("format", strings.Join(scripts.ReportFormats, ","), "生成的报告格式，多个以逗号分隔，standalone 为内嵌截图的独立 HTML 报告（可选参数，默认值: csv,html,json）\n\t\t示例: -format standalone")
+	limit := fs.Int("standalone-limit", scripts.DefaultStandaloneLimit>>20, "单个独立报告文件的大小上限（MB），超出时拆分为多个文件（可选参数，默认值: 15）\n\t\t示例: -standalone-limit 8")
+	if err := fs.Parse(args); err != nil {
+		return parseError(err)
+	}
+	if fs.NArg() == 0 {
+		return usageError(fs, errors.New("请指定运行目录"))
+	}
+	if *limit < 1 {
+		return usageError(fs, errors.New("独立报告大小上限必须大于 0"))
+	}
+
+	opts := scripts.ReportOptions{StandaloneLimit: int64(*limit) << 20}
+	for _, runDir := range fs.Args() {
+		if err := scripts.CreateRunReports(runDir, strings.Split(*formats, ","), opts); err != nil {
+			log.Error(fmt.Sprintf("生成报告失败 %s: %v", runDir, err))
+			return err
+		}
+	}
+	return nil
+}
+
+func runDiff(args []string) error {
+	fs := newFlagSet("diff")
+	asJSON := fs.Bool("json", false, "以 JSON 格式输出差异（可选参数）\n\t\t示例: -json")
+	noVisual := fs.Bool("no-visual", false, "不比较截图，只比较状态码与标题（可选参数）\n\t\t示例: -no-visual")
+	threshold := fs.Float64("threshold", scripts.DefaultVisualThreshold, "变化像素占比超过该值时视为截图变化，取值 0~1（可选参数，默认值: 0.01）\n\t\t示例: -threshold 0.05")
+	tolerance := fs.Int("tolerance", scripts.DefaultPixelTolerance, "单个像素任一颜色通道的差值超过该值时视为变化，用于忽略抗锯齿等细微差异，取值 0~255（可选参数，默认值: 32）\n\t\t示例: -tolerance 16")
+	out := fs.String("out", "", "差异报告目录，写入 index.html、diff.json 与差异叠加图（可选参数，默认为新运行目录旁的 diff_<旧运行>_<新运行>）\n\t\t示例: -out ./result/weekly_diff")
+	noReport := fs.Bool("no-report", false, "只输出差异，不生成差异报告（可选参数）\n\t\t示例: -no-report")
+	if err := fs.Parse(args); err != nil {
+		return parseError(err)
+	}
+	if fs.NArg() != 2 {
+		return usageError(fs, errors.New("请指定两个运行目录"))
+	}
+	if *threshold < 0 || *threshold >= 1 {
+		return usageError(fs, errors.New("截图变化阈值应在 0~1 之间"))
+	}
+	if *tolerance < 0 || *tolerance > 255 {
+		return usageError(fs, errors.New("像素容差应在 0~255 之间"))
+	}
+
+	oldDir, newDir := fs.Arg(0), fs.Arg(1)
+	reportDir := *out
+	if reportDir == "" {
+		reportDir = filepath.Join(filepath.Dir(filepath.Clean(newDir)), fmt.Sprintf("diff_%s_%s", filepath.Base(filepath.Clean(oldDir)), filepath.Base(filepath.Clean(newDir))))
+	}
+	reportDir, err := filepath.Abs(reportDir)
+	if err != nil {
+		return err
+	}
+
+	opts := scripts.DiffOptions{Visual: !*noVisual, Threshold: *threshold, Tolerance: *tolerance}
+	if !*noReport {
+		opts.OverlayDir = filepath.Join(reportDir, scripts.DiffOverlayDirName)
+	}
+	diff, err := scripts.DiffRuns(oldDir, newDir, opts)
+	if err != nil {
+		log.Error(err.Error())
+		return err
+	}
+	if !*noReport {
+		if err := scripts.WriteDiffReport(diff, reportDir); err != nil {
+			log.Error(err.Error())
+			return err
+		}
+	}
+
+	if *asJSON {
+		encoder := json.NewEncoder(os.Stdout)
+		encoder.SetIndent("", "  ")
+		return encoder.Encode(diff)
+	}
+	printDiff(diff)
+	return nil
+}
+
+func printDiff(diff *scripts.RunDiff) {
+	if diff.Threshold > 0 {
+		fmt.Printf("新增 %d，消失 %d，状态码变化 %d，标题变化 %d，截图变化 %d，未变化 %d\n",
+			len(diff.Added), len(diff.Removed), len(diff.StatusChanged), len(diff.TitleChanged), len(diff.VisualChanged), diff.Unchanged)
+	} else {
+		fmt.Printf("新增 %d，消失 %d，状态码变化 %d，标题变化 %d，未变化 %d\n",
+			len(diff.Added), len(diff.Removed), len(diff.StatusChanged), len(diff.TitleChanged), diff.Unchanged)
+	}
+
+	if len(diff.Added) > 0 {
+		fmt.Println("\n[新增]")
+		for _, result := range diff.Added {
+			fmt.Printf("  %s  %s  %s\n", result.URL, result.Status(), result.Title)
+		}
+	}
+	if len(diff.Removed) > 0 {
+		fmt.Println("\n[消失]")
+		for _, result := range diff.Removed {
+			fmt.Printf("  %s  %s  %s\n", result.URL, result.Status(), result.Title)
+		}
+	}
+	if len(diff.StatusChanged) > 0 {
+		fmt.Println("\n[状态码变化]")
+		for _, change := range diff.StatusChanged {
+			fmt.Printf("  %s  %s -> %s\n", change.URL, change.Old.Status(), change.New.Status())
+		}
+	}
+	if len(diff.TitleChanged) > 0 {
+		fmt.Println("\n[标题变化]")
+		for _, change := range diff.TitleChanged {
+			fmt.Printf("  %s  %q -> %q\n", change.URL, change.Old.Title, change.New.Title)
+		}
+	}
+	if len(diff.VisualChanged) > 0 {
+		fmt.Println("\n[截图变化]")
+		for _, change := range diff.VisualChanged {
+			fmt.Printf("  %s  %.2f%%\n", change.URL, *change.Difference*100)
+		}
+	}
+}
+
+func runServe(args []string) error {
+	fs := newFlagSet("serve")
+	listen := fs.String("listen", "127.0.0.1:8080", "监听地址（可选参数，默认值: 127.0.0.1:8080）\n\t\t示例: -listen 0.0.0.0:8080")
+	dir := fs.String("dir", "./result", "未指定运行目录时，索引该目录下的全部运行目录（可选参数，默认值: ./result）\n\t\t示例: -dir /data/result")
+	if err := fs.Parse(args); err != nil {
+		return parseError(err)
+	}
+
+	runDirs := fs.Args()
+	if len(runDirs) == 0 {
+		var err error
+		if runDirs, err = scripts.FindRunDirs(*dir); err != nil {
+			return usageError(fs, err)
+		}
+	}
+	if len(runDirs) == 0 {
+		return usageError(fs, fmt.Errorf("%s 下没有运行目录", *dir))
+	}
+
+	index, err := scripts.NewResultIndex(runDirs)
+	if err != nil {
+		log.Error(err.Error())
+		return err
+	}
+	log.Info(fmt.Sprintf("已索引 %d 个运行目录", len(runDirs)))
+
+	server := &http.Server{
+		Addr:              *listen,
+		Handler:           scripts.NewBrowseHandler(index),
+		ReadHeaderTimeout: 10 * time.Second,
+	}
+	return serveUntilSignal(server)
+}
+
+// serveUntilSignal 启动 HTTP 服务，收到 SIGINT/SIGTERM 后优雅关闭
+func serveUntilSignal(server *http.Server) error {
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	errChan := make(chan error, 1)
+	go func() {
+		errChan <- server.ListenAndServe()
+	}()
+	log.Info(fmt.Sprintf("服务已启动: http://%s/", server.Addr))
+
+	select {
+	case err := <-errChan:
+		log.Error(fmt.Sprintf("服务启动失败: %v", err))
+		return err
+	case <-ctx.Done():
+	}
+
+	log.Info("收到中断信号，正在关闭服务")
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	return server.Shutdown(shutdownCtx)
+}
@@ -0,0 +1,392 @@
+package core
+
+import (
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/BurntSushi/toml"
+	"gopkg.in/yaml.v3"
+)
+
+// defaultConfigFile 为未指定 -config 时自动加载的配置文件
+const defaultConfigFile = "./sowhp.yaml"
+
+// envPrefix 为环境变量前缀，参数名转为大写并将 - 替换为 _，如 SOWHP_HTTP_TIMEOUT
+const envPrefix = "SOWHP_"
+
+// 配置值来源，优先级依次升高
+const (
+	sourceDefault = "default"
+	sourceFile    = "file"
+	sourceProfile = "profile"
+	sourceEnv     = "env"
+	sourceCLI     = "cli"
+)
+
+// configOnlyFlags 只能在命令行中指定，不能写入配置文件
+var configOnlyFlags = map[string]bool{
+	"config":       true,
+	"print-config": true,
+}
+
+// configAliases 为配置文件中可读性更好的参数别名
+var configAliases = map[string]string{
+	"file":    "f",
+	"threads": "t",
+}
+
+// mapSeparators 为可在配置文件中写成映射的参数及其键值分隔符
+var mapSeparators = map[string]string{
+	"header":      ": ",
+	"chrome-flag": "=",
+}
+
+// listFlag 为可重复指定的参数，如 -header 与 -chrome-flag
+type listFlag []string
+
+func (l *listFlag) String() string {
+	if l == nil {
+		return ""
+	}
+	return strings.Join(*l, ", ")
+}
+
+func (l *listFlag) Set(value string) error {
+	*l = append(*l, value)
+	return nil
+}
+
+// configValue 为合并后的单个配置值，列表参数保留各个元素
+type configValue struct {
+	values []string
+	source string
+}
+
+// effectiveConfig 记录最终使用的配置文件、配置方案与各参数的来源
+type effectiveConfig struct {
+	path    string
+	profile string
+	sources map[string]string
+}
+
+// loadConfig 按 命令行 > 环境变量 > 配置方案 > 配置文件 > 默认值 的优先级合并配置，
+// 并将未在命令行中指定的参数写回对应的 flag
+func (app *App) loadConfig() error {
+	fs := app.flags
+	passed := make(map[string]bool)
+	fs.Visit(func(f *flag.Flag) {
+		passed[f.Name] = true
+	})
+
+	path := app.config.ConfigFile
+	if !passed["config"] {
+		if env := os.Getenv(envPrefix + "CONFIG"); env != "" {
+			path = env
+		} else if _, err := os.Stat(defaultConfigFile); err == nil {
+			path = defaultConfigFile
+		}
+	}
+
+	var file map[string]interface{}
+	if path != "" {
+		var err error
+		file, err = readConfigFile(path)
+		if err != nil {
+			return err
+		}
+	}
+
+	profiles, err := configProfiles(file)
+	if err != nil {
+		return err
+	}
+	delete(file, "profiles")
+
+	profile := app.config.Profile
+	if !passed["profile"] {
+		if env := os.Getenv(envPrefix + "PROFILE"); env != "" {
+			profile = env
+		} else if value, ok := file["profile"]; ok {
+			profile = fmt.Sprint(value)
+		}
+	}
+	delete(file, "profile")
+
+	merged := make(map[string]configValue)
+	if err := mergeConfig(fs, merged, file, sourceFile); err != nil {
+		return fmt.Errorf("配置文件 %s: %w", path, err)
+	}
+	if profile != "" {
+		values, ok := profiles[profile]
+		if !ok {
+			return fmt.Errorf("配置方案不存在: %s", profile)
+		}
+		if err := mergeConfig(fs, merged, values, sourceProfile); err != nil {
+			return fmt.Errorf("配置方案 %s: %w", profile, err)
+		}
+	}
+	mergeEnv(fs, merged)
+
+	sources := make(map[string]string)
+	var setErr error
+	fs.VisitAll(func(f *flag.Flag) {
+		if setErr != nil {
+			return
+		}
+		if passed[f.Name] {
+			sources[f.Name] = sourceCLI
+			return
+		}
+		value, ok := merged[f.Name]
+		if !ok {
+			sources[f.Name] = sourceDefault
+			return
+		}
+		sources[f.Name] = value.source
+		setErr = setFlag(f, value.values)
+	})
+	if setErr != nil {
+		return setErr
+	}
+
+	app.effective = effectiveConfig{path: path, profile: profile, sources: sources}
+	return nil
+}
+
+func readConfigFile(path string) (map[string]interface{}, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("读取配置文件失败: %w", err)
+	}
+
+	values := make(map[string]interface{})
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".yaml", ".yml":
+		err = yaml.Unmarshal(data, &values)
+	case ".toml":
+		err = toml.Unmarshal(data, &values)
+	case ".json":
+		err = json.Unmarshal(data, &values)
+	default:
+		return nil, fmt.Errorf("不支持的配置文件格式: %s（支持 .yaml/.yml/.toml/.json）", path)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("解析配置文件 %s 失败: %w", path, err)
+	}
+	return values, nil
+}
+
+func configProfiles(file map[string]interface{}) (map[string]map[string]interface{}, error) {
+	profiles := make(map[string]map[string]interface{})
+	raw, ok := file["profiles"]
+	if !ok {
+		return profiles, nil
+	}
+
+	entries, ok := raw.(map[string]interface{})
+	if !ok {
+		return nil, errors.New("配置项 profiles 必须为映射")
+	}
+	for name, entry := range entries {
+		values, ok := entry.(map[string]interface{})
+		if !ok {
+			return nil, fmt.Errorf("配置方案 %s 必须为映射", name)
+		}
+		profiles[name] = values
+	}
+	return profiles, nil
+}
+
+// mergeConfig 将配置文件或配置方案中的值写入 merged，后写入的覆盖先写入的
+func mergeConfig(fs *flag.FlagSet, merged map[string]configValue, values map[string]interface{}, source string) error {
+	for key, raw := range values {
+		name := configFlagName(key)
+		if configOnlyFlags[name] || name == "profile" || name == "profiles" {
+			return fmt.Errorf("配置项 %s 不能在此处指定", key)
+		}
+		if fs.Lookup(name) == nil {
+			if otherCommandFlag(name) {
+				continue
+			}
+			return fmt.Errorf("未知配置项: %s", key)
+		}
+
+		list, err := configStrings(name, raw)
+		if err != nil {
+			return fmt.Errorf("配置项 %s: %w", key, err)
+		}
+		merged[name] = configValue{values: list, source: source}
+	}
+	return nil
+}
+
+// otherCommandFlag 判断配置项是否为其他子命令的参数（如 scan 的 f、api 的 listen），各子命令共用配置文件时忽略这些项
+func otherCommandFlag(name string) bool {
+	for _, command := range []string{"scan", "api", "monitor", "doctor"} {
+		if newCommandApp(command).flags.Lookup(name) != nil {
+			return true
+		}
+	}
+	return false
+}
+
+// mergeEnv 读取 SOWHP_<参数名> 环境变量，列表参数的多个值以 ; 分隔
+func mergeEnv(fs *flag.FlagSet, merged map[string]configValue) {
+	fs.VisitAll(func(f *flag.Flag) {
+		if configOnlyFlags[f.Name] || f.Name == "profile" {
+			return
+		}
+		env, ok := os.LookupEnv(envName(f.Name))
+		if !ok {
+			return
+		}
+
+		values := []string{env}
+		if _, isList := f.Value.(*listFlag); isList {
+			values = strings.Split(env, ";")
+		}
+		merged[f.Name] = configValue{values: values, source: sourceEnv}
+	})
+}
+
+func configFlagName(key string) string {
+	name := strings.ReplaceAll(strings.TrimSpace(key), "_", "-")
+	if alias, ok := configAliases[name]; ok {
+		return alias
+	}
+	return name
+}
+
+func envName(name string) string {
+	return envPrefix + strings.ToUpper(strings.ReplaceAll(name, "-", "_"))
+}
+
+// configStrings 将配置文件中的值转换为 flag 可接受的字符串，列表逐项转换，映射按参数的分隔符拼接
+func configStrings(name string, raw interface{}) ([]string, error) {
+	switch value := raw.(type) {
+	case []interface{}:
+		list := make([]string, 0, len(value))
+		for _, item := range value {
+			s, err := configScalar(item)
+			if err != nil {
+				return nil, err
+			}
+			list = append(list, s)
+		}
+		return list, nil
+	case map[string]interface{}:
+		separator, ok := mapSeparators[name]
+		if !ok {
+			return nil, errors.New("不支持映射类型的值")
+		}
+		keys := make([]string, 0, len(value))
+		for key := range value {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+
+		list := make([]string, 0, len(value))
+		for _, key := range keys {
+			s, err := configScalar(value[key])
+			if err != nil {
+				return nil, err
+			}
+			if s == "" && name == "chrome-flag" {
+				list = append(list, key)
+				continue
+			}
+			list = append(list, key+separator+s)
+		}
+		return list, nil
+	}
+
+	s, err := configScalar(raw)
+	if err != nil {
+		return nil, err
+	}
+	return []string{s}, nil
+}
+
+func configScalar(raw interface{}) (string, error) {
+	switch value := raw.(type) {
+	case nil:
+		return "", nil
+	case string:
+		return value, nil
+	case bool:
+		return strconv.FormatBool(value), nil
+	case int:
+		return strconv.Itoa(value), nil
+	case int64:
+		return strconv.FormatInt(value, 10), nil
+	case float64:
+		return strconv.FormatFloat(value, 'f', -1, 64), nil
+	}
+	return "", fmt.Errorf("不支持的值类型: %T", raw)
+}
+
+// setFlag 将合并后的值写入 flag：列表参数逐项写入，其他参数以逗号拼接后写入
+func setFlag(f *flag.Flag, values []string) error {
+	if _, isList := f.Value.(*listFlag); isList {
+		for _, value := range values {
+			if err := f.Value.Set(value); err != nil {
+				return fmt.Errorf("参数 %s 的值无效: %w", f.Name, err)
+			}
+		}
+		return nil
+	}
+
+	if err := f.Value.Set(strings.Join(values, ",")); err != nil {
+		return fmt.Errorf("参数 %s 的值 %q 无效: %w", f.Name, strings.Join(values, ","), err)
+	}
+	return nil
+}
+
+// printConfig 以 YAML 形式输出合并后的最终配置及各项来源，输出内容可直接作为配置文件使用
+func (app *App) printConfig() {
+	var builder strings.Builder
+	if app.effective.path != "" {
+		builder.WriteString(fmt.Sprintf("# 配置文件: %s\n", app.effective.path))
+	}
+	if app.effective.profile != "" {
+		builder.WriteString(fmt.Sprintf("# 配置方案: %s\n", app.effective.profile))
+	}
+
+	app.flags.VisitAll(func(f *flag.Flag) {
+		if configOnlyFlags[f.Name] || f.Name == "profile" {
+			return
+		}
+		source := app.effective.sources[f.Name]
+
+		if list, isList := f.Value.(*listFlag); isList {
+			if len(*list) == 0 {
+				builder.WriteString(fmt.Sprintf("%s: []  # %s\n", f.Name, source))
+				return
+			}
+			builder.WriteString(fmt.Sprintf("%s:  # %s\n", f.Name, source))
+			for _, value := range *list {
+				builder.WriteString(fmt.Sprintf("  - %s\n", strconv.Quote(value)))
+			}
+			return
+		}
+		builder.WriteString(fmt.Sprintf("%s: %s  # %s\n", f.Name, yamlScalar(f), source))
+	})
+	fmt.Print(builder.String())
+}
+
+func yamlScalar(f *flag.Flag) string {
+	value := f.Value.String()
+	if getter, ok := f.Value.(flag.Getter); ok {
+		if _, isString := getter.Get().(string); isString {
+			return strconv.Quote(value)
+		}
+	}
+	return value
+}
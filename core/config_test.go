@@ -0,0 +1,62 @@
+package core
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func parseScanArgs(t *testing.T, args ...string) (*App, error) {
+	t.Helper()
+	app := newCommandApp("scan")
+	if err := app.flags.Parse(append([]string{"-f", "urls.txt"}, args...)); err != nil {
+		t.Fatal(err)
+	}
+	return app, app.parseFlags()
+}
+
+func TestHTTPOnlyThreads(t *testing.T) {
+	config := filepath.Join(t.TempDir(), "sowhp.yaml")
+	if err := os.WriteFile(config, []byte("threads: 10\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name string
+		env  string
+		args []string
+		want int
+	}{
+		{name: "默认值", args: []string{"-http-only"}, want: httpOnlyThreads},
+		{name: "命令行", args: []string{"-http-only", "-t", "3"}, want: 3},
+		{name: "环境变量", env: "7", args: []string{"-http-only"}, want: 7},
+		{name: "配置文件", args: []string{"-http-only", "-config", config}, want: 10},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if tt.env != "" {
+				t.Setenv(envName("t"), tt.env)
+			}
+			app, err := parseScanArgs(t, tt.args...)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if app.options.Threads != tt.want {
+				t.Errorf("并发数为 %d，应为 %d", app.options.Threads, tt.want)
+			}
+		})
+	}
+}
+
+func TestProxyValidation(t *testing.T) {
+	for _, proxy := range []string{"127.0.0.1:8080", "localhost:8080", "ftp://127.0.0.1:21", "http://"} {
+		if _, err := parseScanArgs(t, "-proxy", proxy); err == nil {
+			t.Errorf("代理地址 %q 应报错", proxy)
+		}
+	}
+	for _, proxy := range []string{"http://127.0.0.1:8080", "socks5://127.0.0.1:1080"} {
+		if _, err := parseScanArgs(t, "-proxy", proxy); err != nil {
+			t.Errorf("代理地址 %q 不应报错: %v", proxy, err)
+		}
+	}
+}
@@ -0,0 +1,79 @@
+package core
+
+import (
+	log "Sowhp/concert/logger"
+	"Sowhp/scripts"
+	"Sowhp/sowhp"
+	"context"
+	"errors"
+	"fmt"
+	"path/filepath"
+)
+
+// preflight 在创建运行目录前检查输出目录与截图引擎，浏览器不可用时提前结束任务，避免每个地址都记录一次失败
+func (app *App) preflight() error {
+	log.Info("正在检查运行环境")
+	outputDir := app.config.OutputDir
+	if app.config.Resume != "" {
+		outputDir = filepath.Dir(filepath.Clean(app.config.Resume))
+	}
+
+	checks := []scripts.CheckResult{scripts.CheckWritable(outputDir)}
+	if check := sowhp.New(app.options).Check(context.Background()); check != nil {
+		checks = append(checks, *check)
+	}
+
+	if failed := logChecks(checks, false); failed > 0 {
+		return errors.New("运行环境检查未通过，请根据上述提示修复后重试，或运行 sowhp doctor 查看详情")
+	}
+	return nil
+}
+
+// logChecks 输出检查结果，verbose 为 false 时只输出未通过的项，返回未通过的项数
+func logChecks(checks []scripts.CheckResult, verbose bool) int {
+	failed := 0
+	for _, check := range checks {
+		if !check.OK {
+			failed++
+			log.Error(fmt.Sprintf("%s: %s", check.Name, check.Detail))
+		} else if verbose {
+			log.Info(fmt.Sprintf("%s: %s", check.Name, check.Detail))
+		}
+	}
+	return failed
+}
+
+// runDoctor 为 doctor 子命令：与 scan 相同地合并命令行、环境变量与配置文件，按最终的截图参数检查运行环境
+func runDoctor(args []string) error {
+	app := newCommandApp("doctor")
+	if err := app.flags.Parse(args); err != nil {
+		return parseError(err)
+	}
+	if err := app.parseFlags(); err != nil {
+		app.flags.Usage()
+		log.Error(err.Error())
+		return err
+	}
+	if app.config.PrintConfig {
+		app.printConfig()
+		return nil
+	}
+
+	dir := app.config.CheckDir
+	if dir == "" {
+		dir = app.config.OutputDir
+	}
+	var checks []scripts.CheckResult
+	log.Info("正在检查截图引擎")
+	if check := sowhp.New(app.options).Check(context.Background()); check != nil {
+		checks = append(checks, *check)
+	} else {
+		log.Info(fmt.Sprintf("截图引擎 %s 无需检查浏览器", app.config.Engine))
+	}
+	checks = append(checks, scripts.CheckWritable(dir))
+	if failed := logChecks(checks, true); failed > 0 {
+		return fmt.Errorf("%d 项检查未通过", failed)
+	}
+	log.Info("运行环境检查通过")
+	return nil
+}
@@ -0,0 +1,342 @@
+package core
+
+import (
+	log "Sowhp/concert/logger"
+	"Sowhp/scripts"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"time"
+)
+
+const (
+	// monitorStateFile 记录监控模式的运行，位于输出目录中
+	monitorStateFile = "monitor.json"
+	// monitorDiffDir 为每次运行与上一次运行的差异报告目录，位于本次运行目录中
+	monitorDiffDir = "diff"
+	// maxNotifyLines 为通知正文中列出的变化条数上限，完整内容见差异报告
+	maxNotifyLines = 20
+)
+
+// monitorState 按时间顺序记录监控模式创建的运行目录名
+type monitorState struct {
+	Runs []string `json:"runs"`
+	// Failed 为失败或中断的运行，计入 -keep 的保留数量但不作为比较基准
+	Failed []string `json:"failed,omitempty"`
+}
+
+// defineMonitorFlags 注册 monitor 子命令特有的参数
+func (app *App) defineMonitorFlags() {
+	fs := app.flags
+	fs.StringVar(&app.config.FilePath, "f", "", "指定包含URL列表的文本文件路径，每次运行前重新读取（必需参数）\n\t\t示例: -f /path/to/urls.txt")
+	fs.BoolVar(&app.config.KeepPath, "keep-path", false, "去重时保留同一主机下的不同路径（可选参数，默认同一主机只截图一次）\n\t\t示例: -keep-path")
+	fs.BoolVar(&app.config.NoPreflight, "no-preflight", false, "跳过启动时的浏览器与输出目录检查（可选参数）\n\t\t示例: -no-preflight")
+	fs.StringVar(&app.config.RunName, "run-name", "monitor_{date}_{time}", "运行目录命名模板，支持 {date} {time} {index}（可选参数，默认值: monitor_{date}_{time}）\n\t\t示例: -run-name weekly_{date}")
+	fs.DurationVar(&app.config.Interval, "interval", 6*time.Hour, "两次运行开始的间隔，上次运行超时时立即开始下一次（可选参数，默认值: 6h）\n\t\t示例: -interval 30m")
+	fs.IntVar(&app.config.Keep, "keep", 10, "保留最近多少次运行，更早的运行目录、报告与历史记录会被删除（可选参数，默认值: 10）\n\t\t示例: -keep 28")
+	fs.StringVar(&app.config.Webhook, "webhook", "", "发现变化时以 JSON 格式 POST 通知的地址，等同于 -notify webhook=地址（可选参数）\n\t\t示例: -webhook https://example.com/hooks/sowhp")
+	fs.Float64Var(&app.config.Threshold, "threshold", scripts.DefaultVisualThreshold, "变化像素占比超过该值时视为截图变化，取值 0~1（可选参数，默认值: 0.01）\n\t\t示例: -threshold 0.05")
+	fs.IntVar(&app.config.Tolerance, "tolerance", scripts.DefaultPixelTolerance, "单个像素任一颜色通道的差值超过该值时视为变化，取值 0~255（可选参数，默认值: 32）\n\t\t示例: -tolerance 16")
+	fs.BoolVar(&app.config.NoVisual, "no-visual", false, "不比较截图，只比较状态码与标题（可选参数）\n\t\t示例: -no-visual")
+	fs.BoolVar(&app.config.Once, "once", false, "只运行一次并与上一次运行比较后退出，便于由外部调度（可选参数）\n\t\t示例: -once")
+	fs.StringVar(&app.config.History, "history", "", "输出指定地址的历史记录后退出（可选参数）\n\t\t示例: -history https://example.com")
+}
+
+func (app *App) parseMonitorFlags() error {
+	if err := app.parseFlags(); err != nil || app.config.PrintConfig {
+		return err
+	}
+	switch {
+	case app.config.Interval <= 0:
+		return errors.New("运行间隔必须大于 0")
+	case app.config.Keep < 2:
+		return errors.New("至少需要保留 2 次运行才能比较变化")
+	case app.config.Threshold < 0 || app.config.Threshold >= 1:
+		return errors.New("截图变化阈值应在 0~1 之间")
+	case app.config.Tolerance < 0 || app.config.Tolerance > 255:
+		return errors.New("像素容差应在 0~255 之间")
+	}
+	return nil
+}
+
+// runMonitor 为 monitor 子命令：按间隔重复截图，与上一次运行比较并在发现变化时发送通知
+func runMonitor(args []string) error {
+	app := newCommandApp("monitor")
+	if err := app.flags.Parse(args); err != nil {
+		return parseError(err)
+	}
+	if err := app.parseMonitorFlags(); err != nil {
+		app.flags.Usage()
+		log.Error(err.Error())
+		return err
+	}
+	if app.config.PrintConfig {
+		app.printConfig()
+		return nil
+	}
+	if app.config.History != "" {
+		return app.printHistory(app.config.History)
+	}
+
+	if !app.config.NoPreflight {
+		if err := app.preflight(); err != nil {
+			return err
+		}
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	stopSignals := watchSignals(cancel)
+	defer stopSignals()
+
+	for cycle := 1; ; cycle++ {
+		start := time.Now()
+		log.Info(fmt.Sprintf("开始第 %d 次监控运行", cycle))
+		err := app.monitorCycle(ctx)
+		switch {
+		case ctx.Err() != nil:
+			log.Warning("监控已停止，本次中断的运行不参与比较")
+			return nil
+		case err != nil && app.config.Once:
+			log.Error(err.Error())
+			return err
+		case err != nil:
+			log.Error(fmt.Sprintf("第 %d 次监控运行失败: %v", cycle, err))
+		}
+		if app.config.Once {
+			return nil
+		}
+
+		next := start.Add(app.config.Interval)
+		log.Info(fmt.Sprintf("下次运行时间: %s", next.Format("2006-01-02 15:04:05")))
+		select {
+		case <-time.After(time.Until(next)):
+		case <-ctx.Done():
+			log.Warning("监控已停止")
+			return nil
+		}
+	}
+}
+
+// monitorCycle 完成一次运行：截图、与上一次运行比较、记录历史、发送通知并清理超出保留数的运行
+func (app *App) monitorCycle(ctx context.Context) error {
+	outputDir := app.config.OutputDir
+	state, err := loadMonitorState(outputDir)
+	if err != nil {
+		return err
+	}
+
+	app.runPath = ""
+	if err := app.execute(ctx); err != nil {
+		app.recordFailed(state, app.runPath)
+		return err
+	}
+	current := app.runPath
+	run := filepath.Base(current)
+
+	var events []scripts.ChangeEvent
+	if previous := state.last(outputDir); previous != "" {
+		diffDir := filepath.Join(current, monitorDiffDir)
+		diff, err := scripts.DiffRuns(filepath.Join(outputDir, previous), current, scripts.DiffOptions{
+			Visual:     !app.config.NoVisual,
+			Threshold:  app.config.Threshold,
+			Tolerance:  app.config.Tolerance,
+			OverlayDir: filepath.Join(diffDir, scripts.DiffOverlayDirName),
+		})
+		if err != nil {
+			app.recordFailed(state, current)
+			return fmt.Errorf("比较运行结果失败: %w", err)
+		}
+		if err := scripts.WriteDiffReport(diff, diffDir); err != nil {
+			log.Warning(err.Error())
+		}
+
+		events = diff.Events()
+		log.Info(fmt.Sprintf("与 %s 相比: 新服务 %d，消失 %d，状态码变化 %d，标题变化 %d，截图变化 %d",
+			previous, diff.Summary.Added, diff.Summary.Removed, diff.Summary.StatusChanged, diff.Summary.TitleChanged, diff.Summary.VisualChanged))
+		if len(events) > 0 && len(app.notifiers) > 0 {
+			report, _ := filepath.Abs(filepath.Join(diffDir, scripts.DiffReportFileName))
+			if app.notify(ctx, monitorNotification(run, previous, report, diff, events)) > 0 {
+				log.Info("已发送变化通知")
+			}
+		}
+	} else {
+		log.Info("首次运行，作为后续比较的基准")
+	}
+
+	if err := scripts.AppendHistory(outputDir, current, events); err != nil {
+		log.Warning(err.Error())
+	}
+
+	state.Runs = append(state.Runs, run)
+	if err := app.pruneRuns(state); err != nil {
+		log.Warning(err.Error())
+	}
+	return saveMonitorState(outputDir, state)
+}
+
+// recordFailed 记录已创建运行目录但未完成的运行，使其能按 -keep 被清理
+func (app *App) recordFailed(state *monitorState, runDir string) {
+	if runDir == "" {
+		return
+	}
+	run := filepath.Base(runDir)
+	state.Runs = append(state.Runs, run)
+	state.Failed = append(state.Failed, run)
+	if err := app.pruneRuns(state); err != nil {
+		log.Warning(err.Error())
+	}
+	if err := saveMonitorState(app.config.OutputDir, state); err != nil {
+		log.Warning(err.Error())
+	}
+}
+
+func monitorNotification(run, previous, report string, diff *scripts.RunDiff, events []scripts.ChangeEvent) scripts.Notification {
+	names := map[string]string{
+		scripts.ChangeNewService: "新服务",
+		scripts.ChangeGone:       "消失",
+		scripts.ChangeStatus:     "状态码变化",
+		scripts.ChangeTitle:      "标题变化",
+		scripts.ChangeVisual:     "截图变化",
+	}
+
+	lines := []string{
+		fmt.Sprintf("运行 %s（对比 %s）", run, previous),
+		fmt.Sprintf("新服务 %d，消失 %d，状态码变化 %d，标题变化 %d，截图变化 %d",
+			diff.Summary.Added, diff.Summary.Removed, diff.Summary.StatusChanged, diff.Summary.TitleChanged, diff.Summary.VisualChanged),
+		"差异报告: " + report,
+	}
+	for i, event := range events {
+		if i == maxNotifyLines {
+			lines = append(lines, fmt.Sprintf("……共 %d 项变化", len(events)))
+			break
+		}
+		line := fmt.Sprintf("[%s] %s", names[event.Type], event.URL)
+		switch event.Type {
+		case scripts.ChangeNewService:
+			line += fmt.Sprintf(" %s %s", event.NewStatus, event.NewTitle)
+		case scripts.ChangeStatus:
+			line += fmt.Sprintf(" %s -> %s", event.OldStatus, event.NewStatus)
+		case scripts.ChangeTitle:
+			line += fmt.Sprintf(" %q -> %q", event.OldTitle, event.NewTitle)
+		case scripts.ChangeVisual:
+			line += fmt.Sprintf(" %.2f%%", *event.Difference*100)
+		}
+		lines = append(lines, line)
+	}
+
+	return scripts.Notification{
+		Event: "monitor.changes",
+		Title: fmt.Sprintf("Sowhp 监控发现 %d 项变化", len(events)),
+		Text:  strings.Join(lines, "\n"),
+		Data: map[string]interface{}{
+			"run":          run,
+			"previous_run": previous,
+			"report":       report,
+			"summary":      diff.Summary,
+			"changes":      events,
+		},
+		Time: time.Now(),
+	}
+}
+
+// pruneRuns 删除超出 -keep 的最早运行目录及其报告，并清理对应的历史记录
+func (app *App) pruneRuns(state *monitorState) error {
+	if len(state.Runs) <= app.config.Keep {
+		return nil
+	}
+
+	outputDir := app.config.OutputDir
+	expired := state.Runs[:len(state.Runs)-app.config.Keep]
+	state.Runs = state.Runs[len(state.Runs)-app.config.Keep:]
+	failed := state.Failed[:0]
+	for _, run := range state.Failed {
+		if slices.Contains(state.Runs, run) {
+			failed = append(failed, run)
+		}
+	}
+	state.Failed = failed
+	for _, run := range expired {
+		if err := os.RemoveAll(filepath.Join(outputDir, run)); err != nil {
+			return fmt.Errorf("删除过期运行失败: %w", err)
+		}
+		reports, _ := filepath.Glob(filepath.Join(outputDir, run+".*"))
+		for _, report := range reports {
+			os.Remove(report)
+		}
+		log.Info(fmt.Sprintf("已删除过期运行 %s", run))
+	}
+	return scripts.PruneHistory(outputDir, state.Runs)
+}
+
+func (app *App) printHistory(url string) error {
+	found := 0
+	err := scripts.ScanHistory(app.config.OutputDir, url, func(entry scripts.HistoryEntry) error {
+		found++
+		status := entry.Status
+		if status == "" {
+			status = "-"
+		}
+		line := fmt.Sprintf("%s  %s  %s  %s", entry.CapturedAt.Local().Format("2006-01-02 15:04:05"), entry.Run, status, entry.Title)
+		if len(entry.Changes) > 0 {
+			line += "  [" + strings.Join(entry.Changes, ",") + "]"
+		}
+		fmt.Println(line)
+		return nil
+	})
+	if err != nil {
+		log.Error(err.Error())
+		return err
+	}
+	if found == 0 {
+		err := fmt.Errorf("%s 下没有 %s 的历史记录", app.config.OutputDir, url)
+		log.Error(err.Error())
+		return err
+	}
+	return nil
+}
+
+func loadMonitorState(outputDir string) (*monitorState, error) {
+	state := &monitorState{}
+	data, err := os.ReadFile(filepath.Join(outputDir, monitorStateFile))
+	if errors.Is(err, os.ErrNotExist) {
+		return state, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("读取监控状态失败: %w", err)
+	}
+	if err := json.Unmarshal(data, state); err != nil {
+		return nil, fmt.Errorf("解析监控状态失败: %w", err)
+	}
+	return state, nil
+}
+
+func saveMonitorState(outputDir string, state *monitorState) error {
+	data, err := json.MarshalIndent(state, "", "  ")
+	if err != nil {
+		return err
+	}
+	path := filepath.Join(outputDir, monitorStateFile)
+	if err := os.WriteFile(path+".tmp", data, 0644); err != nil {
+		return fmt.Errorf("保存监控状态失败: %w", err)
+	}
+	return os.Rename(path+".tmp", path)
+}
+
+// last 返回最近一次仍存在的运行目录名
+func (s *monitorState) last(outputDir string) string {
+	for i := len(s.Runs) - 1; i >= 0; i-- {
+		if slices.Contains(s.Failed, s.Runs[i]) {
+			continue
+		}
+		if _, err := os.Stat(filepath.Join(outputDir, s.Runs[i], scripts.ResultsFileName)); err == nil {
+			return s.Runs[i]
+		}
+	}
+	return ""
+}
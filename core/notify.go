@@ -0,0 +1,290 @@
+package core
+
+import (
+	log "Sowhp/concert/logger"
+	"Sowhp/scripts"
+	"context"
+	"fmt"
+	"net/url"
+	"path/filepath"
+	"slices"
+	"sort"
+	"strings"
+	"time"
+)
+
+const (
+	// findingBatchDelay 为合并发送匹配结果的等待时间，期间匹配的结果合并为一条消息，避免触发机器人的频率限制
+	findingBatchDelay = 3 * time.Second
+	// maxFindingLines 为单条匹配结果通知中列出的结果数上限
+	maxFindingLines = 20
+	// findingQueueSize 为等待发送的匹配结果数上限，超出时不再逐条通知，仅计入运行摘要
+	findingQueueSize = 1000
+)
+
+// namedNotifier 为带名称的通知渠道，名称用于日志
+type namedNotifier struct {
+	name string
+	scripts.Notifier
+}
+
+// findingQueue 将匹配筛选条件的结果异步合并发送
+type findingQueue struct {
+	results chan scripts.Result
+	done    chan struct{}
+	matched int
+	dropped int
+}
+
+// defineNotifyFlags 注册 scan 与 monitor 子命令共用的通知参数
+func (app *App) defineNotifyFlags() {
+	fs := app.flags
+	fs.Var(&app.config.Notify, "notify", "通知渠道，格式为 类型=地址，类型可选 webhook、dingtalk、feishu、wecom，可重复指定（可选参数）\n\t\t示例: -notify dingtalk=https://oapi.dingtalk.com/robot/send?access_token=xxx")
+	fs.Var(&app.config.NotifySecrets, "notify-secret", "通知渠道的加签密钥，格式为 类型=密钥，可重复指定（可选参数）\n\t\t示例: -notify-secret dingtalk=SECxxx")
+	fs.Var(&app.config.NotifyFilters, "notify-filter", "结果满足筛选条件时立即通知，可重复指定，满足任一条件即通知（可选参数）\n\t\t示例: -notify-filter \"status:200 title:后台|登录\"")
+}
+
+// parseNotifyFlags 创建通知渠道并解析筛选条件
+func (app *App) parseNotifyFlags() error {
+	secrets := make(map[string]string)
+	for _, value := range app.config.NotifySecrets {
+		kind, secret, ok := strings.Cut(value, "=")
+		kind = strings.ToLower(strings.TrimSpace(kind))
+		if !ok || !slices.Contains(scripts.NotifierTypes, kind) || secret == "" {
+			return fmt.Errorf("无效的通知密钥 %q，格式应为 类型=密钥", value)
+		}
+		secrets[kind] = secret
+	}
+
+	app.notifiers = nil
+	for _, value := range app.config.Notify {
+		kind, address, ok := strings.Cut(value, "=")
+		if !ok || strings.Contains(kind, "/") {
+			// 未指定类型的地址作为通用 Webhook
+			kind, address = scripts.NotifyWebhook, value
+		}
+		kind = strings.ToLower(strings.TrimSpace(kind))
+		notifier, err := scripts.NewNotifier(kind, strings.TrimSpace(address), secrets[kind])
+		if err != nil {
+			return err
+		}
+		app.notifiers = append(app.notifiers, namedNotifier{name: notifierName(kind, address), Notifier: notifier})
+	}
+	if app.config.Webhook != "" {
+		notifier, err := scripts.NewNotifier(scripts.NotifyWebhook, app.config.Webhook, secrets[scripts.NotifyWebhook])
+		if err != nil {
+			return err
+		}
+		app.notifiers = append(app.notifiers, namedNotifier{name: notifierName(scripts.NotifyWebhook, app.config.Webhook), Notifier: notifier})
+	}
+
+	app.filters = nil
+	for _, expr := range app.config.NotifyFilters {
+		filter, err := scripts.ParseResultFilter(expr)
+		if err != nil {
+			return err
+		}
+		app.filters = append(app.filters, filter)
+	}
+	if len(app.filters) > 0 && len(app.notifiers) == 0 {
+		return fmt.Errorf("-notify-filter 需要同时指定 -notify")
+	}
+	return nil
+}
+
+// notifierName 返回用于日志的渠道名称，不包含地址中的令牌
+func notifierName(kind, address string) string {
+	if parsed, err := url.Parse(strings.TrimSpace(address)); err == nil && parsed.Host != "" {
+		return kind + "(" + parsed.Host + ")"
+	}
+	return kind
+}
+
+// notify 将通知发送到全部渠道，返回发送成功的渠道数；发送失败只记录警告，不影响任务
+func (app *App) notify(ctx context.Context, n scripts.Notification) int {
+	if n.Time.IsZero() {
+		n.Time = time.Now()
+	}
+	sent := 0
+	for _, notifier := range app.notifiers {
+		if err := notifier.Notify(ctx, n); err != nil {
+			log.Warning(fmt.Sprintf("通知 %s 失败: %v", notifier.name, err))
+			continue
+		}
+		log.Debug(fmt.Sprintf("已发送通知到 %s: %s", notifier.name, n.Title))
+		sent++
+	}
+	return sent
+}
+
+// startFindings 在指定了筛选条件时启动匹配结果的发送协程
+func (app *App) startFindings(run string) {
+	if len(app.filters) == 0 || len(app.notifiers) == 0 {
+		return
+	}
+	queue := &findingQueue{
+		results: make(chan scripts.Result, findingQueueSize),
+		done:    make(chan struct{}),
+	}
+	app.findings = queue
+	go app.sendFindings(run, queue)
+}
+
+// stopFindings 等待已匹配的结果发送完毕
+func (app *App) stopFindings() {
+	if app.findings == nil {
+		return
+	}
+	close(app.findings.results)
+	<-app.findings.done
+	if app.findings.dropped > 0 {
+		log.Warning(fmt.Sprintf("匹配筛选条件的结果过多，%d 个结果未逐条通知", app.findings.dropped))
+	}
+}
+
+// matchFinding 检查结果是否满足任一筛选条件，满足时加入发送队列，调用方需持有 app.mu
+func (app *App) matchFinding(result *scripts.Result) {
+	if app.findings == nil {
+		return
+	}
+	if !slices.ContainsFunc(app.filters, func(filter *scripts.ResultFilter) bool { return filter.Match(result) }) {
+		return
+	}
+
+	app.findings.matched++
+	record := *result
+	record.Image = nil
+	select {
+	case app.findings.results <- record:
+	default:
+		app.findings.dropped++
+	}
+}
+
+// sendFindings 合并 findingBatchDelay 内匹配的结果，每批发送一条通知
+func (app *App) sendFindings(run string, queue *findingQueue) {
+	defer close(queue.done)
+	for first := range queue.results {
+		batch := []scripts.Result{first}
+		timer := time.NewTimer(findingBatchDelay)
+	collect:
+		for len(batch) < maxFindingLines {
+			select {
+			case result, ok := <-queue.results:
+				if !ok {
+					break collect
+				}
+				batch = append(batch, result)
+			case <-timer.C:
+				break collect
+			}
+		}
+		timer.Stop()
+		app.notify(context.Background(), findingNotification(run, batch))
+	}
+}
+
+func findingNotification(run string, results []scripts.Result) scripts.Notification {
+	lines := make([]string, 0, len(results))
+	for _, result := range results {
+		line := fmt.Sprintf("%s %s", result.Status(), result.URL)
+		if result.Title != "" {
+			line += " " + result.Title
+		}
+		lines = append(lines, line)
+	}
+	return scripts.Notification{
+		Event: "scan.finding",
+		Title: fmt.Sprintf("Sowhp %s 发现 %d 个匹配的结果", run, len(results)),
+		Text:  strings.Join(lines, "\n"),
+		Data: map[string]interface{}{
+			"run":     run,
+			"results": results,
+		},
+	}
+}
+
+// notifyRunSummary 在任务结束后发送运行摘要：结果统计、错误类型分布与报告路径
+func (app *App) notifyRunSummary(start time.Time, interrupted bool, runErr error) {
+	if len(app.notifiers) == 0 || app.runPath == "" {
+		return
+	}
+	stats, err := scripts.SummarizeRun(app.runPath)
+	if err != nil {
+		log.Warning(fmt.Sprintf("统计运行结果失败，未发送运行摘要: %v", err))
+		return
+	}
+
+	run := filepath.Base(app.runPath)
+	runDir, _ := filepath.Abs(app.runPath)
+	reports, _ := filepath.Glob(runDir + ".*")
+	duration := time.Since(start).Round(time.Second)
+
+	status, title := "completed", fmt.Sprintf("Sowhp 任务完成: %s", run)
+	switch {
+	case interrupted:
+		status, title = "interrupted", fmt.Sprintf("Sowhp 任务已中断: %s", run)
+	case runErr != nil:
+		status, title = "failed", fmt.Sprintf("Sowhp 任务失败: %s", run)
+	}
+
+	lines := []string{
+		fmt.Sprintf("地址 %d，成功 %d，失败 %d，未处理 %d，耗时 %s", stats.Total, stats.Success, stats.Failed, stats.NotProcessed, duration),
+	}
+	if len(stats.ErrorClasses) > 0 {
+		lines = append(lines, "错误类型: "+formatClassCounts(stats.ErrorClasses))
+	}
+	matched := 0
+	if app.findings != nil {
+		matched = app.findings.matched
+		lines = append(lines, fmt.Sprintf("匹配筛选条件: %d", matched))
+	}
+	if runErr != nil && !interrupted {
+		lines = append(lines, "错误: "+runErr.Error())
+	}
+	if len(reports) > 0 {
+		lines = append(lines, "报告:")
+		lines = append(lines, reports...)
+	} else {
+		lines = append(lines, "运行目录: "+runDir)
+	}
+
+	data := map[string]interface{}{
+		"run":      run,
+		"run_dir":  runDir,
+		"status":   status,
+		"stats":    stats,
+		"duration": duration.String(),
+		"reports":  reports,
+	}
+	if app.findings != nil {
+		data["matched"] = matched
+	}
+	if app.notify(context.Background(), scripts.Notification{
+		Event: "scan.finished",
+		Title: title,
+		Text:  strings.Join(lines, "\n"),
+		Data:  data,
+	}) > 0 {
+		log.Info("已发送运行摘要通知")
+	}
+}
+
+// formatClassCounts 按数量从多到少列出错误类型
+func formatClassCounts(classes map[string]int) string {
+	names := make([]string, 0, len(classes))
+	for name := range classes {
+		names = append(names, name)
+	}
+	sort.Slice(names, func(i, j int) bool {
+		if classes[names[i]] != classes[names[j]] {
+			return classes[names[i]] > classes[names[j]]
+		}
+		return names[i] < names[j]
+	})
+	parts := make([]string, 0, len(names))
+	for _, name := range names {
+		parts = append(parts, fmt.Sprintf("%s %d", name, classes[name]))
+	}
+	return strings.Join(parts, "，")
+}
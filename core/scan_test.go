@@ -0,0 +1,225 @@
+package core
+
+import (
+	"Sowhp/scripts"
+	"Sowhp/sowhp"
+	"bufio"
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// newTestScan 按命令行参数创建 scan 任务，截图引擎替换为 fake
+func newTestScan(t *testing.T, fake *sowhp.FakeCapturer, urls []string, args ...string) *App {
+	t.Helper()
+	dir := t.TempDir()
+	input := filepath.Join(dir, "urls.txt")
+	if err := os.WriteFile(input, []byte(strings.Join(urls, "\n")), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	app := newCommandApp("scan")
+	args = append([]string{"-f", input, "-output-dir", filepath.Join(dir, "result"), "-no-preflight", "-log", "0", "-backoff", "0"}, args...)
+	if err := app.flags.Parse(args); err != nil {
+		t.Fatal(err)
+	}
+	if err := app.parseFlags(); err != nil {
+		t.Fatal(err)
+	}
+	app.options.Capturer = fake
+	app.options.NoAlive = true
+	return app
+}
+
+func readResults(t *testing.T, runDir string) map[string]scripts.Result {
+	t.Helper()
+	file, err := os.Open(filepath.Join(runDir, scripts.ResultsFileName))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer file.Close()
+
+	results := make(map[string]scripts.Result)
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		var result scripts.Result
+		if err := json.Unmarshal(scanner.Bytes(), &result); err != nil {
+			t.Fatalf("%s 格式错误: %v", scripts.ResultsFileName, err)
+		}
+		results[result.URL] = result
+	}
+	return results
+}
+
+func readCSV(t *testing.T, path string) [][]string {
+	t.Helper()
+	file, err := os.Open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer file.Close()
+	rows, err := csv.NewReader(file).ReadAll()
+	if err != nil {
+		t.Fatalf("%s 格式错误: %v", filepath.Base(path), err)
+	}
+	return rows
+}
+
+func TestScanReports(t *testing.T) {
+	scope := filepath.Join(t.TempDir(), "scope.txt")
+	if err := os.WriteFile(scope, []byte("deny blocked.example:443,8443\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	fake := &sowhp.FakeCapturer{
+		Flaky:  map[string]int{"https://flaky.example": 1, "https://down.example": 5},
+		Errors: map[string]string{"https://dns.example": sowhp.ClassDNS},
+	}
+	urls := []string{"ok.example", "flaky.example", "down.example", "dns.example", "ok.example", "https://blocked.example"}
+	app := newTestScan(t, fake, urls, "-retry", "1", "-format", "csv,json", "-scope", scope)
+
+	if err := app.execute(context.Background()); err != nil {
+		t.Fatalf("执行失败: %v", err)
+	}
+
+	results := readResults(t, app.runPath)
+	if len(results) != 4 {
+		t.Fatalf("%s 中有 %d 个结果，应为 4（重复与范围外地址不处理）", scripts.ResultsFileName, len(results))
+	}
+	want := map[string]string{
+		"https://ok.example":    "",
+		"https://flaky.example": "",
+		"https://down.example":  sowhp.ClassTimeout,
+		"https://dns.example":   sowhp.ClassDNS,
+	}
+	for url, class := range want {
+		result, ok := results[url]
+		if !ok {
+			t.Errorf("缺少 %s 的结果", url)
+			continue
+		}
+		if result.ErrorClass != class {
+			t.Errorf("%s 的错误类型为 %q，应为 %q", url, result.ErrorClass, class)
+		}
+		if class == "" {
+			if _, err := os.Stat(filepath.Join(app.runPath, filepath.FromSlash(result.Screenshot))); err != nil {
+				t.Errorf("%s 的截图不存在: %v", url, err)
+			}
+		}
+	}
+	if app.countResult != 2 {
+		t.Errorf("成功数为 %d，应为 2", app.countResult)
+	}
+
+	rows := readCSV(t, app.runPath+".csv")
+	if len(rows) != 5 || rows[0][0] != "Website URL Address" {
+		t.Fatalf("CSV 报告应有表头与 4 行结果，实际为 %d 行", len(rows))
+	}
+	for _, row := range rows[1:] {
+		if class := want[row[0]]; row[4] != class {
+			t.Errorf("CSV 中 %s 的错误类型为 %q，应为 %q", row[0], row[4], class)
+		}
+	}
+
+	rows = readCSV(t, app.runPath+"_out_of_scope.csv")
+	if len(rows) != 2 || len(rows[1]) != 2 || rows[1][0] != "https://blocked.example" || !strings.Contains(rows[1][1], "443,8443") {
+		t.Fatalf("范围外目标报告格式错误: %q", rows)
+	}
+
+	data, err := os.ReadFile(app.runPath + ".json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var report struct {
+		Run     string `json:"run"`
+		Summary struct {
+			Total      int `json:"total"`
+			Success    int `json:"success"`
+			Failed     int `json:"failed"`
+			OutOfScope int `json:"out_of_scope"`
+		} `json:"summary"`
+		Results    []scripts.Result           `json:"results"`
+		OutOfScope []scripts.OutOfScopeTarget `json:"out_of_scope"`
+	}
+	if err := json.Unmarshal(data, &report); err != nil {
+		t.Fatalf("JSON 报告格式错误: %v", err)
+	}
+	if report.Run != filepath.Base(app.runPath) || report.Summary.Total != 4 || report.Summary.Success != 2 ||
+		report.Summary.Failed != 2 || report.Summary.OutOfScope != 1 {
+		t.Errorf("JSON 报告摘要错误: %+v", report.Summary)
+	}
+	if len(report.Results) != 4 || len(report.OutOfScope) != 1 {
+		t.Errorf("JSON 报告中有 %d 个结果、%d 个范围外目标", len(report.Results), len(report.OutOfScope))
+	}
+}
+
+func TestScanResume(t *testing.T) {
+	app := newTestScan(t, &sowhp.FakeCapturer{}, []string{"a.example", "b.example"}, "-format", "csv")
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if err := app.execute(ctx); err == nil {
+		t.Fatal("中断的任务应返回错误")
+	}
+	for url, result := range readResults(t, app.runPath) {
+		if !result.NotProcessed() {
+			t.Fatalf("中断后 %s 应为 NOT_PROCESSED，实际为 %q", url, result.ErrorClass)
+		}
+	}
+
+	// 恢复运行处理上次未处理的地址，并移除 NOT_PROCESSED 记录
+	app.config.Resume = app.runPath
+	if err := app.execute(context.Background()); err != nil {
+		t.Fatalf("恢复运行失败: %v", err)
+	}
+	results := readResults(t, app.runPath)
+	if len(results) != 2 || results["https://a.example"].ErrorClass != "" || results["https://b.example"].ErrorClass != "" {
+		t.Fatalf("恢复运行后的结果错误: %+v", results)
+	}
+	if rows := readCSV(t, app.runPath+".csv"); len(rows) != 3 {
+		t.Errorf("CSV 报告应有表头与 2 行结果，实际为 %d 行", len(rows))
+	}
+}
+
+func TestScanResumeSkipsCompleted(t *testing.T) {
+	fake := &sowhp.FakeCapturer{Errors: map[string]string{"https://b.example": sowhp.ClassDNS}}
+	app := newTestScan(t, fake, []string{"a.example", "b.example"})
+	if err := app.execute(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+
+	// 模拟上次运行在处理 c.example 之前中断
+	input, err := os.OpenFile(filepath.Join(app.runPath, scripts.InputFileName), os.O_WRONLY|os.O_APPEND, 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+	input.WriteString("c.example\n")
+	input.Close()
+	store, err := scripts.OpenResultStore(app.runPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	store.Append(scripts.NotProcessedResult("c.example", "c.example"))
+	store.Close()
+
+	// 已完成的地址（包括失败的）不再处理：a.example 若被重新处理会失败，b.example 若被重新处理会成功
+	app.options.Capturer = &sowhp.FakeCapturer{Errors: map[string]string{"https://a.example": sowhp.ClassTimeout}}
+	app.config.Resume = app.runPath
+	if err := app.execute(context.Background()); err != nil {
+		t.Fatalf("恢复运行失败: %v", err)
+	}
+
+	results := readResults(t, app.runPath)
+	want := map[string]string{"https://a.example": "", "https://b.example": sowhp.ClassDNS, "https://c.example": ""}
+	if len(results) != len(want) {
+		t.Fatalf("恢复运行后有 %d 个结果，应为 %d: %+v", len(results), len(want), results)
+	}
+	for url, class := range want {
+		if result, ok := results[url]; !ok || result.ErrorClass != class {
+			t.Errorf("%s 的错误类型为 %q，应为 %q", url, result.ErrorClass, class)
+		}
+	}
+}
@@ -0,0 +1,41 @@
+package core
+
+import (
+	log "Sowhp/concert/logger"
+	"context"
+	"os"
+	"os/signal"
+	"syscall"
+)
+
+// watchSignals 第一次收到 SIGINT/SIGTERM 时取消根上下文，让任务尽快收尾并生成已完成部分的报告；再次收到时强制退出
+func watchSignals(cancel context.CancelFunc) func() {
+	sigChan := make(chan os.Signal, 2)
+	signal.Notify(sigChan, os.Interrupt, syscall.SIGTERM)
+
+	done := make(chan struct{})
+	go func() {
+		select {
+		case <-sigChan:
+		case <-done:
+			return
+		}
+
+		log.ClearProgressBar()
+		log.Warning("收到中断信号，正在停止任务并生成已完成部分的报告，再次按 Ctrl-C 强制退出")
+		cancel()
+
+		select {
+		case <-sigChan:
+			log.ClearProgressBar()
+			log.Error("再次收到中断信号，强制退出")
+			os.Exit(130)
+		case <-done:
+		}
+	}()
+
+	return func() {
+		signal.Stop(sigChan)
+		close(done)
+	}
+}
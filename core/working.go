@@ -1,13 +1,19 @@
 package core
 
 import (
-	dire "Sowhp/concert"
 	log "Sowhp/concert/logger"
 	"Sowhp/scripts"
+	"Sowhp/sowhp"
+	"context"
 	"errors"
 	"flag"
 	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
 	"sync"
+	"time"
 )
 
 const Banner = `
@@ -27,50 +33,349 @@ const Banner = `
 `
 
 type Config struct {
-	FilePath string
-	LogLevel int
+	FilePath       string
+	LogLevel       int
+	KeepPath       bool
+	ScopeFile      string
+	Engine         string
+	HTTPOnly       bool
+	RemoteCDP      string
+	CaptureBoth    bool
+	Resume         string
+	NoAlive        bool
+	NoPreflight    bool
+	AliveThread    int
+	AliveTime      int
+	ProbeTime      int
+	CheckDir       string
+	Threads        int
+	PageTimeout    int
+	HTTPTimeout    int
+	Retries        int
+	Backoff        time.Duration
+	MaxBackoff     time.Duration
+	Jitter         float64
+	RetryRules     string
+	Rate           float64
+	HostThreads    int
+	HostDelay      time.Duration
+	ConfigFile     string
+	Profile        string
+	PrintConfig    bool
+	Headers        listFlag
+	Proxy          string
+	UserAgent      string
+	ChromeFlags    listFlag
+	Viewport       string
+	Formats        string
+	OutputDir      string
+	RunName        string
+	ScreenshotName string
+	Listen         string
+	Token          string
+	QueueSize      int
+	Browsers       int
+	Tabs           int
+	JobWorkers     int
+	MaxURLs        int
+	Interval       time.Duration
+	Keep           int
+	Webhook        string
+	Threshold      float64
+	Tolerance      int
+	NoVisual       bool
+	Once           bool
+	History        string
+	Notify         listFlag
+	NotifySecrets  listFlag
+	NotifyFilters  listFlag
+	StandaloneMB   int
 }
 
 type App struct {
-	config      *Config
-	resultMap   map[string]map[string][]string
-	arrayMap    map[string][]string
-	count       int
-	countResult int
-	mu          sync.Mutex
+	config       *Config
+	store        *scripts.ResultStore
+	options      sowhp.Options
+	formats      []string
+	reportOpts   scripts.ReportOptions
+	effective    effectiveConfig
+	flags        *flag.FlagSet
+	runPath      string
+	count        int
+	countResult  int
+	countSkipped int
+	notifiers    []namedNotifier
+	filters      []*scripts.ResultFilter
+	findings     *findingQueue
+	mu           sync.Mutex
 }
 
 func NewApp() *App {
 	return &App{
 		config:      &Config{},
-		resultMap:   make(map[string]map[string][]string),
-		arrayMap:    make(map[string][]string),
+		flags:       newFlagSet("scan"),
 		count:       0,
 		countResult: 0,
 	}
 }
 
+// newCommandApp 创建子命令的 App 并注册该子命令的参数
+func newCommandApp(name string) *App {
+	app := NewApp()
+	app.flags = newFlagSet(name)
+	switch name {
+	case "api":
+		app.defineCaptureFlags()
+		app.defineAPIFlags()
+	case "monitor":
+		app.defineMonitorFlags()
+		app.defineNotifyFlags()
+		app.defineCaptureFlags()
+	case "doctor":
+		app.flags.StringVar(&app.config.CheckDir, "dir", "", "需要检查写入权限的结果目录，默认为 -output-dir（可选参数）\n\t\t示例: -dir /data/result")
+		app.defineCaptureFlags()
+	default:
+		app.defineFlags()
+	}
+	return app
+}
+
+// defineFlags 注册 scan 子命令的参数
+func (app *App) defineFlags() {
+	fs := app.flags
+	fs.StringVar(&app.config.FilePath, "f", "", "指定包含URL列表的文本文件路径（必需参数）\n\t\t示例: -f /path/to/urls.txt（每行一个地址）")
+	fs.StringVar(&app.config.Resume, "resume", "", "从中断的运行目录继续执行，跳过已完成的地址并重新生成报告（可选参数，指定后无需 -f）\n\t\t示例: -resume ./result/result_202501010001")
+	fs.BoolVar(&app.config.KeepPath, "keep-path", false, "去重时保留同一主机下的不同路径（可选参数，默认同一主机只截图一次）\n\t\t示例: -keep-path")
+	fs.BoolVar(&app.config.NoPreflight, "no-preflight", false, "跳过任务开始前的浏览器与输出目录检查（可选参数）\n\t\t示例: -no-preflight")
+	fs.StringVar(&app.config.RunName, "run-name", scripts.DefaultRunNameTemplate, "运行目录命名模板，支持 {date} {time} {index}，重名时自动递增（可选参数，默认值: "+scripts.DefaultRunNameTemplate+"）\n\t\t示例: -run-name weekly_{date}_{time}")
+	app.defineNotifyFlags()
+	app.defineCaptureFlags()
+}
+
+// defineCaptureFlags 注册 scan 与 api 子命令共用的截图、请求、输出与配置参数
+func (app *App) defineCaptureFlags() {
+	fs := app.flags
+	fs.BoolVar(&app.config.CaptureBoth, "both", false, "未指定协议的目标同时响应 HTTP 与 HTTPS 且内容不同时两者都截图（可选参数）\n\t\t示例: -both")
+	fs.IntVar(&app.config.Threads, "t", 5, "截图并发数（可选参数，默认值: 5）\n\t\t示例: -t 10")
+	fs.IntVar(&app.config.PageTimeout, "timeout", 30, "单次页面加载与截图超时秒数（可选参数，默认值: 30）\n\t\t示例: -timeout 60")
+	fs.IntVar(&app.config.HTTPTimeout, "http-timeout", 10, "状态码与响应内容请求超时秒数（可选参数，默认值: 10）\n\t\t示例: -http-timeout 5")
+	fs.IntVar(&app.config.Retries, "retry", 1, "失败后的默认重试次数（可选参数，默认值: 1）\n\t\t示例: -retry 2")
+	fs.DurationVar(&app.config.Backoff, "backoff", 2*time.Second, "首次重试前的等待时间，之后按指数增长（可选参数，默认值: 2s）\n\t\t示例: -backoff 500ms")
+	fs.DurationVar(&app.config.MaxBackoff, "max-backoff", 30*time.Second, "重试等待时间上限（可选参数，默认值: 30s）\n\t\t示例: -max-backoff 1m")
+	fs.Float64Var(&app.config.Jitter, "jitter", 0.2, "重试等待时间的随机抖动比例（可选参数，默认值: 0.2）\n\t\t示例: -jitter 0.5")
+	fs.StringVar(&app.config.RetryRules, "retry-rules", "", "按错误类型设置重试次数，覆盖 -retry（可选参数）\n\t\t示例: -retry-rules DNS_ERROR=0,TIMEOUT=2")
+	fs.Float64Var(&app.config.Rate, "rate", 0, "全局每秒请求数上限，0 表示不限制（可选参数）\n\t\t示例: -rate 5")
+	fs.IntVar(&app.config.HostThreads, "host-threads", 0, "同一主机（IP）的最大并发请求数，0 表示不限制（可选参数）\n\t\t示例: -host-threads 1")
+	fs.DurationVar(&app.config.HostDelay, "host-delay", 0, "同一主机（IP）两次请求之间的最小间隔（可选参数）\n\t\t示例: -host-delay 2s")
+	fs.StringVar(&app.config.Engine, "engine", "chrome", "截图引擎: chrome=本地 Chrome，http=仅 HTTP 请求不截图（可选参数，默认值: chrome）\n\t\t示例: -engine http")
+	fs.BoolVar(&app.config.HTTPOnly, "http-only", false, "仅 HTTP 快速模式，不启动浏览器、不截图，只记录状态码、标题、Server 与重定向链，等同于 -engine http（可选参数，未指定 -t 时并发数为 50）\n\t\t示例: -http-only")
+	fs.StringVar(&app.config.RemoteCDP, "cdp", "", "连接已运行的 Chrome 远程调试地址截图，指定后忽略 -engine（可选参数）\n\t\t示例: -cdp ws://127.0.0.1:9222")
+	fs.BoolVar(&app.config.NoAlive, "no-alive", false, "跳过截图前的 TCP 存活检测（可选参数）\n\t\t示例: -no-alive")
+	fs.IntVar(&app.config.AliveThread, "alive-threads", 50, "TCP 存活检测并发数（可选参数，默认值: 50）\n\t\t示例: -alive-threads 100")
+	fs.IntVar(&app.config.AliveTime, "alive-timeout", 3, "TCP 存活检测超时秒数（可选参数，默认值: 3）\n\t\t示例: -alive-timeout 5")
+	fs.IntVar(&app.config.ProbeTime, "probe-timeout", 5, "未指定协议的目标探测 HTTPS/HTTP 的超时秒数（可选参数，默认值: 5）\n\t\t示例: -probe-timeout 10")
+	fs.StringVar(&app.config.ScopeFile, "scope", "", "指定测试范围文件，按 allow/deny 规则过滤目标及重定向（可选参数）\n\t\t示例: -scope scope.txt")
+	fs.IntVar(&app.config.LogLevel, "log", 3, "设置日志输出详细程度（可选参数，默认值: 3）\n\t\t级别说明: 1=错误 2=警告 3=信息 4=调试\n\t\t示例: -log 4")
+	fs.StringVar(&app.config.ConfigFile, "config", "", "指定配置文件，支持 YAML/TOML/JSON，未指定时自动加载 ./sowhp.yaml（可选参数）\n\t\t示例: -config sowhp.toml")
+	fs.StringVar(&app.config.Profile, "profile", "", "使用配置文件 profiles 中的命名配置方案（可选参数）\n\t\t示例: -profile internal-fast")
+	fs.BoolVar(&app.config.PrintConfig, "print-config", false, "输出合并命令行、环境变量与配置文件后的最终配置及来源，然后退出（可选参数）\n\t\t示例: -print-config")
+	fs.Var(&app.config.Headers, "header", "附加到每个请求（包括浏览器访问）的请求头，可重复指定（可选参数）\n\t\t示例: -header \"Cookie: a=b\" -header \"X-Forwarded-For: 127.0.0.1\"")
+	fs.StringVar(&app.config.Proxy, "proxy", "", "HTTP 请求与 Chrome 使用的代理，必须包含协议，指定后跳过 TCP 存活检测（可选参数）\n\t\t示例: -proxy socks5://127.0.0.1:1080")
+	fs.StringVar(&app.config.UserAgent, "user-agent", "", "覆盖 HTTP 请求与浏览器的 User-Agent（可选参数）\n\t\t示例: -user-agent \"Mozilla/5.0 ...\"")
+	fs.Var(&app.config.ChromeFlags, "chrome-flag", "启动本地 Chrome 时附加的命令行参数，可重复指定，不带值表示开关参数（可选参数）\n\t\t示例: -chrome-flag lang=zh-CN -chrome-flag disable-extensions")
+	fs.StringVar(&app.config.Viewport, "viewport", "1920x1080", "截图窗口大小，格式为 宽x高（可选参数，默认值: 1920x1080）\n\t\t示例: -viewport 1366x768")
+	fs.StringVar(&app.config.OutputDir, "output-dir", "./result", "结果输出目录，运行目录与报告均写入该目录（可选参数，默认值: ./result）\n\t\t示例: -output-dir /data/sowhp")
+	fs.StringVar(&app.config.ScreenshotName, "screenshot-name", scripts.DefaultScreenshotNameTemplate, "截图文件命名模板，支持 {host} {port} {scheme} {path} {hash} {date} {time} {index} {run}，重名时自动追加序号（可选参数，默认值: "+scripts.DefaultScreenshotNameTemplate+"）\n\t\t示例: -screenshot-name {index}_{host}")
+	fs.StringVar(&app.config.Formats, "format", strings.Join(scripts.ReportFormats, ","), "生成的报告格式，多个以逗号分隔，standalone 为内嵌截图的独立 HTML 报告（可选参数，默认值: csv,html,json）\n\t\t示例: -format html,standalone")
+	fs.IntVar(&app.config.StandaloneMB, "standalone-limit", scripts.DefaultStandaloneLimit>>20, "单个独立报告文件的大小上限（MB），超出时拆分为多个文件（可选参数，默认值: 15）\n\t\t示例: -standalone-limit 8")
+}
+
+// parseFlags 在命令行参数解析完成后合并配置文件并校验参数
 func (app *App) parseFlags() error {
-	flag.StringVar(&app.config.FilePath, "f", "", "指定包含URL列表的文本文件路径（必需参数）\n\t\t示例: -f /path/to/urls.txt（每行一个地址）")
-	flag.IntVar(&app.config.LogLevel, "log", 3, "设置日志输出详细程度（可选参数，默认值: 3）\n\t\t级别说明: 1=错误 2=警告 3=信息 4=调试\n\t\t示例: -log 4")
-	print(Banner)
-	flag.Parse()
+	log.LogLevel = app.config.LogLevel
+	if err := app.loadConfig(); err != nil {
+		return err
+	}
+	log.LogLevel = app.config.LogLevel
+	if app.config.PrintConfig {
+		return nil
+	}
 
-	if app.config.FilePath == "" {
+	// api 没有 -f；monitor 使用 -history 查看历史记录时无需地址文件
+	if app.flags.Lookup("f") != nil && app.config.FilePath == "" && app.config.Resume == "" && app.config.History == "" {
 		return errors.New("文件路径不能为空")
 	}
 
-	log.LogLevel = app.config.LogLevel
+	if app.config.HTTPOnly {
+		app.config.Engine = "http"
+	}
+	if app.config.Engine == "http" && app.effective.sources["t"] == sourceDefault {
+		app.config.Threads = httpOnlyThreads
+	}
+	app.options.Logger = sowhp.ConsoleLogger{}
+	app.options.Threads = app.config.Threads
+	app.options.AliveThreads = app.config.AliveThread
+	app.options.NoAlive = app.config.NoAlive
+	app.options.CaptureBoth = app.config.CaptureBoth
+	app.options.AliveTimeout = time.Duration(app.config.AliveTime) * time.Second
+	app.options.ProbeTimeout = time.Duration(app.config.ProbeTime) * time.Second
+	app.options.PageTimeout = time.Duration(app.config.PageTimeout) * time.Second
+	app.options.HTTPTimeout = time.Duration(app.config.HTTPTimeout) * time.Second
+
+	if app.config.Threads < 1 {
+		return errors.New("截图并发数必须大于 0")
+	}
+
+	if err := app.parseRequestOptions(); err != nil {
+		return err
+	}
+	if app.flags.Lookup("run-name") != nil {
+		if err := scripts.ValidateRunNameTemplate(app.config.RunName); err != nil {
+			return err
+		}
+	}
+	if err := scripts.ValidateScreenshotNameTemplate(app.config.ScreenshotName); err != nil {
+		return err
+	}
+	app.options.ScreenshotName = app.config.ScreenshotName
+
+	app.options.Limiter = sowhp.NewRateLimiter(app.config.Rate, app.config.HostThreads, app.config.HostDelay)
+
+	rules, err := sowhp.ParseRetryRules(app.config.RetryRules)
+	if err != nil {
+		return err
+	}
+	app.options.Retry = &sowhp.RetryPolicy{
+		Retries:    app.config.Retries,
+		Backoff:    app.config.Backoff,
+		MaxBackoff: app.config.MaxBackoff,
+		Jitter:     app.config.Jitter,
+		Rules:      rules,
+	}
+
+	capturer, err := app.capturer()
+	if err != nil {
+		return err
+	}
+	app.options.Capturer = capturer
+
+	if app.config.ScopeFile != "" {
+		scope, err := sowhp.LoadScope(app.config.ScopeFile)
+		if err != nil {
+			return err
+		}
+		app.options.Scope = scope
+	}
+
+	if app.flags.Lookup("notify") != nil {
+		return app.parseNotifyFlags()
+	}
 	return nil
 }
 
-func Run() error {
-	app := NewApp()
+// parseRequestOptions 解析请求头、代理、浏览器参数、窗口大小与报告格式
+func (app *App) parseRequestOptions() error {
+	if len(app.config.Headers) > 0 {
+		app.options.Headers = make(map[string]string)
+	}
+	for _, header := range app.config.Headers {
+		name, value, ok := strings.Cut(header, ":")
+		if !ok || strings.TrimSpace(name) == "" {
+			return fmt.Errorf("请求头格式错误，应为 \"名称: 值\": %s", header)
+		}
+		app.options.Headers[strings.TrimSpace(name)] = strings.TrimSpace(value)
+	}
+
+	if len(app.config.ChromeFlags) > 0 {
+		app.options.ChromeFlags = make(map[string]string)
+	}
+	for _, chromeFlag := range app.config.ChromeFlags {
+		name, value, _ := strings.Cut(strings.TrimLeft(chromeFlag, "-"), "=")
+		if name == "" {
+			return fmt.Errorf("Chrome 参数格式错误，应为 名称=值: %s", chromeFlag)
+		}
+		app.options.ChromeFlags[name] = value
+	}
+
+	if app.config.Proxy != "" {
+		if _, err := scripts.ParseProxy(app.config.Proxy); err != nil {
+			return err
+		}
+	}
+	app.options.Proxy = app.config.Proxy
+	app.options.UserAgent = app.config.UserAgent
+
+	w, h, err := parseViewport(app.config.Viewport)
+	if err != nil {
+		return err
+	}
+	app.options.ViewportWidth, app.options.ViewportHeight = w, h
+
+	app.formats = nil
+	for _, format := range strings.Split(app.config.Formats, ",") {
+		format = strings.ToLower(strings.TrimSpace(format))
+		if format == "" {
+			continue
+		}
+		if !scripts.IsReportFormat(format) {
+			return fmt.Errorf("不支持的报告格式: %s", format)
+		}
+		app.formats = append(app.formats, format)
+	}
+	if len(app.formats) == 0 {
+		return errors.New("至少需要指定一种报告格式")
+	}
+	if app.config.StandaloneMB < 1 {
+		return errors.New("独立报告大小上限必须大于 0")
+	}
+	app.reportOpts = scripts.ReportOptions{StandaloneLimit: int64(app.config.StandaloneMB) << 20}
+	return nil
+}
+
+// parseViewport 解析 宽x高 格式的窗口大小
+func parseViewport(viewport string) (int, int, error) {
+	width, height, ok := strings.Cut(strings.ToLower(viewport), "x")
+	w, wErr := strconv.Atoi(strings.TrimSpace(width))
+	h, hErr := strconv.Atoi(strings.TrimSpace(height))
+	if !ok || wErr != nil || hErr != nil || w < 1 || h < 1 {
+		return 0, 0, fmt.Errorf("窗口大小格式错误，应为 宽x高: %s", viewport)
+	}
+	return w, h, nil
+}
+
+// capturer 根据 -engine 与 -cdp 选择截图引擎
+func (app *App) capturer() (sowhp.Capturer, error) {
+	if app.config.RemoteCDP != "" {
+		return sowhp.RemoteCapturer{URL: app.config.RemoteCDP}, nil
+	}
+
+	switch app.config.Engine {
+	case "", "chrome":
+		return sowhp.ChromeCapturer{}, nil
+	case "http":
+		return sowhp.HTTPCapturer{}, nil
+	}
+	return nil, fmt.Errorf("未知的截图引擎: %s", app.config.Engine)
+}
+
+// httpOnlyThreads 为仅 HTTP 模式未指定 -t 时的并发数
+const httpOnlyThreads = 50
+
+// runScan 为 scan 子命令：读取地址列表截图并生成报告
+func runScan(args []string) error {
+	app := newCommandApp("scan")
+	if err := app.flags.Parse(args); err != nil {
+		return parseError(err)
+	}
 	if err := app.parseFlags(); err != nil {
-		flag.Usage()
+		app.flags.Usage()
 		log.Error(err.Error())
 		return err
 	}
+	if app.config.PrintConfig {
+		app.printConfig()
+		return nil
+	}
 
 	log.Debug(fmt.Sprintf("当前输入路径为：%s", app.config.FilePath))
 	return app.run()
@@ -81,111 +386,279 @@ func isValidPath(path string) bool {
 }
 
 func (app *App) run() error {
-	urls := scripts.FindTextUrl(app.config.FilePath)
-	if len(urls) == 0 {
-		return errors.New("未能从文件中获取到有效的 URL 列表")
+	if !app.config.NoPreflight {
+		if err := app.preflight(); err != nil {
+			return err
+		}
 	}
 
-	log.Debug(fmt.Sprint("已获取 URL 列表：", urls))
-	resultName := fmt.Sprintf("result_%s", scripts.GetTimeStrin())
-	total := len(urls)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	stopSignals := watchSignals(cancel)
+	defer stopSignals()
+
+	start := time.Now()
+	err := app.execute(ctx)
+	app.notifyRunSummary(start, ctx.Err() != nil, err)
+	return err
+}
 
-	if err := app.createDirectories(resultName); err != nil {
-		return fmt.Errorf("创建目录失败: %w", err)
+// execute 创建或恢复运行目录，处理全部地址并生成报告，ctx 被取消时生成已完成部分的报告后返回错误
+func (app *App) execute(ctx context.Context) error {
+	var resultName string
+	var completed map[uint64]struct{}
+	var total int
+	app.count, app.countResult, app.countSkipped = 0, 0, 0
+	app.findings = nil
+
+	if app.config.Resume != "" {
+		var err error
+		resultName, completed, total, err = app.resume(app.config.Resume)
+		if err != nil {
+			return err
+		}
+	} else {
+		var err error
+		resultName, total, err = app.prepareRun()
+		if err != nil {
+			return err
+		}
 	}
 
-	if err := app.processURLs(urls, resultName, total); err != nil {
-		return fmt.Errorf("处理截图失败: %w", err)
+	store, err := scripts.OpenResultStore(app.runPath)
+	if err != nil {
+		return err
+	}
+	defer store.Close()
+	app.store = store
+
+	if total > 0 {
+		app.startFindings(resultName)
+		err := app.processURLs(ctx, resultName, completed, total)
+		app.stopFindings()
+		if err != nil {
+			return fmt.Errorf("处理截图失败: %w", err)
+		}
 	}
 
-	app.resultMap[resultName] = app.arrayMap
-	log.Info(fmt.Sprintf("处理完成，成功截图 %d 个网站", app.countResult))
-	if err := scripts.CreateHtml(app.resultMap); err != nil {
+	action := "截图"
+	if app.config.Engine == "http" {
+		action = "访问"
+	}
+	if ctx.Err() != nil {
+		log.Warning(fmt.Sprintf("任务已中断，成功%s %d 个网站，未处理 %d 个地址", action, app.countResult, app.countSkipped))
+	} else {
+		log.Info(fmt.Sprintf("处理完成，成功%s %d 个网站", action, app.countResult))
+	}
+	if err := scripts.CreateRunReports(app.runPath, app.formats, app.reportOpts); err != nil {
 		return fmt.Errorf("生成报告失败: %w", err)
 	}
 
+	if ctx.Err() != nil {
+		return errors.New("任务被中断")
+	}
 	return nil
 }
 
-func (app *App) createDirectories(resultName string) error {
-	dire.MkdirResport()
-	dire.Dir_mk(fmt.Sprintf("./result/%s", resultName))
-	dire.Dir_mk(fmt.Sprintf("./result/%s/%s", resultName, "data"))
-	return nil
+// prepareRun 流式读取地址文件，去重并按范围过滤后写入运行目录的地址列表，返回待处理地址数
+func (app *App) prepareRun() (string, int, error) {
+	runDir, err := scripts.CreateRunDir(app.config.OutputDir, app.config.RunName)
+	if err != nil {
+		return "", 0, err
+	}
+	app.runPath = runDir
+	resultName := filepath.Base(runDir)
+
+	stats, err := writeInputs(runDir, app.config.KeepPath, app.options.Scope, func(add func(string) error) error {
+		return scripts.ScanTextUrl(app.config.FilePath, add)
+	})
+	if err == nil && stats.extracted == 0 {
+		err = errors.New("未能从文件中获取到有效的 URL 列表")
+	}
+	if err == nil && stats.total == 0 {
+		err = errors.New("范围过滤后没有可处理的 URL")
+	}
+	if err != nil {
+		os.RemoveAll(runDir)
+		app.runPath = ""
+		return "", 0, err
+	}
+
+	scripts.ReportDuplicates(stats.extracted, stats.duplicates)
+	if stats.outOfScope > 0 {
+		log.Warning(fmt.Sprintf("共有 %d 个地址超出测试范围，已跳过", stats.outOfScope))
+	}
+	return resultName, stats.total, nil
+}
+
+// inputStats 为写入地址列表时的统计
+type inputStats struct {
+	extracted  int
+	duplicates int
+	outOfScope int
+	total      int
 }
 
-func (app *App) processURLs(urls []string, resultName string, total int) error {
-	maxConcurrency := 5
-	if total < maxConcurrency {
-		maxConcurrency = total
+// writeInputs 对 scan 产生的地址去重并按范围过滤，写入运行目录的地址列表与范围外目标列表
+func writeInputs(runDir string, keepPath bool, scope *sowhp.Scope, scan func(add func(string) error) error) (inputStats, error) {
+	var stats inputStats
+	inputs, err := scripts.CreateInputList(runDir)
+	if err != nil {
+		return stats, err
 	}
+	outOfScope, err := scripts.CreateOutOfScope(runDir)
+	if err != nil {
+		inputs.Close()
+		return stats, err
+	}
+
+	deduper := scripts.NewDeduper(keepPath)
+	err = scan(func(url string) error {
+		stats.extracted++
+		canonical, dup := deduper.Add(url)
+		if dup != nil {
+			return nil
+		}
 
-	urlChan := make(chan string, len(urls))
-	resultChan := make(chan ScreenshotResult, len(urls))
+		if ok, reason := scripts.CheckTarget(scope, canonical); !ok {
+			log.Debug(fmt.Sprintf("超出范围: %s - %s", canonical, reason))
+			return outOfScope.Add(scripts.OutOfScopeTarget{URL: canonical, Reason: reason})
+		}
 
-	var wg sync.WaitGroup
-	for i := 0; i < maxConcurrency; i++ {
-		wg.Add(1)
-		go app.screenshotWorker(&wg, urlChan, resultChan, resultName)
+		stats.total++
+		return inputs.Add(canonical)
+	})
+	if closeErr := inputs.Close(); err == nil {
+		err = closeErr
+	}
+	if closeErr := outOfScope.Close(); err == nil {
+		err = closeErr
+	}
+
+	stats.duplicates = deduper.Duplicates
+	stats.outOfScope = outOfScope.Count
+	return stats, err
+}
+
+// resume 从已有运行目录恢复：整理已写入的结果，返回已完成地址集合与剩余地址数
+func (app *App) resume(runDir string) (string, map[uint64]struct{}, int, error) {
+	absDir, err := filepath.Abs(runDir)
+	if err != nil {
+		return "", nil, 0, err
+	}
+	if _, err := os.Stat(filepath.Join(absDir, scripts.InputFileName)); err != nil {
+		return "", nil, 0, fmt.Errorf("不是有效的运行目录 %s: %w", runDir, err)
+	}
+	app.runPath = absDir
+	resultName := filepath.Base(absDir)
+
+	completed, err := scripts.CompactResults(absDir)
+	if err != nil {
+		return "", nil, 0, err
+	}
+	err = scripts.ScanResults(absDir, func(record scripts.Result) error {
+		if record.Success() {
+			app.countResult++
+		}
+		return nil
+	})
+	if err != nil {
+		return "", nil, 0, err
 	}
 
-	for _, url := range urls {
-		urlChan <- url
+	total, remaining := 0, 0
+	err = scripts.ScanInputList(absDir, func(url string) error {
+		total++
+		if !scripts.IsCompleted(completed, url) {
+			remaining++
+		}
+		return nil
+	})
+	if err != nil {
+		return "", nil, 0, err
 	}
-	close(urlChan)
 
+	log.Info(fmt.Sprintf("恢复运行 %s: 共 %d 个地址，已完成 %d 个，剩余 %d 个", resultName, total, total-remaining, remaining))
+	return resultName, completed, remaining, nil
+}
+
+// interleaveWindow 为按主机交错排序时每批读取的地址数，保证输入按批流式读取
+const interleaveWindow = 1000
+
+func (app *App) processURLs(ctx context.Context, resultName string, completed map[uint64]struct{}, total int) error {
+	options := app.options
+	options.OutputDir = app.runPath
+	options.Name = resultName
+	if total < options.Threads {
+		options.Threads = total
+	}
+	if total < options.AliveThreads {
+		options.AliveThreads = total
+	}
+	client := sowhp.New(options)
+
+	urlChan := make(chan string, options.Threads)
+	resultChan := client.CaptureBatches(ctx, urlChan)
+
+	var feedErr error
 	go func() {
-		wg.Wait()
-		close(resultChan)
+		defer close(urlChan)
+		feedErr = app.feedURLs(ctx, app.runPath, completed, urlChan)
 	}()
 
-	for result := range resultChan {
+	for results := range resultChan {
 		app.mu.Lock()
 		app.count++
 
 		log.ClearProgressBar()
 
-		if result.Success {
-			app.countResult++
-			log.Common(fmt.Sprintf("%s %s", log.LightGreen("[√]"), result.URL))
-			app.arrayMap[result.URL] = []string{result.Data[1], result.Data[2], result.Data[3], result.Data[4]}
-		} else {
-			log.Common(fmt.Sprintf("%s %s - %s", log.LightRed("[×]"), result.URL, result.Error))
-			app.arrayMap[result.URL] = []string{"无标题", "连接失败", "data/", result.Error}
+		records := make([]scripts.Result, 0, len(results))
+		for _, result := range results {
+			records = append(records, *result)
+			switch {
+			case result.Success():
+				app.countResult++
+				log.Common(fmt.Sprintf("%s %s", log.LightGreen("[√]"), result.URL))
+			case result.NotProcessed():
+				app.countSkipped++
+			default:
+				log.Common(fmt.Sprintf("%s %s - %s", log.LightRed("[×]"), result.URL, result.Error))
+			}
+			app.matchFinding(result)
+		}
+
+		// 未处理的记录同样写入，保证报告完整；恢复运行时会先将其移除
+		if err := app.store.Append(records...); err != nil {
+			log.Warning(err.Error())
 		}
 
 		log.ShowProgressBar(app.count, total, "执行进度")
 		app.mu.Unlock()
 	}
 
-	return nil
+	return feedErr
 }
 
-type ScreenshotResult struct {
-	URL     string
-	Success bool
-	Data    []string
-	Error   string
-}
-
-func (app *App) screenshotWorker(wg *sync.WaitGroup, urlChan <-chan string, resultChan chan<- ScreenshotResult, resultName string) {
-	defer wg.Done()
-
-	for url := range urlChan {
-		urlResultList := scripts.SmartScreenshot(url, resultName)
-
-		result := ScreenshotResult{
-			URL: url,
+// feedURLs 逐批读取地址列表，跳过已完成的地址，并在每批内按主机交错后送入处理队列
+func (app *App) feedURLs(ctx context.Context, runDir string, completed map[uint64]struct{}, urlChan chan<- string) error {
+	batch := make([]string, 0, interleaveWindow)
+	flush := func() {
+		for _, url := range scripts.InterleaveByHost(ctx, batch, app.options.Limiter) {
+			urlChan <- url
 		}
+		batch = batch[:0]
+	}
 
-		if len(urlResultList) == 0 {
-			result.Success = false
-			result.Error = "截图失败或网络超时"
-		} else {
-			result.Success = true
-			result.Data = urlResultList
+	err := scripts.ScanInputList(runDir, func(url string) error {
+		if scripts.IsCompleted(completed, url) {
+			return nil
 		}
-
-		resultChan <- result
-	}
+		batch = append(batch, url)
+		if len(batch) >= interleaveWindow {
+			flush()
+		}
+		return nil
+	})
+	flush()
+	return err
 }
@@ -2,10 +2,15 @@ package scripts
 
 import (
 	log "Sowhp/concert/logger"
+	"bufio"
+	"encoding/csv"
 	"encoding/json"
 	"fmt"
 	"os"
 	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
 )
 
 type ReportGenerator struct {
@@ -13,6 +18,12 @@ type ReportGenerator struct {
 	resultDir  string
 }
 
+type reportStats struct {
+	total      int
+	success    int
+	outOfScope int
+}
+
 func NewReportGenerator(resultName string) *ReportGenerator {
 	return &ReportGenerator{
 		resultName: resultName,
@@ -20,36 +31,152 @@ func NewReportGenerator(resultName string) *ReportGenerator {
 	}
 }
 
-func CreateHtml(resultMap map[string]map[string][]string) error {
-	if len(resultMap) == 0 {
-		return fmt.Errorf("结果数据为空，无法生成报告")
+// ReportFormats 为默认生成的报告格式，另可指定 standalone 生成内嵌截图的独立报告
+var ReportFormats = []string{"csv", "html", "json"}
+
+// CreateHtml 基于运行目录中的结果文件生成全部格式的报告
+func CreateHtml(resultName string) error {
+	return CreateReports(resultName, ReportFormats)
+}
+
+// CreateReports 基于运行目录中的结果文件生成指定格式（csv、html、json）的报告
+func CreateReports(resultName string, formats []string) error {
+	if resultName == "" {
+		return fmt.Errorf("结果名称为空，无法生成报告")
+	}
+	return CreateRunReports(filepath.Join("./result", resultName), formats, ReportOptions{})
+}
+
+// CreateRunReports 为指定的运行目录重新生成报告，报告写入运行目录的上一级目录
+func CreateRunReports(runDir string, formats []string, opts ReportOptions) error {
+	runDir = filepath.Clean(runDir)
+	if _, err := os.Stat(filepath.Join(runDir, ResultsFileName)); err != nil {
+		return fmt.Errorf("不是有效的运行目录 %s: %w", runDir, err)
+	}
+
+	enabled := make(map[string]bool, len(formats))
+	for _, format := range formats {
+		format = strings.ToLower(strings.TrimSpace(format))
+		if !IsReportFormat(format) {
+			return fmt.Errorf("不支持的报告格式: %s", format)
+		}
+		enabled[format] = true
 	}
 
-	var resultName string
-	for name := range resultMap {
-		resultName = name
-		break
+	generator := &ReportGenerator{
+		resultName: filepath.Base(runDir),
+		resultDir:  filepath.Dir(runDir),
 	}
+	return generator.generateReports(enabled, opts)
+}
 
-	generator := NewReportGenerator(resultName)
-	return generator.generateReports(resultMap[resultName])
+func isReportFormat(format string) bool {
+	for _, known := range ReportFormats {
+		if format == known {
+			return true
+		}
+	}
+	return false
 }
 
-func (rg *ReportGenerator) generateReports(data map[string][]string) error {
-	if err := rg.generateTextReport(data); err != nil {
-		log.Error(fmt.Sprintf("生成文本报告失败: %v", err))
-		return err
+func (rg *ReportGenerator) runDir() string {
+	return filepath.Join(rg.resultDir, rg.resultName)
+}
+
+func (rg *ReportGenerator) collectStats() (reportStats, error) {
+	var stats reportStats
+	err := ScanResults(rg.runDir(), func(record Result) error {
+		stats.total++
+		if record.Success() {
+			stats.success++
+		}
+		return nil
+	})
+	if err != nil {
+		return stats, err
 	}
 
-	if err := rg.generateHTMLReport(data); err != nil {
-		log.Error(fmt.Sprintf("生成HTML报告失败: %v", err))
+	err = ScanOutOfScope(rg.runDir(), func(OutOfScopeTarget) error {
+		stats.outOfScope++
+		return nil
+	})
+	return stats, err
+}
+
+// RunStats 为运行目录的结果统计，ErrorClasses 为各错误类型的失败数
+type RunStats struct {
+	Total        int            `json:"total"`
+	Success      int            `json:"success"`
+	Failed       int            `json:"failed"`
+	NotProcessed int            `json:"not_processed"`
+	ErrorClasses map[string]int `json:"error_classes"`
+}
+
+// SummarizeRun 统计运行目录中的结果
+func SummarizeRun(runDir string) (RunStats, error) {
+	stats := RunStats{ErrorClasses: make(map[string]int)}
+	err := ScanResults(runDir, func(record Result) error {
+		stats.Total++
+		switch {
+		case record.Success():
+			stats.Success++
+		case record.NotProcessed():
+			stats.NotProcessed++
+		default:
+			stats.Failed++
+			stats.ErrorClasses[record.ErrorClass]++
+		}
+		return nil
+	})
+	return stats, err
+}
+
+func (rg *ReportGenerator) generateReports(enabled map[string]bool, opts ReportOptions) error {
+	stats, err := rg.collectStats()
+	if err != nil {
 		return err
 	}
+	if stats.total == 0 && stats.outOfScope == 0 {
+		return fmt.Errorf("结果数据为空，无法生成报告")
+	}
+
+	if enabled["csv"] {
+		if err := rg.generateTextReport(); err != nil {
+			log.Error(fmt.Sprintf("生成文本报告失败: %v", err))
+			return err
+		}
+
+		if err := rg.generateOutOfScopeReport(stats); err != nil {
+			log.Error(fmt.Sprintf("生成范围外目标报告失败: %v", err))
+			return err
+		}
+	}
+
+	if enabled["json"] {
+		if err := rg.generateJSONReport(stats); err != nil {
+			log.Error(fmt.Sprintf("生成JSON报告失败: %v", err))
+			return err
+		}
+	}
+
+	if enabled["html"] {
+		if err := rg.generateHTMLReport(stats); err != nil {
+			log.Error(fmt.Sprintf("生成HTML报告失败: %v", err))
+			return err
+		}
+	}
+
+	if enabled[StandaloneFormat] {
+		if err := rg.generateStandaloneReport(stats, opts.StandaloneLimit); err != nil {
+			log.Error(fmt.Sprintf("生成独立报告失败: %v", err))
+			return err
+		}
+	}
 
 	return nil
 }
 
-func (rg *ReportGenerator) generateTextReport(data map[string][]string) error {
+func (rg *ReportGenerator) generateTextReport() error {
 	csvPath := filepath.Join(rg.resultDir, rg.resultName+".csv")
 
 	file, err := os.OpenFile(csvPath, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0644)
@@ -62,39 +189,185 @@ func (rg *ReportGenerator) generateTextReport(data map[string][]string) error {
 		}
 	}()
 
-	header := "Website URL Address,Title Name,Status,Screenshot Path\n"
-	if _, err := file.WriteString(header); err != nil {
+	writer := csv.NewWriter(file)
+	header := []string{"Website URL Address", "Final URL", "Title Name", "Status", "Error Class", "Server", "Content Length", "Redirect Chain", "Screenshot Path"}
+	if err := writer.Write(header); err != nil {
 		return fmt.Errorf("写入CSV报告表头失败: %w", err)
 	}
 
-	for url, info := range data {
-		if len(info) < 3 {
-			log.Warning(fmt.Sprintf("URL %s 的信息不完整，跳过", url))
-			continue
+	err = ScanResults(rg.runDir(), func(record Result) error {
+		var contentLength string
+		if record.ContentLength > 0 {
+			contentLength = strconv.FormatInt(record.ContentLength, 10)
 		}
+		var redirects []string
+		for _, hop := range record.RedirectChain {
+			redirects = append(redirects, fmt.Sprintf("%d %s", hop.StatusCode, hop.URL))
+		}
+
+		row := []string{
+			record.URL,
+			record.FinalURL,
+			record.Title,
+			record.Status(),
+			record.ErrorClass,
+			record.Server,
+			contentLength,
+			strings.Join(redirects, " -> "),
+			record.Screenshot,
+		}
+
+		if err := writer.Write(row); err != nil {
+			return fmt.Errorf("写入数据行失败: %w", err)
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	writer.Flush()
+	return writer.Error()
+}
+
+// generateJSONReport 生成供其他工具读取的 JSON 报告，截图路径相对于运行目录
+func (rg *ReportGenerator) generateJSONReport(stats reportStats) error {
+	jsonPath := filepath.Join(rg.resultDir, rg.resultName+".json")
+
+	file, err := os.OpenFile(jsonPath, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0644)
+	if err != nil {
+		return fmt.Errorf("创建JSON报告文件失败: %w", err)
+	}
+	defer func() {
+		if closeErr := file.Close(); closeErr != nil {
+			log.Warning(fmt.Sprintf("关闭JSON报告文件失败: %v", closeErr))
+		}
+	}()
+
+	header, err := json.Marshal(map[string]interface{}{
+		"schema_version": ResultSchemaVersion,
+		"run":            rg.resultName,
+		"generated_at":   time.Now(),
+		"summary": map[string]int{
+			"total":        stats.total,
+			"success":      stats.success,
+			"failed":       stats.total - stats.success,
+			"out_of_scope": stats.outOfScope,
+		},
+	})
+	if err != nil {
+		return fmt.Errorf("JSON编码失败: %w", err)
+	}
+
+	writer := bufio.NewWriter(file)
+	// 去掉结尾的 }，随后流式写入结果数组
+	writer.Write(header[:len(header)-1])
+
+	writer.WriteString(`,"results":`)
+	err = writeJSONArray(writer, func(emit func(interface{}) error) error {
+		return ScanResults(rg.runDir(), func(record Result) error {
+			return emit(record)
+		})
+	})
+	if err != nil {
+		return err
+	}
+
+	writer.WriteString(`,"out_of_scope":`)
+	err = writeJSONArray(writer, func(emit func(interface{}) error) error {
+		return ScanOutOfScope(rg.runDir(), func(target OutOfScopeTarget) error {
+			return emit(target)
+		})
+	})
+	if err != nil {
+		return err
+	}
+
+	writer.WriteString("}\n")
+	if err := writer.Flush(); err != nil {
+		return fmt.Errorf("写入JSON报告失败: %w", err)
+	}
+	return nil
+}
 
-		titleName := info[0]
-		status := info[1]
-		screenshotPath := info[2]
+// writeJSONArray 将 scan 逐条产生的数据写为 JSON 数组，不在内存中保留完整数组
+func writeJSONArray(writer *bufio.Writer, scan func(emit func(interface{}) error) error) error {
+	writer.WriteString("[")
+	first := true
+	err := scan(func(v interface{}) error {
+		jsonData, err := json.Marshal(v)
+		if err != nil {
+			return fmt.Errorf("JSON编码失败: %w", err)
+		}
 
-		line := fmt.Sprintf("%s,%s,%s,%s\n",
-			url,
-			titleName,
-			status,
-			screenshotPath)
+		if !first {
+			writer.WriteString(",")
+		}
+		first = false
+		_, err = writer.Write(jsonData)
+		return err
+	})
+	writer.WriteString("]")
+	return err
+}
+
+func (rg *ReportGenerator) generateOutOfScopeReport(stats reportStats) error {
+	if stats.outOfScope == 0 {
+		return nil
+	}
 
-		if _, err := file.WriteString(line); err != nil {
+	csvPath := filepath.Join(rg.resultDir, rg.resultName+"_out_of_scope.csv")
+	file, err := os.OpenFile(csvPath, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0644)
+	if err != nil {
+		return fmt.Errorf("创建范围外目标报告文件失败: %w", err)
+	}
+	defer func() {
+		if closeErr := file.Close(); closeErr != nil {
+			log.Warning(fmt.Sprintf("关闭范围外目标报告文件失败: %v", closeErr))
+		}
+	}()
+
+	writer := csv.NewWriter(file)
+	if err := writer.Write([]string{"Website URL Address", "Reason"}); err != nil {
+		return fmt.Errorf("写入范围外目标报告表头失败: %w", err)
+	}
+
+	err = ScanOutOfScope(rg.runDir(), func(target OutOfScopeTarget) error {
+		if err := writer.Write([]string{target.URL, target.Reason}); err != nil {
 			return fmt.Errorf("写入数据行失败: %w", err)
 		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	writer.Flush()
+	if err := writer.Error(); err != nil {
+		return err
 	}
 
+	log.Info(fmt.Sprintf("范围外目标已单独记录: %s", csvPath))
 	return nil
 }
 
-func (rg *ReportGenerator) generateHTMLReport(data map[string][]string) error {
-	htmlPath := filepath.Join(rg.resultDir, rg.resultName+".html")
+// htmlReportItem 为 HTML 报告中的一行；独立报告中 Screenshot 为缩略图、Image 为原图的 data URI
+type htmlReportItem struct {
+	URL        string `json:"url"`
+	Title      string `json:"title"`
+	Status     string `json:"status"`
+	ErrorClass string `json:"errorClass"`
+	Screenshot string `json:"screenshot"`
+	Image      string `json:"image,omitempty"`
+	Response   string `json:"response"`
+}
 
-	// 构建HTML内容
+type htmlOutOfScopeItem struct {
+	URL    string `json:"url"`
+	Reason string `json:"reason"`
+}
+
+// htmlReportHead 返回 HTML 报告从页面开头到 window.reportData 对象左括号的部分，之后由调用方写入数据
+func htmlReportHead(name string, stats reportStats) string {
 	htmlContent := fmt.Sprintf(`<!DOCTYPE html>
 <html>
 <head>
@@ -113,6 +386,8 @@ func (rg *ReportGenerator) generateHTMLReport(data map[string][]string) error {
         th:nth-child(3), td:nth-child(3) { width: 10%%; }
         th:nth-child(4), td:nth-child(4) { width: 15%%; }
         th:nth-child(5), td:nth-child(5) { width: 40%%; }
+        .scope-table th:nth-child(1), .scope-table td:nth-child(1) { width: 50%%; }
+        .scope-table th:nth-child(2), .scope-table td:nth-child(2) { width: 50%%; }
         tr:hover { background-color: #f5f5f5; }
         .url-link { color: #1976D2; text-decoration: none; word-break: break-all; }
         .url-link:hover { text-decoration: underline; }
@@ -124,6 +399,10 @@ func (rg *ReportGenerator) generateHTMLReport(data map[string][]string) error {
         .status-dns { color: #9c27b0; font-weight: bold; }
         .status-ssl { color: #795548; font-weight: bold; }
         .summary { background-color: #e3f2fd; padding: 15px; border-radius: 4px; margin-bottom: 20px; }
+        .parts { margin: 10px 0; }
+        .parts a { margin: 0 8px; color: #1976D2; }
+        .filter { margin: 10px 0; }
+        .filter select { padding: 4px 8px; }
         .pagination { text-align: center; margin: 20px 0; }
         .pagination button { margin: 0 5px; padding: 8px 12px; border: 1px solid #ddd; background: white; cursor: pointer; border-radius: 4px; }
         .pagination button:hover { background: #f5f5f5; }
@@ -142,20 +421,19 @@ func (rg *ReportGenerator) generateHTMLReport(data map[string][]string) error {
 </head>
 <body>
     <div class="container">
-        <h1>网站截图报告 - %s</h1>`, rg.resultName, rg.resultName)
-
-	// 计算统计信息
-	totalCount := len(data)
-	successCount := 0
-	for _, info := range data {
-		if len(info) >= 2 && info[1] != "连接失败" && info[1] != "ERROR" {
-			successCount++
-		}
-	}
+        <h1>网站截图报告 - %s</h1>`, name, name)
+
+	totalCount := stats.total
+	successCount := stats.success
 
 	htmlContent += fmt.Sprintf(`
         <div class="summary">
-            <p>总计: %d 个地址，成功: %d 个，失败: %d 个</p>
+            <p>总计: %d 个地址，成功: %d 个，失败: %d 个，超出范围: %d 个</p>
+        </div>
+        <div class="parts" id="parts" style="display: none;"></div>
+        <div class="filter">
+            <label for="classFilter">错误类型: </label>
+            <select id="classFilter"><option value="">全部</option></select>
         </div>
         <div class="pagination" id="pagination"></div>
         <table id="dataTable">
@@ -181,60 +459,126 @@ func (rg *ReportGenerator) generateHTMLReport(data map[string][]string) error {
                 <button class="zoom-btn" onclick="zoomImage(0.2)">+</button>
             </div>
         </div>
+        <div id="outOfScope" style="display: none;">
+            <h2>范围外目标</h2>
+            <table class="scope-table">
+                <thead>
+                    <tr>
+                        <th>URL地址</th>
+                        <th>原因</th>
+                    </tr>
+                </thead>
+                <tbody id="outOfScopeBody"></tbody>
+            </table>
+        </div>
     </div>
     <script>
         // 使用安全的数据传递方式
-        window.reportData = {`, totalCount, successCount, totalCount-successCount)
-
-	// 构建数据结构
-	type ReportItem struct {
-		URL        string `json:"url"`
-		Title      string `json:"title"`
-		Status     string `json:"status"`
-		Screenshot string `json:"screenshot"`
-		Response   string `json:"response"`
-	}
-
-	var items []ReportItem
-	for url, info := range data {
-		if len(info) < 4 {
-			continue
-		}
+        window.reportData = {`, totalCount, successCount, totalCount-successCount, stats.outOfScope)
+	return htmlContent
+}
 
-		titleName := info[0]
-		status := info[1]
-		screenshotPath := info[2]
-		responseContent := ""
-		if len(info) >= 4 {
-			responseContent = info[3]
-		}
+func (rg *ReportGenerator) generateHTMLReport(stats reportStats) error {
+	htmlPath := filepath.Join(rg.resultDir, rg.resultName+".html")
 
-		if screenshotPath != "data/" && screenshotPath != "" {
-			screenshotPath = fmt.Sprintf("%s/%s", rg.resultName, screenshotPath)
+	// 写入文件，数据逐条从结果文件读取并写出，不在内存中拼接完整报告
+	file, err := os.OpenFile(htmlPath, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0644)
+	if err != nil {
+		return fmt.Errorf("创建报告文件失败: %w", err)
+	}
+	defer func() {
+		if closeErr := file.Close(); closeErr != nil {
+			log.Warning(fmt.Sprintf("关闭报告文件失败: %v", closeErr))
 		}
+	}()
 
-		items = append(items, ReportItem{
-			URL:        url,
-			Title:      titleName,
-			Status:     status,
-			Screenshot: screenshotPath,
-			Response:   responseContent,
+	writer := bufio.NewWriter(file)
+	writer.WriteString(htmlReportHead(rg.resultName, stats))
+	writer.WriteString("\n            items: ")
+
+	err = writeJSONArray(writer, func(emit func(interface{}) error) error {
+		return ScanResults(rg.runDir(), func(record Result) error {
+			screenshotPath := record.Screenshot
+			if screenshotPath != "" {
+				screenshotPath = fmt.Sprintf("%s/%s", rg.resultName, screenshotPath)
+			}
+
+			// 使用JSON编码确保数据安全
+			return emit(htmlReportItem{
+				URL:        record.URL,
+				Title:      record.Title,
+				Status:     record.Status(),
+				ErrorClass: record.ErrorClass,
+				Screenshot: screenshotPath,
+				Response:   record.Response(),
+			})
 		})
+	})
+	if err != nil {
+		return err
 	}
 
-	// 使用JSON编码确保数据安全
-	jsonData, err := json.Marshal(items)
+	writer.WriteString(",\n            outOfScope: ")
+	err = writeJSONArray(writer, func(emit func(interface{}) error) error {
+		return ScanOutOfScope(rg.runDir(), func(target OutOfScopeTarget) error {
+			return emit(htmlOutOfScopeItem{URL: target.URL, Reason: target.Reason})
+		})
+	})
 	if err != nil {
-		return fmt.Errorf("JSON编码失败: %w", err)
+		return err
 	}
 
-	htmlContent += fmt.Sprintf("\n            items: %s\n        };", string(jsonData))
+	writer.WriteString("\n        };")
+	writer.WriteString(htmlReportScript)
+	if err := writer.Flush(); err != nil {
+		return fmt.Errorf("写入报告失败: %w", err)
+	}
 
-	htmlContent += `
+	log.Info(fmt.Sprintf("生成报告成功: %s", htmlPath))
+	return nil
+}
+
+func truncateString(s string, maxLen int) string {
+	if len(s) <= maxLen {
+		return s
+	}
+	return s[:maxLen-3] + "..."
+}
+
+const htmlReportScript = `
 
          const itemsPerPage = 20;
          let currentPage = 1;
-         let totalPages = Math.ceil(window.reportData.items.length / itemsPerPage);
+         let filteredItems = window.reportData.items;
+         let totalPages = Math.ceil(filteredItems.length / itemsPerPage);
+
+        function renderClassFilter() {
+            const select = document.getElementById('classFilter');
+            if (!select) return;
+
+            const counts = {};
+            window.reportData.items.forEach(function(item) {
+                const key = item.errorClass || 'OK';
+                counts[key] = (counts[key] || 0) + 1;
+            });
+            Object.keys(counts).sort().forEach(function(key) {
+                const option = document.createElement('option');
+                option.value = key;
+                option.textContent = key + ' (' + counts[key] + ')';
+                select.appendChild(option);
+            });
+
+            select.onchange = function() {
+                const value = select.value;
+                filteredItems = window.reportData.items.filter(function(item) {
+                    return value === '' || (item.errorClass || 'OK') === value;
+                });
+                totalPages = Math.max(1, Math.ceil(filteredItems.length / itemsPerPage));
+                currentPage = 1;
+                renderTable(currentPage);
+                renderPagination();
+            };
+        }
 
         function renderTable(page) {
             try {
@@ -248,7 +592,7 @@ func (rg *ReportGenerator) generateHTMLReport(data map[string][]string) error {
                 
                 const start = (page - 1) * itemsPerPage;
                 const end = start + itemsPerPage;
-                const pageData = window.reportData.items.slice(start, end);
+                const pageData = filteredItems.slice(start, end);
                 
                 pageData.forEach(function(item) {
                     const row = tbody.insertRow();
@@ -262,17 +606,17 @@ func (rg *ReportGenerator) generateHTMLReport(data map[string][]string) error {
                     urlCell.appendChild(link);
                     
                     const titleCell = row.insertCell();
-                    titleCell.textContent = item.title;
+                    titleCell.textContent = item.title || '无标题';
                     
                     const statusCell = row.insertCell();
                     let statusClass = 'status-success';
-                    if (item.status === 'TIMEOUT') {
+                    if (item.errorClass === 'TIMEOUT') {
                         statusClass = 'status-timeout';
-                    } else if (item.status === 'DNS_ERROR') {
+                    } else if (item.errorClass === 'DNS_ERROR') {
                         statusClass = 'status-dns';
-                    } else if (item.status === 'SSL_ERROR') {
+                    } else if (item.errorClass === 'SSL_ERROR') {
                         statusClass = 'status-ssl';
-                    } else if (item.status === 'CONNECTION_REFUSED' || item.status === 'ERROR' || item.status === '连接失败') {
+                    } else if (item.errorClass) {
                         statusClass = 'status-error';
                     } else if (item.status.indexOf('4') === 0 || item.status.indexOf('5') === 0) {
                         statusClass = 'status-error';
@@ -281,14 +625,20 @@ func (rg *ReportGenerator) generateHTMLReport(data map[string][]string) error {
                     statusSpan.className = statusClass;
                     statusSpan.textContent = item.status;
                     statusCell.appendChild(statusSpan);
+                    if (item.errorClass && item.errorClass !== item.status) {
+                        const classSpan = document.createElement('div');
+                        classSpan.className = 'status-error';
+                        classSpan.textContent = item.errorClass;
+                        statusCell.appendChild(classSpan);
+                    }
                     
                     const screenshotCell = row.insertCell();
-                    if (item.screenshot && item.screenshot !== 'data/') {
+                    if (item.screenshot) {
                         const img = document.createElement('img');
                         img.src = item.screenshot;
                         img.className = 'screenshot';
                         img.alt = '网站截图';
-                        img.onclick = function() { openModal(this.src); };
+                        img.onclick = function() { openModal(item.image || this.src); };
                         screenshotCell.appendChild(img);
                     } else {
                         screenshotCell.textContent = '无截图';
@@ -305,6 +655,38 @@ func (rg *ReportGenerator) generateHTMLReport(data map[string][]string) error {
             }
         }
 
+        function renderOutOfScope() {
+            const items = window.reportData.outOfScope || [];
+            const section = document.getElementById('outOfScope');
+            const tbody = document.getElementById('outOfScopeBody');
+            if (!section || !tbody || items.length === 0) return;
+
+            section.style.display = 'block';
+            tbody.innerHTML = '';
+            items.forEach(function(item) {
+                const row = tbody.insertRow();
+                row.insertCell().textContent = item.url;
+                row.insertCell().textContent = item.reason;
+            });
+        }
+
+        function renderParts() {
+            const parts = window.reportData.parts;
+            const nav = document.getElementById('parts');
+            if (!parts || !nav) return;
+
+            nav.innerHTML = '';
+            nav.appendChild(document.createTextNode('本报告分为多个文件，当前为第 ' + parts.index + ' 部分'));
+            [['上一部分', parts.prev], ['下一部分', parts.next]].forEach(function(link) {
+                if (!link[1]) return;
+                const a = document.createElement('a');
+                a.href = link[1];
+                a.textContent = link[0];
+                nav.appendChild(a);
+            });
+            nav.style.display = 'block';
+        }
+
         function renderPagination() {
             try {
                 const pagination = document.getElementById('pagination');
@@ -440,8 +822,11 @@ func (rg *ReportGenerator) generateHTMLReport(data map[string][]string) error {
         function initializeReport() {
             try {
                 console.log('Initializing report, data items:', window.reportData.items.length);
+                renderClassFilter();
                 renderTable(1);
                 renderPagination();
+                renderOutOfScope();
+                renderParts();
                 
                 const modal = document.getElementById('imageModal');
                 const modalImg = document.getElementById('modalImage');
@@ -513,29 +898,3 @@ func (rg *ReportGenerator) generateHTMLReport(data map[string][]string) error {
     </script>
 </body>
 </html>`
-
-	// 写入文件
-	file, err := os.OpenFile(htmlPath, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0644)
-	if err != nil {
-		return fmt.Errorf("创建报告文件失败: %w", err)
-	}
-	defer func() {
-		if closeErr := file.Close(); closeErr != nil {
-			log.Warning(fmt.Sprintf("关闭报告文件失败: %v", closeErr))
-		}
-	}()
-
-	if _, err := file.WriteString(htmlContent); err != nil {
-		return fmt.Errorf("写入报告失败: %w", err)
-	}
-
-	log.Info(fmt.Sprintf("生成报告成功: %s", htmlPath))
-	return nil
-}
-
-func truncateString(s string, maxLen int) string {
-	if len(s) <= maxLen {
-		return s
-	}
-	return s[:maxLen-3] + "..."
-}
@@ -0,0 +1,351 @@
+package scripts
+
+import (
+	log "Sowhp/concert/logger"
+	"encoding/json"
+	"net/http"
+	"path"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// refreshInterval 为检查结果文件变化的最小间隔
+const refreshInterval = 5 * time.Second
+
+type browseHandler struct {
+	index       *ResultIndex
+	mux         *http.ServeMux
+	mu          sync.Mutex
+	lastRefresh time.Time
+}
+
+// NewBrowseHandler 返回浏览运行结果的 HTTP 处理器，包含 Web 界面与以下 JSON 接口：
+//
+//	GET /api/runs                 已索引的运行目录
+//	GET /api/results              检索结果，参数 run q status class tag page size
+//	GET /api/results/{id}         单条完整结果，包含响应头与响应体预览
+//	PUT /api/results/{id}/tags    设置标签，请求体为 {"tags": ["..."]}
+//	GET /api/facets               各状态、错误类型与标签的结果数，参数 run
+//	GET /files/{run}/data/...     截图文件
+func NewBrowseHandler(index *ResultIndex) http.Handler {
+	h := &browseHandler{index: index, mux: http.NewServeMux()}
+	h.mux.HandleFunc("GET /{$}", h.page)
+	h.mux.HandleFunc("GET /api/runs", h.runs)
+	h.mux.HandleFunc("GET /api/results", h.search)
+	h.mux.HandleFunc("GET /api/results/{id}", h.result)
+	h.mux.HandleFunc("PUT /api/results/{id}/tags", h.setTags)
+	h.mux.HandleFunc("GET /api/facets", h.facets)
+	h.mux.HandleFunc("GET /files/{run}/{file...}", h.file)
+	return h
+}
+
+func (h *browseHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if strings.HasPrefix(r.URL.Path, "/api/") {
+		h.refresh()
+	}
+	h.mux.ServeHTTP(w, r)
+}
+
+// refresh 至多每 refreshInterval 检查一次结果文件，运行中的任务写入的新结果可直接看到
+func (h *browseHandler) refresh() {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	if time.Since(h.lastRefresh) < refreshInterval {
+		return
+	}
+	h.lastRefresh = time.Now()
+	if err := h.index.Refresh(); err != nil {
+		log.Warning(err.Error())
+	}
+}
+
+func (h *browseHandler) page(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	w.Write([]byte(browsePage))
+}
+
+func (h *browseHandler) runs(w http.ResponseWriter, r *http.Request) {
+	WriteJSON(w, http.StatusOK, h.index.Runs())
+}
+
+func (h *browseHandler) search(w http.ResponseWriter, r *http.Request) {
+	params := r.URL.Query()
+	page, _ := strconv.Atoi(params.Get("page"))
+	size, _ := strconv.Atoi(params.Get("size"))
+	WriteJSON(w, http.StatusOK, h.index.Search(ResultQuery{
+		Run:        params.Get("run"),
+		Text:       params.Get("q"),
+		Status:     params.Get("status"),
+		ErrorClass: params.Get("class"),
+		Tag:        params.Get("tag"),
+		Page:       page,
+		Size:       size,
+	}))
+}
+
+func (h *browseHandler) result(w http.ResponseWriter, r *http.Request) {
+	item, ok := h.index.Get(r.PathValue("id"))
+	if !ok {
+		WriteError(w, http.StatusNotFound, "结果不存在")
+		return
+	}
+	WriteJSON(w, http.StatusOK, struct {
+		IndexedResult
+		Response string `json:"response"`
+	}{item, item.Response()})
+}
+
+func (h *browseHandler) setTags(w http.ResponseWriter, r *http.Request) {
+	var body struct {
+		Tags []string `json:"tags"`
+	}
+	if err := json.NewDecoder(http.MaxBytesReader(w, r.Body, 64*1024)).Decode(&body); err != nil {
+		WriteError(w, http.StatusBadRequest, "请求体格式错误: "+err.Error())
+		return
+	}
+	if err := h.index.SetTags(r.PathValue("id"), body.Tags); err != nil {
+		WriteError(w, http.StatusNotFound, err.Error())
+		return
+	}
+
+	item, _ := h.index.Get(r.PathValue("id"))
+	WriteJSON(w, http.StatusOK, item.summary())
+}
+
+func (h *browseHandler) facets(w http.ResponseWriter, r *http.Request) {
+	WriteJSON(w, http.StatusOK, h.index.Facets(r.URL.Query().Get("run")))
+}
+
+// file 只提供运行目录 data 子目录中的截图
+func (h *browseHandler) file(w http.ResponseWriter, r *http.Request) {
+	runDir, ok := h.index.RunDir(r.PathValue("run"))
+	name := path.Clean("/" + r.PathValue("file"))
+	if !ok || !strings.HasPrefix(name, "/data/") {
+		http.NotFound(w, r)
+		return
+	}
+	http.ServeFile(w, r, filepath.Join(runDir, filepath.FromSlash(name)))
+}
+
+// WriteJSON 以 JSON 格式写入响应
+func WriteJSON(w http.ResponseWriter, status int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json; charset=utf-8")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(v)
+}
+
+// WriteError 写入 {"error": message} 格式的错误响应
+func WriteError(w http.ResponseWriter, status int, message string) {
+	WriteJSON(w, status, map[string]string{"error": message})
+}
+
+const browsePage = `<!DOCTYPE html>
+<html>
+<head>
+    <meta charset="utf-8">
+    <title>Sowhp 结果浏览</title>
+    <style>
+        body { font-family: Arial, sans-serif; margin: 0; background-color: #f5f5f5; }
+        header { background-color: #4CAF50; color: white; padding: 10px 20px; }
+        header h1 { margin: 0; font-size: 20px; }
+        .toolbar { display: flex; flex-wrap: wrap; gap: 8px; padding: 10px 20px; background-color: white; border-bottom: 1px solid #ddd; position: sticky; top: 0; }
+        .toolbar input[type=search] { flex: 1; min-width: 240px; padding: 6px; }
+        .toolbar select, .toolbar button { padding: 6px; }
+        .summary { padding: 8px 20px; color: #555; font-size: 13px; }
+        main { padding: 0 20px 20px; }
+        table { width: 100%; border-collapse: collapse; background-color: white; table-layout: fixed; }
+        th, td { padding: 8px; text-align: left; border-bottom: 1px solid #ddd; vertical-align: top; word-wrap: break-word; font-size: 12px; }
+        th { background-color: #4CAF50; color: white; font-size: 14px; }
+        th:nth-child(1) { width: 18%; } th:nth-child(2) { width: 26%; } th:nth-child(3) { width: 20%; }
+        th:nth-child(4) { width: 8%; } th:nth-child(5) { width: 10%; } th:nth-child(6) { width: 18%; }
+        tr:hover { background-color: #f5f5f5; }
+        a { color: #1976D2; text-decoration: none; word-break: break-all; }
+        a:hover { text-decoration: underline; }
+        .thumb { width: 100%; height: auto; border: 1px solid #ddd; border-radius: 4px; cursor: pointer; }
+        .status-success { color: #4CAF50; font-weight: bold; }
+        .status-error { color: #f44336; font-weight: bold; }
+        .tag { display: inline-block; background-color: #e3f2fd; color: #1565C0; border-radius: 3px; padding: 1px 6px; margin: 1px; font-size: 11px; }
+        .link { color: #1976D2; cursor: pointer; font-size: 11px; margin-right: 6px; }
+        .gallery { display: grid; grid-template-columns: repeat(auto-fill, minmax(280px, 1fr)); gap: 12px; }
+        .card { background-color: white; border: 1px solid #ddd; border-radius: 4px; padding: 8px; font-size: 12px; }
+        .card .thumb { height: 180px; object-fit: cover; object-position: top; }
+        .card .empty { height: 180px; display: flex; align-items: center; justify-content: center; color: #999; background-color: #fafafa; }
+        .pager { display: flex; gap: 8px; align-items: center; justify-content: center; padding: 16px; }
+        .modal { display: none; position: fixed; inset: 0; background-color: rgba(0,0,0,0.6); z-index: 10; }
+        .modal-content { background-color: white; margin: 3% auto; width: 85%; max-height: 88%; overflow: auto; padding: 16px; border-radius: 4px; }
+        .modal pre { white-space: pre-wrap; word-break: break-all; background-color: #f8f8f8; padding: 10px; font-size: 12px; }
+        .modal img { max-width: 100%; }
+    </style>
+</head>
+<body>
+<header><h1>Sowhp 结果浏览</h1></header>
+<div class="toolbar">
+    <select id="run"><option value="">全部运行</option></select>
+    <input type="search" id="q" placeholder="搜索 URL、标题、响应头与响应内容">
+    <select id="status"><option value="">全部状态</option></select>
+    <select id="class"><option value="">全部错误类型</option></select>
+    <select id="tag"><option value="">全部标签</option></select>
+    <select id="size"><option>20</option><option selected>50</option><option>100</option><option>200</option></select>
+    <button id="view">画廊视图</button>
+</div>
+<div class="summary" id="summary"></div>
+<main id="content"></main>
+<div class="pager">
+    <button id="prev">上一页</button><span id="pageInfo"></span><button id="next">下一页</button>
+</div>
+<div class="modal" id="modal"><div class="modal-content" id="modalContent"></div></div>
+<script>
+const state = { page: 1, gallery: false, total: 0 };
+const $ = id => document.getElementById(id);
+
+function escapeHtml(value) {
+    return String(value == null ? '' : value).replace(/[&<>"']/g, c => ({ '&': '&amp;', '<': '&lt;', '>': '&gt;', '"': '&quot;', "'": '&#39;' }[c]));
+}
+
+// link 只为 http/https 地址生成链接，避免 javascript: 等地址被点击执行
+function link(url) {
+    if (!/^https?:\/\//i.test(url || '')) return escapeHtml(url);
+    return '<a href="' + escapeHtml(url) + '" target="_blank" rel="noreferrer">' + escapeHtml(url) + '</a>';
+}
+
+function shotURL(item) {
+    return '/files/' + encodeURIComponent(item.run) + '/' + item.screenshot.split('/').map(encodeURIComponent).join('/');
+}
+
+function statusClass(item) {
+    return item.error_class ? 'status-error' : 'status-success';
+}
+
+function tagsHtml(item) {
+    return item.tags.map(t => '<span class="tag">' + escapeHtml(t) + '</span>').join('') +
+        ' <span class="link" data-tags="' + escapeHtml(item.id) + '">编辑标签</span>';
+}
+
+async function getJSON(url, options) {
+    const response = await fetch(url, options);
+    const data = await response.json();
+    if (!response.ok) throw new Error(data.error || response.statusText);
+    return data;
+}
+
+function fillSelect(select, counts, label) {
+    const current = select.value;
+    select.innerHTML = '<option value="">' + label + '</option>';
+    Object.keys(counts).sort().forEach(key => {
+        const option = document.createElement('option');
+        option.value = key;
+        option.textContent = key + ' (' + counts[key] + ')';
+        select.appendChild(option);
+    });
+    select.value = current;
+}
+
+async function loadFacets() {
+    const facets = await getJSON('/api/facets?run=' + encodeURIComponent($('run').value));
+    const statuses = Object.assign({}, facets.statuses);
+    ['2xx', '3xx', '4xx', '5xx'].forEach(range => {
+        const count = Object.keys(facets.statuses).filter(s => s[0] === range[0] && /^\d{3}$/.test(s))
+            .reduce((sum, s) => sum + facets.statuses[s], 0);
+        if (count > 0) statuses[range] = count;
+    });
+    fillSelect($('status'), statuses, '全部状态');
+    fillSelect($('class'), facets.classes, '全部错误类型');
+    fillSelect($('tag'), facets.tags, '全部标签');
+}
+
+async function loadRuns() {
+    const runs = await getJSON('/api/runs');
+    runs.forEach(run => {
+        const option = document.createElement('option');
+        option.value = run.name;
+        option.textContent = run.name + ' (' + run.success + '/' + run.total + ')';
+        $('run').appendChild(option);
+    });
+}
+
+async function load() {
+    const params = new URLSearchParams({
+        run: $('run').value, q: $('q').value, status: $('status').value, class: $('class').value,
+        tag: $('tag').value, page: state.page, size: $('size').value
+    });
+    const data = await getJSON('/api/results?' + params);
+    state.total = data.total;
+    const pages = Math.max(1, Math.ceil(data.total / data.size));
+    $('summary').textContent = '共 ' + data.total + ' 条结果';
+    $('pageInfo').textContent = '第 ' + data.page + ' / ' + pages + ' 页';
+    $('prev').disabled = data.page <= 1;
+    $('next').disabled = data.page >= pages;
+    $('content').innerHTML = state.gallery ? renderGallery(data.items) : renderTable(data.items);
+}
+
+function renderTable(items) {
+    const rows = items.map(item => '<tr>' +
+        '<td>' + (item.screenshot ? '<img loading="lazy" class="thumb" data-id="' + escapeHtml(item.id) + '" src="' + shotURL(item) + '">' : '无截图') + '</td>' +
+        '<td>' + link(item.url) +
+        (item.final_url && item.final_url !== item.url ? '<br>→ ' + escapeHtml(item.final_url) : '') + '</td>' +
+        '<td>' + escapeHtml(item.title || '无标题') + '</td>' +
+        '<td class="' + statusClass(item) + '">' + escapeHtml(item.status) + '</td>' +
+        '<td>' + escapeHtml(item.server) + '</td>' +
+        '<td>' + tagsHtml(item) + '<br><span class="link" data-id="' + escapeHtml(item.id) + '">查看响应</span></td>' +
+        '</tr>').join('');
+    return '<table><tr><th>截图</th><th>URL</th><th>标题</th><th>状态</th><th>Server</th><th>标签</th></tr>' + rows + '</table>';
+}
+
+function renderGallery(items) {
+    return '<div class="gallery">' + items.map(item => '<div class="card">' +
+        (item.screenshot ? '<img loading="lazy" class="thumb" data-id="' + escapeHtml(item.id) + '" src="' + shotURL(item) + '">' : '<div class="empty">无截图</div>') +
+        '<div>' + link(item.url) + '</div>' +
+        '<div>' + escapeHtml(item.title || '无标题') + ' <span class="' + statusClass(item) + '">' + escapeHtml(item.status) + '</span></div>' +
+        '<div>' + tagsHtml(item) + '</div>' +
+        '</div>').join('') + '</div>';
+}
+
+async function showDetail(id) {
+    const item = await getJSON('/api/results/' + encodeURIComponent(id));
+    $('modalContent').innerHTML = '<h3>' + escapeHtml(item.url) + '</h3>' +
+        (item.screenshot ? '<img src="' + shotURL(item) + '">' : '') +
+        '<pre>' + escapeHtml(item.response || '无响应内容') + '</pre>';
+    $('modal').style.display = 'block';
+}
+
+async function editTags(id) {
+    const item = await getJSON('/api/results/' + encodeURIComponent(id));
+    const input = prompt('输入标签，多个以逗号分隔', (item.tags || []).join(', '));
+    if (input === null) return;
+    await getJSON('/api/results/' + encodeURIComponent(id) + '/tags', {
+        method: 'PUT', headers: { 'Content-Type': 'application/json' },
+        body: JSON.stringify({ tags: input.split(',') })
+    });
+    await loadFacets();
+    await load();
+}
+
+function reload() { state.page = 1; load().catch(e => alert(e.message)); }
+
+let timer;
+$('q').addEventListener('input', () => { clearTimeout(timer); timer = setTimeout(reload, 300); });
+['status', 'class', 'tag', 'size'].forEach(id => $(id).addEventListener('change', reload));
+$('run').addEventListener('change', () => { loadFacets().then(reload); });
+$('prev').addEventListener('click', () => { state.page--; load(); });
+$('next').addEventListener('click', () => { state.page++; load(); });
+$('view').addEventListener('click', () => {
+    state.gallery = !state.gallery;
+    $('view').textContent = state.gallery ? '表格视图' : '画廊视图';
+    load();
+});
+$('content').addEventListener('click', e => {
+    const target = e.target;
+    if (target.dataset.tags) editTags(target.dataset.tags).catch(err => alert(err.message));
+    else if (target.dataset.id) showDetail(target.dataset.id).catch(err => alert(err.message));
+});
+$('modal').addEventListener('click', e => { if (e.target === $('modal')) $('modal').style.display = 'none'; });
+
+loadRuns().then(loadFacets).then(load).catch(e => alert(e.message));
+</script>
+</body>
+</html>
+`
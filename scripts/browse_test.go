@@ -0,0 +1,127 @@
+package scripts
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+)
+
+func newBrowseServer(t *testing.T, dir string) http.Handler {
+	t.Helper()
+	index, err := NewResultIndex([]string{dir})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return NewBrowseHandler(index)
+}
+
+func browse(t *testing.T, handler http.Handler, method, target, body string, v interface{}) int {
+	t.Helper()
+	req := httptest.NewRequest(method, target, strings.NewReader(body))
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+	if v != nil && rec.Code == http.StatusOK {
+		if err := json.Unmarshal(rec.Body.Bytes(), v); err != nil {
+			t.Fatalf("%s 响应格式错误: %v", target, err)
+		}
+	}
+	return rec.Code
+}
+
+func TestBrowseSearch(t *testing.T) {
+	failed := NewResult("dns.example", "https://dns.example")
+	failed.Fail(ClassDNS, nil)
+	handler := newBrowseServer(t, writeRun(t,
+		testResult("a.example", "https://a.example", 200, "管理后台"),
+		testResult("b.example", "https://b.example", 404, "Not Found"),
+		testResult("c.example", "https://c.example", 302, ""),
+		failed,
+	))
+
+	tests := []struct {
+		query string
+		want  []string
+	}{
+		{query: "", want: []string{"https://a.example", "https://b.example", "https://c.example", "https://dns.example"}},
+		{query: "status=2xx", want: []string{"https://a.example"}},
+		{query: "status=404", want: []string{"https://b.example"}},
+		{query: "class=DNS_ERROR", want: []string{"https://dns.example"}},
+		{query: "q=" + url.QueryEscape("管理"), want: []string{"https://a.example"}},
+		{query: "q=not+found", want: []string{"https://b.example"}},
+		{query: "status=3xx&q=a.example", want: nil},
+	}
+	for _, tt := range tests {
+		var page ResultPage
+		if code := browse(t, handler, http.MethodGet, "/api/results?"+tt.query, "", &page); code != http.StatusOK {
+			t.Fatalf("%s 返回 %d", tt.query, code)
+		}
+		var got []string
+		for _, item := range page.Items {
+			got = append(got, item.URL)
+		}
+		if page.Total != len(tt.want) || strings.Join(got, ",") != strings.Join(tt.want, ",") {
+			t.Errorf("%q 的结果为 %v（共 %d 条），应为 %v", tt.query, got, page.Total, tt.want)
+		}
+	}
+}
+
+func TestBrowseTags(t *testing.T) {
+	dir := writeRun(t,
+		testResult("a.example", "https://a.example", 200, "A"),
+		testResult("b.example", "https://b.example", 200, "B"),
+	)
+	handler := newBrowseServer(t, dir)
+
+	var page ResultPage
+	browse(t, handler, http.MethodGet, "/api/results?q=b.example", "", &page)
+	if len(page.Items) != 1 {
+		t.Fatalf("未找到 b.example: %+v", page)
+	}
+	id := page.Items[0].ID
+
+	var summary ResultSummary
+	if code := browse(t, handler, http.MethodPut, "/api/results/"+id+"/tags", `{"tags":["登录页"," 登录页 ","vpn"]}`, &summary); code != http.StatusOK {
+		t.Fatalf("设置标签返回 %d", code)
+	}
+	if strings.Join(summary.Tags, ",") != "vpn,登录页" {
+		t.Errorf("标签为 %v，应去重并排序", summary.Tags)
+	}
+	if code := browse(t, handler, http.MethodPut, "/api/results/missing:0/tags", `{"tags":["x"]}`, nil); code != http.StatusNotFound {
+		t.Errorf("不存在的结果返回 %d，应为 404", code)
+	}
+
+	// 重新打开运行目录后标签仍然存在，并可按标签筛选
+	handler = newBrowseServer(t, dir)
+	page = ResultPage{}
+	browse(t, handler, http.MethodGet, "/api/results?tag="+url.QueryEscape("登录页"), "", &page)
+	if page.Total != 1 || page.Items[0].URL != "https://b.example" {
+		t.Errorf("按标签筛选的结果错误: %+v", page)
+	}
+}
+
+func TestBrowseEscaping(t *testing.T) {
+	hostile := testResult("evil.example", `https://evil.example/?q="onmouseover="alert(1)`, 200, `"><img src=x onerror=alert(1)>`)
+	handler := newBrowseServer(t, writeRun(t, hostile))
+
+	var page ResultPage
+	browse(t, handler, http.MethodGet, "/api/results", "", &page)
+	if len(page.Items) != 1 || page.Items[0].Title != hostile.Title || page.Items[0].URL != hostile.URL {
+		t.Fatalf("接口应原样返回标题与地址，由页面转义: %+v", page.Items)
+	}
+
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+	html := rec.Body.String()
+	for _, want := range []string{`'"': '&quot;'`, `"'": '&#39;'`, `/^https?:\/\//i.test(url || '')`} {
+		if !strings.Contains(html, want) {
+			t.Errorf("页面缺少 %s", want)
+		}
+	}
+	if strings.Contains(html, "innerHTML;") || strings.Contains(html, `'<a href="' + escapeHtml(item.url)`) {
+		t.Error("页面仍使用不转义引号的 escapeHtml 或直接为地址生成链接")
+	}
+}
@@ -0,0 +1,168 @@
+package scripts
+
+import (
+	log "Sowhp/concert/logger"
+	"errors"
+	"fmt"
+	"hash/fnv"
+	"net"
+	"net/url"
+	"strings"
+
+	"golang.org/x/net/idna"
+)
+
+var defaultPorts = map[string]string{
+	"http":  "80",
+	"https": "443",
+}
+
+type DuplicateURL struct {
+	URL       string
+	Index     int
+	KeptIndex int
+}
+
+// CanonicalizeURL 将地址规范化：协议与主机名小写、国际化域名转为 punycode、省略默认端口、去除根路径斜杠与锚点，
+// 未指定协议的地址保持无协议形式
+func CanonicalizeURL(raw string) (string, error) {
+	raw = strings.TrimSpace(raw)
+	schemeless := !HasScheme(raw)
+	if schemeless {
+		raw = "//" + raw
+	}
+
+	u, err := url.Parse(raw)
+	if err != nil {
+		return "", err
+	}
+	if u.Host == "" {
+		return "", errors.New("地址缺少主机名")
+	}
+
+	u.Scheme = strings.ToLower(u.Scheme)
+
+	host := strings.TrimSuffix(strings.ToLower(u.Hostname()), ".")
+	if host == "" {
+		return "", errors.New("地址缺少主机名")
+	}
+	if net.ParseIP(host) == nil {
+		if ascii, err := idna.Lookup.ToASCII(host); err == nil {
+			host = ascii
+		} else {
+			log.Debug(fmt.Sprintf("域名 %s 转换 punycode 失败: %v", host, err))
+		}
+	}
+
+	port := u.Port()
+	if port == defaultPorts[u.Scheme] {
+		port = ""
+	}
+
+	if strings.Contains(host, ":") {
+		host = "[" + host + "]"
+	}
+	if port != "" {
+		host = host + ":" + port
+	}
+	u.Host = host
+
+	if u.Path == "/" {
+		u.Path = ""
+		u.RawPath = ""
+	}
+	u.Fragment = ""
+	u.RawFragment = ""
+
+	if schemeless {
+		return strings.TrimPrefix(u.String(), "//"), nil
+	}
+	return u.String(), nil
+}
+
+func HasScheme(raw string) bool {
+	return strings.Contains(raw, "://")
+}
+
+// dedupKey 生成去重键，未指定协议的地址按 https 处理
+func dedupKey(canonical string, keepPath bool) string {
+	if !HasScheme(canonical) {
+		if withScheme, err := CanonicalizeURL("https://" + canonical); err == nil {
+			canonical = withScheme
+		}
+	}
+	if keepPath {
+		return canonical
+	}
+	u, err := url.Parse(canonical)
+	if err != nil {
+		return canonical
+	}
+	return u.Scheme + "://" + u.Host
+}
+
+// Deduper 流式去重，记录已出现的去重键及其序号
+type Deduper struct {
+	keepPath   bool
+	seen       map[string]int
+	count      int
+	Duplicates int
+}
+
+func NewDeduper(keepPath bool) *Deduper {
+	return &Deduper{keepPath: keepPath, seen: make(map[string]int)}
+}
+
+// Add 规范化地址并判断是否重复，重复时返回的 DuplicateURL 不为空
+func (d *Deduper) Add(raw string) (string, *DuplicateURL) {
+	d.count++
+
+	canonical, err := CanonicalizeURL(raw)
+	if err != nil {
+		log.Debug(fmt.Sprintf("地址 %s 规范化失败，保留原始地址: %v", raw, err))
+		canonical = raw
+	}
+
+	key := dedupKey(canonical, d.keepPath)
+	if kept, ok := d.seen[key]; ok {
+		d.Duplicates++
+		log.Debug(fmt.Sprintf("第 %d 个地址 %s 与第 %d 个地址重复，已跳过", d.count, raw, kept))
+		return canonical, &DuplicateURL{URL: raw, Index: d.count, KeptIndex: kept}
+	}
+
+	d.seen[key] = d.count
+	return canonical, nil
+}
+
+func hashKey(s string) uint64 {
+	h := fnv.New64a()
+	h.Write([]byte(s))
+	return h.Sum64()
+}
+
+// DedupURLs 规范化并去除重复地址，keepPath 为 false 时同一主机只保留第一个地址
+func DedupURLs(urls []string, keepPath bool) ([]string, []DuplicateURL) {
+	deduper := NewDeduper(keepPath)
+	unique := make([]string, 0, len(urls))
+	var duplicates []DuplicateURL
+
+	for _, raw := range urls {
+		canonical, dup := deduper.Add(raw)
+		if dup != nil {
+			duplicates = append(duplicates, *dup)
+			continue
+		}
+		unique = append(unique, canonical)
+	}
+
+	return unique, duplicates
+}
+
+func ReportDuplicates(total int, removed int) {
+	if removed == 0 {
+		log.Debug(fmt.Sprintf("共 %d 个地址，未发现重复地址", total))
+		return
+	}
+
+	log.Info(fmt.Sprintf("去重完成: 共 %d 个地址，移除重复地址 %d 个，剩余 %d 个", total, removed, total-removed))
+}
@@ -0,0 +1,78 @@
+package scripts
+
+import (
+	"slices"
+	"testing"
+)
+
+func TestCanonicalizeURL(t *testing.T) {
+	tests := []struct {
+		raw, want string
+	}{
+		{"https://example.com:443/", "https://example.com"},
+		{"http://example.com:80", "http://example.com"},
+		{"https://example.com:80", "https://example.com:80"},
+		{"HTTPS://Example.COM./Path", "https://example.com/Path"},
+		{"https://example.com/#top", "https://example.com"},
+		{"https://example.com/a/", "https://example.com/a/"},
+		{"Example.com:8443", "example.com:8443"},
+		{"  example.com  ", "example.com"},
+		{"https://例子.中国/", "https://xn--fsqu00a.xn--fiqs8s"},
+		{"https://[2001:DB8::1]:443/", "https://[2001:db8::1]"},
+		{"http://[::1]:8080/x", "http://[::1]:8080/x"},
+	}
+	for _, tt := range tests {
+		got, err := CanonicalizeURL(tt.raw)
+		if err != nil {
+			t.Errorf("CanonicalizeURL(%q) 报错: %v", tt.raw, err)
+			continue
+		}
+		if got != tt.want {
+			t.Errorf("CanonicalizeURL(%q) = %q，应为 %q", tt.raw, got, tt.want)
+		}
+	}
+
+	for _, raw := range []string{"https://", "https:///path", "://"} {
+		if _, err := CanonicalizeURL(raw); err == nil {
+			t.Errorf("CanonicalizeURL(%q) 应报错", raw)
+		}
+	}
+}
+
+func TestDedupKey(t *testing.T) {
+	tests := []struct {
+		canonical string
+		keepPath  bool
+		want      string
+	}{
+		{"example.com", false, "https://example.com"},
+		{"example.com:443", false, "https://example.com"},
+		{"example.com/a", false, "https://example.com"},
+		{"example.com/a", true, "https://example.com/a"},
+		{"http://example.com/a?x=1", false, "http://example.com"},
+		{"http://example.com/a?x=1", true, "http://example.com/a?x=1"},
+		{"https://example.com:8443/a", false, "https://example.com:8443"},
+	}
+	for _, tt := range tests {
+		if got := dedupKey(tt.canonical, tt.keepPath); got != tt.want {
+			t.Errorf("dedupKey(%q, %v) = %q，应为 %q", tt.canonical, tt.keepPath, got, tt.want)
+		}
+	}
+}
+
+func TestDedupURLs(t *testing.T) {
+	urls := []string{"https://Example.com/", "example.com", "https://example.com:443/login", "http://example.com", "https://例子.中国", "https://xn--fsqu00a.xn--fiqs8s/"}
+
+	unique, duplicates := DedupURLs(urls, false)
+	if want := []string{"https://example.com", "http://example.com", "https://xn--fsqu00a.xn--fiqs8s"}; !slices.Equal(unique, want) {
+		t.Errorf("同一主机只保留一个时结果为 %v，应为 %v", unique, want)
+	}
+	if len(duplicates) != 3 || duplicates[0].Index != 2 || duplicates[0].KeptIndex != 1 {
+		t.Errorf("重复地址记录错误: %+v", duplicates)
+	}
+
+	unique, _ = DedupURLs(urls, true)
+	if want := []string{"https://example.com", "https://example.com/login", "http://example.com", "https://xn--fsqu00a.xn--fiqs8s"}; !slices.Equal(unique, want) {
+		t.Errorf("保留路径时结果为 %v，应为 %v", unique, want)
+	}
+}
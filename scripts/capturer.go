@@ -0,0 +1,393 @@
+package scripts
+
+import (
+	"context"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/chromedp/cdproto/emulation"
+	"github.com/chromedp/cdproto/network"
+	"github.com/chromedp/chromedp"
+	"github.com/chromedp/chromedp/device"
+)
+
+// Capturer 为截图引擎，负责访问单个地址并返回结果；失败原因记录在 Result 的 ErrorClass 与 Error 中
+type Capturer interface {
+	Capture(ctx context.Context, url string, resultName string, opts *Options) Result
+}
+
+// TargetResolver 可由 Capturer 实现，用于替代默认的协议探测（DetectScheme）
+type TargetResolver interface {
+	ResolveTargets(ctx context.Context, target string, opts *Options) []string
+}
+
+// Checker 可由 Capturer 实现，用于在任务开始前确认截图引擎可用
+type Checker interface {
+	Check(ctx context.Context, opts *Options) CheckResult
+}
+
+func resolveTargets(ctx context.Context, capturer Capturer, target string, opts *Options) []string {
+	if resolver, ok := capturer.(TargetResolver); ok {
+		return resolver.ResolveTargets(ctx, target, opts)
+	}
+	return DetectScheme(ctx, target, opts)
+}
+
+// ChromeCapturer 启动本地 Chrome 截图
+type ChromeCapturer struct{}
+
+func (ChromeCapturer) Capture(ctx context.Context, URL string, resultName string, opts *Options) Result {
+	allocCtx, allocCancel := chromedp.NewExecAllocator(ctx, chromeAllocatorOptions(opts)...)
+	defer allocCancel()
+
+	return browserCapture(ctx, allocCtx, URL, resultName, opts)
+}
+
+// Check 使用与截图相同的启动参数启动本地 Chrome 并渲染测试页面
+func (ChromeCapturer) Check(ctx context.Context, opts *Options) CheckResult {
+	path, err := FindChrome()
+	if err != nil {
+		return CheckResult{Name: "浏览器", Detail: err.Error() + "；也可以使用 -cdp 连接已运行的 Chrome，或使用 -http-only 不截图"}
+	}
+
+	allocCtx, allocCancel := chromedp.NewExecAllocator(ctx, chromeAllocatorOptions(opts)...)
+	defer allocCancel()
+
+	result := checkBrowser(allocCtx, opts)
+	result.Detail = fmt.Sprintf("%s（%s）", result.Detail, path)
+	return result
+}
+
+// chromeAllocatorOptions 返回启动本地 Chrome 的参数
+func chromeAllocatorOptions(opts *Options) []chromedp.ExecAllocatorOption {
+	allocOpts := append(chromedp.DefaultExecAllocatorOptions[:],
+		chromedp.Flag("ignore-certificate-errors", true),
+		chromedp.Flag("ignore-ssl-errors", true),
+		chromedp.Flag("ignore-certificate-errors-spki-list", true),
+		chromedp.Flag("ignore-certificate-errors-skip-list", true),
+		chromedp.Flag("allow-running-insecure-content", true),
+		chromedp.Flag("disable-ssl-verification", true),
+		chromedp.Flag("disable-web-security", true),
+		chromedp.Flag("no-sandbox", true),
+		chromedp.Flag("disable-dev-shm-usage", true),
+		chromedp.Flag("disable-features", "VizDisplayCompositor"),
+		chromedp.Flag("disable-ipc-flooding-protection", true),
+		chromedp.Flag("disable-backgrounding-occluded-windows", true),
+		chromedp.Flag("disable-renderer-backgrounding", true),
+		chromedp.Flag("disable-extensions", true),
+		chromedp.Flag("disable-plugins", true),
+		chromedp.Flag("disable-default-apps", true),
+	)
+
+	if opts != nil && opts.Proxy != "" {
+		allocOpts = append(allocOpts, chromedp.ProxyServer(opts.Proxy))
+	}
+	if opts != nil && opts.UserAgent != "" {
+		allocOpts = append(allocOpts, chromedp.UserAgent(opts.UserAgent))
+	}
+	if opts != nil {
+		for name, value := range opts.ChromeFlags {
+			if value == "" {
+				allocOpts = append(allocOpts, chromedp.Flag(name, true))
+			} else {
+				allocOpts = append(allocOpts, chromedp.Flag(name, value))
+			}
+		}
+	}
+	return allocOpts
+}
+
+// RemoteCapturer 连接已运行的 Chrome（远程调试地址，如 ws://127.0.0.1:9222 或 http://127.0.0.1:9222）截图
+type RemoteCapturer struct {
+	URL string
+}
+
+func (c RemoteCapturer) Capture(ctx context.Context, URL string, resultName string, opts *Options) Result {
+	allocCtx, allocCancel := chromedp.NewRemoteAllocator(ctx, c.URL)
+	defer allocCancel()
+
+	return browserCapture(ctx, allocCtx, URL, resultName, opts)
+}
+
+// Check 连接远程 Chrome 并渲染测试页面
+func (c RemoteCapturer) Check(ctx context.Context, opts *Options) CheckResult {
+	allocCtx, allocCancel := chromedp.NewRemoteAllocator(ctx, c.URL)
+	defer allocCancel()
+
+	result := checkBrowser(allocCtx, opts)
+	result.Detail = fmt.Sprintf("%s（%s）", result.Detail, c.URL)
+	return result
+}
+
+// launchBrowser 启动浏览器或打开标签页。首次 chromedp.Run 使用的 ctx 决定浏览器的生命周期，
+// 不能直接附加超时，因此超过 timeout 时调用 cancel 关闭浏览器（标签页）并返回超时错误
+func launchBrowser(browserCtx context.Context, cancel context.CancelFunc, timeout time.Duration) error {
+	timer := time.AfterFunc(timeout, cancel)
+	err := chromedp.Run(browserCtx)
+	if !timer.Stop() {
+		return fmt.Errorf("超过 %s 未完成启动: %w", timeout, context.DeadlineExceeded)
+	}
+	return err
+}
+
+// browserCapture 在 allocCtx 提供的浏览器中截取页面并获取响应信息
+func browserCapture(ctx context.Context, allocCtx context.Context, URL string, resultName string, opts *Options) (result Result) {
+	result = NewResult(URL, URL)
+	start := time.Now()
+	defer func() {
+		result.Timings.TotalMs = elapsedMs(start)
+	}()
+
+	if URL == "" {
+		opts.logger().Error("URL不能为空")
+		result.Fail(ClassError, errors.New("URL不能为空"))
+		return result
+	}
+
+	browserCtx, browserCancel := chromedp.NewContext(allocCtx)
+	defer browserCancel()
+	// allocCtx 可能来自浏览器池而不是 ctx，ctx 取消时需要主动关闭标签页
+	stop := context.AfterFunc(ctx, browserCancel)
+	defer stop()
+
+	scope := opts.scope()
+	if scope != nil {
+		listenScope(browserCtx, scope, URL, opts.logger())
+	}
+
+	if err := launchBrowser(browserCtx, browserCancel, opts.pageTimeout()); err != nil {
+		errorURL(opts.logger(), fmt.Sprintf("浏览器启动失败: %v", err), URL)
+		class := ClassBrowser
+		if ctx.Err() != nil {
+			class = ClassNotProcessed
+		}
+		result.Fail(class, fmt.Errorf("浏览器启动失败: %w", err))
+		return result
+	}
+
+	var pageTitle, finalURL string
+	var screenshot []byte
+
+	width, height := opts.viewport()
+	executeScreenshot := func() error {
+		release, err := opts.limiter().Acquire(browserCtx, URL)
+		if err != nil {
+			return err
+		}
+		defer release()
+
+		attemptCtx, cancel := context.WithTimeout(browserCtx, opts.pageTimeout())
+		defer cancel()
+
+		return chromedp.Run(attemptCtx,
+
+			enableScopeInterception(scope),
+
+			chromedp.Emulate(device.Reset),
+
+			userAgentOverride(opts),
+
+			chromedp.EmulateViewport(width, height),
+
+			extraHeaders(opts),
+
+			visitURL(URL),
+
+			chromedp.Sleep(3*time.Second),
+
+			chromedp.ActionFunc(func(ctx context.Context) error {
+
+				chromedp.WaitVisible(`body`, chromedp.ByQuery).Do(ctx)
+				return nil
+			}),
+
+			chromedp.Evaluate(`document.title`, &pageTitle),
+
+			chromedp.Location(&finalURL),
+
+			chromedp.CaptureScreenshot(&screenshot),
+		)
+	}
+
+	pageStart := time.Now()
+	var class string
+	err := opts.retryPolicy().Do(browserCtx, func(attempt int) (string, error) {
+		err := executeScreenshot()
+		if err == nil {
+			if attempt > 0 {
+				opts.logger().Info(fmt.Sprintf("访问 %s 重试成功", URL))
+			}
+			return "", nil
+		}
+
+		class = ClassifyError(err)
+		switch class {
+		case ClassSSL:
+			warnURL(opts.logger(), "SSL Certificate Error", URL)
+		case ClassTimeout:
+			warnURL(opts.logger(), "Connect Timeout", URL)
+		default:
+			warnURL(opts.logger(), fmt.Sprintf("%v", err), URL)
+		}
+		return class, err
+	})
+	result.Timings.PageMs = elapsedMs(pageStart)
+	if err != nil {
+		errorURL(opts.logger(), fmt.Sprintf("%v", err), URL)
+		if ctx.Err() != nil {
+			class = ClassNotProcessed
+		}
+		result.Fail(class, err)
+		return result
+	}
+
+	result.Title = pageTitle
+	if finalURL != "" && finalURL != URL {
+		result.FinalURL = finalURL
+	}
+
+	if err := saveScreenshot(&result, screenshot, resultName, opts); err != nil {
+		return result
+	}
+
+	browserFinal := result.FinalURL
+	FetchResponse(ctx, URL, opts, &result)
+	if browserFinal != "" {
+		result.FinalURL = browserFinal
+	}
+
+	opts.logger().Debug(fmt.Sprintf("URL %s 处理完成，标题: %s，状态: %s", URL, pageTitle, result.Status()))
+	return result
+}
+
+// HTTPCapturer 不渲染页面，只通过 HTTP 请求获取响应信息，结果不包含截图
+type HTTPCapturer struct{}
+
+func (HTTPCapturer) Capture(ctx context.Context, URL string, resultName string, opts *Options) Result {
+	result := NewResult(URL, URL)
+	start := time.Now()
+	FetchResponse(ctx, URL, opts, &result)
+	result.Timings.TotalMs = elapsedMs(start)
+	return result
+}
+
+// fakePNG 为 1x1 像素的 PNG 图片
+var fakePNG, _ = base64.StdEncoding.DecodeString("iVBORw0KGgoAAAANSUhEUgAAAAEAAAABCAYAAAAfFcSJAAAADUlEQVR42mNk+M9QDwADhgGAWjR9awAAAABJRU5ErkJggg==")
+
+// FakeCapturer 不访问网络的确定性实现，用于在没有 Chrome 的环境中验证并发、重试与报告生成
+type FakeCapturer struct {
+	// Delay 为每次访问的模拟耗时
+	Delay time.Duration
+	// Errors 指定始终失败的地址及其错误类型
+	Errors map[string]string
+	// Flaky 指定地址在成功前以 TIMEOUT 失败的次数
+	Flaky map[string]int
+
+	mu       sync.Mutex
+	attempts map[string]int
+}
+
+func (c *FakeCapturer) Capture(ctx context.Context, URL string, resultName string, opts *Options) (result Result) {
+	result = NewResult(URL, URL)
+	start := time.Now()
+	defer func() {
+		result.Timings.TotalMs = elapsedMs(start)
+	}()
+
+	var class string
+	err := opts.retryPolicy().Do(ctx, func(attempt int) (string, error) {
+		if err := sleepUntil(ctx, time.Now().Add(c.Delay)); err != nil {
+			class = ClassNotProcessed
+			return class, err
+		}
+
+		if errClass, ok := c.Errors[URL]; ok {
+			class = errClass
+			return class, fmt.Errorf("模拟错误: %s", errClass)
+		}
+		if c.attempt(URL) <= c.Flaky[URL] {
+			class = ClassTimeout
+			return class, errors.New("模拟超时")
+		}
+		return "", nil
+	})
+	result.Timings.PageMs = elapsedMs(start)
+	if err != nil {
+		result.Fail(class, err)
+		return result
+	}
+
+	result.Title = "Fake " + URL
+	result.StatusCode = 200
+	result.Proto = "HTTP/1.1"
+	saveScreenshot(&result, fakePNG, resultName, opts)
+	return result
+}
+
+// ResolveTargets 不进行协议探测，未指定协议的目标直接使用 https
+func (c *FakeCapturer) ResolveTargets(ctx context.Context, target string, opts *Options) []string {
+	return SchemeCandidates(target)[:1]
+}
+
+func (c *FakeCapturer) attempt(url string) int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.attempts == nil {
+		c.attempts = make(map[string]int)
+	}
+	c.attempts[url]++
+	return c.attempts[url]
+}
+
+// saveScreenshot 将截图按命名模板写入输出目录的 data 子目录，文件名重复时追加序号；未设置输出目录时保存在 result.Image 中
+func saveScreenshot(result *Result, screenshot []byte, resultName string, opts *Options) error {
+	outputDir := opts.outputDir()
+	if outputDir == "" {
+		result.Image = screenshot
+		return nil
+	}
+
+	dataDir := filepath.Join(outputDir, "data")
+	file, fileName, err := createUniqueFile(dataDir, opts.screenshotName(result.URL, resultName), ".png")
+	if err == nil {
+		_, err = file.Write(screenshot)
+		if closeErr := file.Close(); err == nil {
+			err = closeErr
+		}
+	}
+	if err != nil {
+		opts.logger().Error(fmt.Sprintf("保存截图文件失败 %s: %v", dataDir, err))
+		result.Fail(ClassError, fmt.Errorf("保存截图文件失败: %w", err))
+		return err
+	}
+
+	opts.logger().Debug(fmt.Sprintf("截图保存成功: %s", filepath.Join(dataDir, fileName)))
+	result.Screenshot = "data/" + fileName
+	return nil
+}
+
+// userAgentOverride 在标签页中覆盖 User-Agent，使 navigator.userAgent 与请求头一致；
+// 浏览器池中的浏览器沿用启动时的参数，每个任务的 User-Agent 只能在标签页中设置
+func userAgentOverride(opts *Options) chromedp.Action {
+	if opts == nil || opts.UserAgent == "" {
+		return chromedp.ActionFunc(func(ctx context.Context) error { return nil })
+	}
+	return emulation.SetUserAgentOverride(opts.UserAgent)
+}
+
+func extraHeaders(opts *Options) chromedp.Action {
+	headers := opts.headers()
+	if len(headers) == 0 {
+		return chromedp.ActionFunc(func(ctx context.Context) error { return nil })
+	}
+
+	extra := make(network.Headers, len(headers))
+	for name, value := range headers {
+		extra[name] = value
+	}
+	return chromedp.Tasks{network.Enable(), network.SetExtraHTTPHeaders(extra)}
+}
@@ -0,0 +1,17 @@
+package scripts
+
+import (
+	"testing"
+
+	"github.com/chromedp/cdproto/emulation"
+)
+
+func TestUserAgentOverride(t *testing.T) {
+	params, ok := userAgentOverride(&Options{UserAgent: "sowhp-test"}).(*emulation.SetUserAgentOverrideParams)
+	if !ok || params.UserAgent != "sowhp-test" {
+		t.Fatalf("应在标签页中覆盖 User-Agent: %#v", params)
+	}
+	if _, ok := userAgentOverride(&Options{}).(*emulation.SetUserAgentOverrideParams); ok {
+		t.Error("未指定 User-Agent 时不应覆盖")
+	}
+}
@@ -0,0 +1,241 @@
+package scripts
+
+import (
+	log "Sowhp/concert/logger"
+	"fmt"
+	"os"
+	"path/filepath"
+	"runtime"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// RunDiff 为两次运行结果按输入地址匹配后的差异
+type RunDiff struct {
+	OldRun        string       `json:"old_run"`
+	NewRun        string       `json:"new_run"`
+	Threshold     float64      `json:"threshold,omitempty"`
+	Summary       DiffSummary  `json:"summary"`
+	Added         []Result     `json:"added"`
+	Removed       []Result     `json:"removed"`
+	StatusChanged []DiffChange `json:"status_changed"`
+	TitleChanged  []DiffChange `json:"title_changed"`
+	VisualChanged []DiffChange `json:"visual_changed"`
+	Unchanged     int          `json:"unchanged"`
+}
+
+// DiffSummary 为各类差异的数量
+type DiffSummary struct {
+	Added         int `json:"added"`
+	Removed       int `json:"removed"`
+	StatusChanged int `json:"status_changed"`
+	TitleChanged  int `json:"title_changed"`
+	VisualChanged int `json:"visual_changed"`
+	Unchanged     int `json:"unchanged"`
+}
+
+// DiffChange 为同一 URL 在两次运行中的结果。两次都有截图时 Difference 为变化像素占比（0~1），
+// 截图变化超过阈值且指定了 DiffOptions.OverlayDir 时 Overlay 为差异叠加图路径
+type DiffChange struct {
+	URL        string   `json:"url"`
+	Old        Result   `json:"old"`
+	New        Result   `json:"new"`
+	Difference *float64 `json:"difference,omitempty"`
+	Overlay    string   `json:"overlay,omitempty"`
+}
+
+// DiffOptions 为比较两次运行的选项
+type DiffOptions struct {
+	// Visual 为 true 时比较两次运行的截图
+	Visual bool
+	// Threshold 为变化像素占比阈值，超过时视为截图变化，为 0 时使用 DefaultVisualThreshold
+	Threshold float64
+	// Tolerance 为单个像素任一通道的差值容差（0~255），为 0 时使用 DefaultPixelTolerance
+	Tolerance int
+	// OverlayDir 非空时为截图变化的目标在该目录下生成差异叠加图
+	OverlayDir string
+}
+
+// Changed 判断两次运行之间是否存在差异
+func (d *RunDiff) Changed() bool {
+	return len(d.Added) > 0 || len(d.Removed) > 0 || len(d.StatusChanged) > 0 || len(d.TitleChanged) > 0 || len(d.VisualChanged) > 0
+}
+
+// DiffRuns 比较两个运行目录的结果：新出现与消失的服务、状态码变化、标题变化，以及可选的截图变化
+func DiffRuns(oldDir, newDir string, opts DiffOptions) (*RunDiff, error) {
+	oldResults, err := loadDiffResults(oldDir)
+	if err != nil {
+		return nil, err
+	}
+	newResults, err := loadDiffResults(newDir)
+	if err != nil {
+		return nil, err
+	}
+
+	diff := &RunDiff{OldRun: oldDir, NewRun: newDir}
+	var matched []*DiffChange
+	for _, key := range sortedKeys(newResults) {
+		current := newResults[key]
+		previous, ok := oldResults[key]
+		switch {
+		case responded(current) && (!ok || !responded(previous)):
+			diff.Added = append(diff.Added, current)
+		case !responded(current) && ok && responded(previous):
+			diff.Removed = append(diff.Removed, previous)
+		case !ok || !responded(current):
+			continue
+		default:
+			matched = append(matched, &DiffChange{URL: current.URL, Old: previous, New: current})
+		}
+	}
+
+	for _, key := range sortedKeys(oldResults) {
+		if _, ok := newResults[key]; !ok && responded(oldResults[key]) {
+			diff.Removed = append(diff.Removed, oldResults[key])
+		}
+	}
+
+	if opts.Visual {
+		if opts.Threshold <= 0 {
+			opts.Threshold = DefaultVisualThreshold
+		}
+		if opts.Tolerance <= 0 {
+			opts.Tolerance = DefaultPixelTolerance
+		}
+		diff.Threshold = opts.Threshold
+		if err := compareRuns(oldDir, newDir, matched, opts); err != nil {
+			return nil, err
+		}
+	}
+
+	for _, change := range matched {
+		changed := false
+		if change.New.StatusCode != change.Old.StatusCode {
+			diff.StatusChanged = append(diff.StatusChanged, *change)
+			changed = true
+		}
+		if change.New.Title != change.Old.Title {
+			diff.TitleChanged = append(diff.TitleChanged, *change)
+			changed = true
+		}
+		if change.Difference != nil && *change.Difference > opts.Threshold {
+			diff.VisualChanged = append(diff.VisualChanged, *change)
+			changed = true
+		}
+		if !changed {
+			diff.Unchanged++
+		}
+	}
+
+	diff.Summary = DiffSummary{
+		Added:         len(diff.Added),
+		Removed:       len(diff.Removed),
+		StatusChanged: len(diff.StatusChanged),
+		TitleChanged:  len(diff.TitleChanged),
+		VisualChanged: len(diff.VisualChanged),
+		Unchanged:     diff.Unchanged,
+	}
+	return diff, nil
+}
+
+// compareRuns 并发比较两次运行中都有截图的目标，结果写入各 DiffChange 的 Difference 与 Overlay
+func compareRuns(oldDir, newDir string, changes []*DiffChange, opts DiffOptions) error {
+	if opts.OverlayDir != "" {
+		if err := os.MkdirAll(opts.OverlayDir, 0755); err != nil {
+			return fmt.Errorf("创建差异叠加图目录失败: %w", err)
+		}
+	}
+
+	jobs := make(chan *DiffChange)
+	var wg sync.WaitGroup
+	for i := 0; i < min(runtime.NumCPU(), 4); i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for change := range jobs {
+				compareChange(oldDir, newDir, change, opts)
+			}
+		}()
+	}
+	for _, change := range changes {
+		if change.Old.Screenshot != "" && change.New.Screenshot != "" {
+			jobs <- change
+		}
+	}
+	close(jobs)
+	wg.Wait()
+	return nil
+}
+
+func compareChange(oldDir, newDir string, change *DiffChange, opts DiffOptions) {
+	ratio, overlay, err := compareScreenshots(screenshotPath(oldDir, change.Old), screenshotPath(newDir, change.New), opts.Tolerance, opts.OverlayDir != "")
+	if err != nil {
+		log.Warning(fmt.Sprintf("比较截图失败 %s: %v", change.URL, err))
+		return
+	}
+	change.Difference = &ratio
+	if overlay == nil || ratio <= opts.Threshold {
+		return
+	}
+
+	path := filepath.Join(opts.OverlayDir, fmt.Sprintf("%016x.png", hashKey(change.URL)))
+	if err := writePNG(path, overlay); err != nil {
+		log.Warning(fmt.Sprintf("保存差异叠加图失败 %s: %v", change.URL, err))
+		return
+	}
+	change.Overlay = path
+}
+
+// loadDiffResults 读取运行目录中的结果并以输入地址为键，两次运行探测到的协议不同时仍能匹配；
+// 丢弃响应头与响应体预览以降低内存占用
+func loadDiffResults(runDir string) (map[string]Result, error) {
+	if _, err := os.Stat(filepath.Join(runDir, ResultsFileName)); err != nil {
+		return nil, fmt.Errorf("不是有效的运行目录 %s: %w", runDir, err)
+	}
+
+	results := make(map[string]Result)
+	err := ScanResults(runDir, func(record Result) error {
+		if record.NotProcessed() {
+			return nil
+		}
+		record.Headers = nil
+		record.BodyPreview = ""
+		key := record.Input
+		if key == "" {
+			key = record.URL
+		}
+		// -both 时同一输入有 http 与 https 两个结果，https 结果以输入为键，另一个附加协议区分
+		if existing, ok := results[key]; ok {
+			if schemeOf(record.URL) == "https" {
+				results[key], record = record, existing
+			}
+			key += " " + schemeOf(record.URL)
+		}
+		results[key] = record
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("读取运行目录 %s 失败: %w", runDir, err)
+	}
+	return results, nil
+}
+
+func schemeOf(rawURL string) string {
+	scheme, _, _ := strings.Cut(rawURL, "://")
+	return strings.ToLower(scheme)
+}
+
+// responded 判断目标是否有响应：获取到状态码或完成截图
+func responded(result Result) bool {
+	return result.StatusCode > 0 || result.Success()
+}
+
+func sortedKeys(results map[string]Result) []string {
+	keys := make([]string, 0, len(results))
+	for key := range results {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
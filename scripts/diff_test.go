@@ -0,0 +1,103 @@
+package scripts
+
+import (
+	"testing"
+)
+
+// writeRun 在临时目录中写入一次运行的结果
+func writeRun(t *testing.T, results ...Result) string {
+	t.Helper()
+	dir := t.TempDir()
+	store, err := OpenResultStore(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := store.Append(results...); err != nil {
+		t.Fatal(err)
+	}
+	if err := store.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return dir
+}
+
+func testResult(input, url string, status int, title string) Result {
+	result := NewResult(input, url)
+	result.StatusCode = status
+	result.Title = title
+	return result
+}
+
+func TestDiffRunsChanges(t *testing.T) {
+	tests := []struct {
+		name          string
+		old, new      Result
+		status, title int
+	}{
+		{
+			name:   "状态码变化",
+			old:    testResult("a.example", "https://a.example", 200, "首页"),
+			new:    testResult("a.example", "https://a.example", 403, "首页"),
+			status: 1,
+		},
+		{
+			name:  "标题变化",
+			old:   testResult("a.example", "https://a.example", 200, "首页"),
+			new:   testResult("a.example", "https://a.example", 200, "登录"),
+			title: 1,
+		},
+		{
+			name:   "状态码与标题都变化",
+			old:    testResult("a.example", "https://a.example", 200, "首页"),
+			new:    testResult("a.example", "https://a.example", 500, "Internal Server Error"),
+			status: 1,
+			title:  1,
+		},
+		{
+			name: "无变化",
+			old:  testResult("a.example", "https://a.example", 200, "首页"),
+			new:  testResult("a.example", "https://a.example", 200, "首页"),
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			diff, err := DiffRuns(writeRun(t, tt.old), writeRun(t, tt.new), DiffOptions{})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if diff.Summary.StatusChanged != tt.status || diff.Summary.TitleChanged != tt.title {
+				t.Errorf("状态码变化 %d、标题变化 %d，应为 %d、%d", diff.Summary.StatusChanged, diff.Summary.TitleChanged, tt.status, tt.title)
+			}
+			unchanged := 0
+			if tt.status == 0 && tt.title == 0 {
+				unchanged = 1
+			}
+			if diff.Unchanged != unchanged || diff.Summary.Added != 0 || diff.Summary.Removed != 0 {
+				t.Errorf("差异摘要错误: %+v", diff.Summary)
+			}
+		})
+	}
+}
+
+func TestDiffRunsMatchesInput(t *testing.T) {
+	oldDir := writeRun(t,
+		testResult("a.example", "http://a.example", 200, "首页"),
+		testResult("b.example", "https://b.example", 200, "B"),
+	)
+	newDir := writeRun(t,
+		testResult("a.example", "https://a.example", 200, "首页"),
+		testResult("b.example", "http://b.example", 200, "B"),
+		testResult("b.example", "https://b.example", 200, "B"),
+	)
+
+	diff, err := DiffRuns(oldDir, newDir, DiffOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if diff.Summary.Removed != 0 || diff.Summary.Unchanged != 2 {
+		t.Fatalf("协议变化的目标应视为同一目标: %+v", diff.Summary)
+	}
+	if len(diff.Added) != 1 || diff.Added[0].URL != "http://b.example" {
+		t.Errorf("-both 新增的 http 结果应为新服务: %+v", diff.Added)
+	}
+}
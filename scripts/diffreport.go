@@ -0,0 +1,254 @@
+package scripts
+
+import (
+	log "Sowhp/concert/logger"
+	"encoding/json"
+	"fmt"
+	"html"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// 差异报告目录中的文件
+const (
+	DiffReportFileName  = "index.html"
+	DiffSummaryFileName = "diff.json"
+	DiffOverlayDirName  = "overlays"
+)
+
+// diffReportItem 为差异报告页面中的一行，图片路径相对于报告目录
+type diffReportItem struct {
+	Kind       string   `json:"kind"`
+	URL        string   `json:"url"`
+	OldStatus  string   `json:"old_status,omitempty"`
+	NewStatus  string   `json:"new_status,omitempty"`
+	OldTitle   string   `json:"old_title,omitempty"`
+	NewTitle   string   `json:"new_title,omitempty"`
+	OldImage   string   `json:"old_image,omitempty"`
+	NewImage   string   `json:"new_image,omitempty"`
+	Overlay    string   `json:"overlay,omitempty"`
+	Difference *float64 `json:"difference,omitempty"`
+}
+
+// WriteDiffReport 在 outDir 下写入 HTML 差异报告（变化前后的截图并排显示，附差异叠加图）与 JSON 差异摘要
+func WriteDiffReport(diff *RunDiff, outDir string) error {
+	if err := os.MkdirAll(outDir, 0755); err != nil {
+		return fmt.Errorf("创建差异报告目录失败: %w", err)
+	}
+
+	summary, err := json.MarshalIndent(diff, "", "  ")
+	if err != nil {
+		return fmt.Errorf("序列化差异失败: %w", err)
+	}
+	summaryPath := filepath.Join(outDir, DiffSummaryFileName)
+	if err := os.WriteFile(summaryPath, summary, 0644); err != nil {
+		return fmt.Errorf("写入差异摘要失败: %w", err)
+	}
+
+	items := diffReportItems(diff, outDir)
+	data, err := json.Marshal(items)
+	if err != nil {
+		return fmt.Errorf("序列化差异失败: %w", err)
+	}
+
+	title := fmt.Sprintf("%s → %s", filepath.Base(diff.OldRun), filepath.Base(diff.NewRun))
+	page := strings.NewReplacer(
+		"{{title}}", html.EscapeString(title),
+		"{{summary}}", html.EscapeString(diffSummaryText(diff)),
+		"{{items}}", string(data),
+	).Replace(diffReportPage)
+
+	reportPath := filepath.Join(outDir, DiffReportFileName)
+	if err := os.WriteFile(reportPath, []byte(page), 0644); err != nil {
+		return fmt.Errorf("写入差异报告失败: %w", err)
+	}
+	log.Info(fmt.Sprintf("生成差异报告成功: %s", reportPath))
+	return nil
+}
+
+func diffSummaryText(diff *RunDiff) string {
+	text := fmt.Sprintf("新增 %d，消失 %d，状态码变化 %d，标题变化 %d", diff.Summary.Added, diff.Summary.Removed, diff.Summary.StatusChanged, diff.Summary.TitleChanged)
+	if diff.Threshold > 0 {
+		text += fmt.Sprintf("，截图变化 %d（阈值 %.2f%%）", diff.Summary.VisualChanged, diff.Threshold*100)
+	}
+	return text + fmt.Sprintf("，未变化 %d", diff.Summary.Unchanged)
+}
+
+func diffReportItems(diff *RunDiff, outDir string) []diffReportItem {
+	var items []diffReportItem
+	for _, result := range diff.Added {
+		items = append(items, diffReportItem{
+			Kind:      "added",
+			URL:       result.URL,
+			NewStatus: result.Status(),
+			NewTitle:  result.Title,
+			NewImage:  reportImage(outDir, diff.NewRun, result),
+		})
+	}
+	for _, result := range diff.Removed {
+		items = append(items, diffReportItem{
+			Kind:      "removed",
+			URL:       result.URL,
+			OldStatus: result.Status(),
+			OldTitle:  result.Title,
+			OldImage:  reportImage(outDir, diff.OldRun, result),
+		})
+	}
+
+	changes := []struct {
+		kind    string
+		changes []DiffChange
+	}{
+		{"status", diff.StatusChanged},
+		{"title", diff.TitleChanged},
+		{"visual", diff.VisualChanged},
+	}
+	for _, group := range changes {
+		for _, change := range group.changes {
+			item := diffReportItem{
+				Kind:       group.kind,
+				URL:        change.URL,
+				OldStatus:  change.Old.Status(),
+				NewStatus:  change.New.Status(),
+				OldTitle:   change.Old.Title,
+				NewTitle:   change.New.Title,
+				OldImage:   reportImage(outDir, diff.OldRun, change.Old),
+				NewImage:   reportImage(outDir, diff.NewRun, change.New),
+				Difference: change.Difference,
+			}
+			if change.Overlay != "" {
+				item.Overlay = relativePath(outDir, change.Overlay)
+			}
+			items = append(items, item)
+		}
+	}
+	return items
+}
+
+func reportImage(outDir, runDir string, result Result) string {
+	path := screenshotPath(runDir, result)
+	if path == "" {
+		return ""
+	}
+	return relativePath(outDir, path)
+}
+
+// relativePath 返回 target 相对于 baseDir 的路径（使用 / 分隔），无法计算时返回绝对路径
+func relativePath(baseDir, target string) string {
+	absBase, err := filepath.Abs(baseDir)
+	if err != nil {
+		return filepath.ToSlash(target)
+	}
+	absTarget, err := filepath.Abs(target)
+	if err != nil {
+		return filepath.ToSlash(target)
+	}
+	rel, err := filepath.Rel(absBase, absTarget)
+	if err != nil {
+		return filepath.ToSlash(absTarget)
+	}
+	return filepath.ToSlash(rel)
+}
+
+const diffReportPage = `<!DOCTYPE html>
+<html>
+<head>
+    <meta charset="utf-8">
+    <title>Sowhp 差异报告 - {{title}}</title>
+    <style>
+        body { font-family: Arial, sans-serif; margin: 10px; background-color: #f5f5f5; }
+        .container { max-width: 1800px; margin: 0 auto; background-color: white; padding: 20px; border-radius: 8px; box-shadow: 0 2px 10px rgba(0,0,0,0.1); }
+        h1 { color: #333; text-align: center; margin-bottom: 30px; }
+        .summary { background-color: #e3f2fd; padding: 15px; border-radius: 4px; margin-bottom: 20px; }
+        .filter { margin: 10px 0; }
+        .filter select { padding: 4px 8px; }
+        table { width: 100%; border-collapse: collapse; margin-top: 20px; table-layout: fixed; }
+        th, td { padding: 8px; text-align: left; border-bottom: 1px solid #ddd; vertical-align: top; word-wrap: break-word; font-size: 12px; }
+        th { background-color: #4CAF50; color: white; font-weight: bold; font-size: 14px; }
+        th:nth-child(1), td:nth-child(1) { width: 8%; }
+        th:nth-child(2), td:nth-child(2) { width: 16%; }
+        tr:hover { background-color: #f5f5f5; }
+        .url-link { color: #1976D2; text-decoration: none; word-break: break-all; }
+        .url-link:hover { text-decoration: underline; }
+        .shot { width: 100%; height: auto; border: 1px solid #ddd; border-radius: 4px; display: block; margin-top: 6px; }
+        .meta { color: #555; }
+        .empty { color: #999; }
+        .kind { display: inline-block; padding: 2px 6px; border-radius: 4px; color: white; font-weight: bold; }
+        .kind-added { background: #4CAF50; }
+        .kind-removed { background: #f44336; }
+        .kind-status { background: #ff9800; }
+        .kind-title { background: #9c27b0; }
+        .kind-visual { background: #1976D2; }
+    </style>
+</head>
+<body>
+    <div class="container">
+        <h1>网站差异报告 - {{title}}</h1>
+        <div class="summary"><p>{{summary}}</p></div>
+        <div class="filter">
+            <label for="kindFilter">变化类型: </label>
+            <select id="kindFilter">
+                <option value="">全部</option>
+                <option value="added">新增</option>
+                <option value="removed">消失</option>
+                <option value="status">状态码变化</option>
+                <option value="title">标题变化</option>
+                <option value="visual">截图变化</option>
+            </select>
+        </div>
+        <table>
+            <thead>
+                <tr>
+                    <th>变化</th>
+                    <th>URL地址</th>
+                    <th>之前</th>
+                    <th>之后</th>
+                    <th>差异叠加图</th>
+                </tr>
+            </thead>
+            <tbody id="tableBody"></tbody>
+        </table>
+    </div>
+    <script>
+        const items = {{items}} || [];
+        const kindNames = { added: '新增', removed: '消失', status: '状态码变化', title: '标题变化', visual: '截图变化' };
+
+        function escapeHtml(value) {
+            return String(value == null ? '' : value).replace(/[&<>"']/g, c => ({ '&': '&amp;', '<': '&lt;', '>': '&gt;', '"': '&quot;', "'": '&#39;' }[c]));
+        }
+
+        function image(src) {
+            if (!src) return '<div class="empty">无截图</div>';
+            const path = escapeHtml(src);
+            return '<a href="' + path + '" target="_blank"><img class="shot" loading="lazy" src="' + path + '"></a>';
+        }
+
+        function side(status, title, src, present) {
+            if (!present) return '<span class="empty">-</span>';
+            return '<div class="meta">' + escapeHtml(status) + '</div><div>' + escapeHtml(title) + '</div>' + image(src);
+        }
+
+        function render() {
+            const kind = document.getElementById('kindFilter').value;
+            const rows = items.filter(item => !kind || item.kind === kind).map(item => {
+                let label = kindNames[item.kind] || item.kind;
+                if (item.difference != null) label += '<br>' + (item.difference * 100).toFixed(2) + '%';
+                const overlay = item.overlay ? image(item.overlay) : '<span class="empty">-</span>';
+                return '<tr>' +
+                    '<td><span class="kind kind-' + escapeHtml(item.kind) + '">' + label + '</span></td>' +
+                    '<td><a class="url-link" href="' + escapeHtml(item.url) + '" target="_blank">' + escapeHtml(item.url) + '</a></td>' +
+                    '<td>' + side(item.old_status, item.old_title, item.old_image, item.kind !== 'added') + '</td>' +
+                    '<td>' + side(item.new_status, item.new_title, item.new_image, item.kind !== 'removed') + '</td>' +
+                    '<td>' + overlay + '</td>' +
+                    '</tr>';
+            });
+            document.getElementById('tableBody').innerHTML = rows.length ? rows.join('') : '<tr><td colspan="5" class="empty">没有变化</td></tr>';
+        }
+
+        document.getElementById('kindFilter').addEventListener('change', render);
+        render();
+    </script>
+</body>
+</html>
+`
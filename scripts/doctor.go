@@ -0,0 +1,131 @@
+package scripts
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"time"
+
+	"github.com/chromedp/cdproto/browser"
+	"github.com/chromedp/chromedp"
+)
+
+// browserCheckTimeout 为启动浏览器并渲染测试页面的超时时间
+const browserCheckTimeout = 30 * time.Second
+
+// checkPage 为浏览器检查时渲染的本地页面，不访问网络
+const checkPage = "data:text/html,<title>sowhp</title><p>sowhp doctor</p>"
+
+// CheckResult 为单项环境检查的结果
+type CheckResult struct {
+	Name   string `json:"name"`
+	OK     bool   `json:"ok"`
+	Detail string `json:"detail"`
+}
+
+// chromeLocations 与 chromedp 查找浏览器的顺序一致
+func chromeLocations() []string {
+	switch runtime.GOOS {
+	case "darwin":
+		return []string{
+			"/Applications/Chromium.app/Contents/MacOS/Chromium",
+			"/Applications/Google Chrome.app/Contents/MacOS/Google Chrome",
+		}
+	case "windows":
+		return []string{
+			"chrome",
+			"chrome.exe",
+			`C:\Program Files (x86)\Google\Chrome\Application\chrome.exe`,
+			`C:\Program Files\Google\Chrome\Application\chrome.exe`,
+			filepath.Join(os.Getenv("USERPROFILE"), `AppData\Local\Google\Chrome\Application\chrome.exe`),
+			filepath.Join(os.Getenv("USERPROFILE"), `AppData\Local\Chromium\Application\chrome.exe`),
+		}
+	}
+	return []string{
+		"headless_shell",
+		"headless-shell",
+		"chromium",
+		"chromium-browser",
+		"google-chrome",
+		"google-chrome-stable",
+		"google-chrome-beta",
+		"google-chrome-unstable",
+		"/usr/bin/google-chrome",
+		"/usr/local/bin/chrome",
+		"/snap/bin/chromium",
+		"chrome",
+	}
+}
+
+// FindChrome 返回本地 Chrome/Chromium 可执行文件路径
+func FindChrome() (string, error) {
+	for _, location := range chromeLocations() {
+		if path, err := exec.LookPath(location); err == nil {
+			return path, nil
+		}
+	}
+	return "", fmt.Errorf("未找到 Chrome/Chromium，请安装浏览器或将其加入 PATH")
+}
+
+// CheckCapturer 检查截图引擎是否可用，引擎未实现 Checker（如 HTTP 引擎）时第二个返回值为 false
+func CheckCapturer(ctx context.Context, opts *Options) (CheckResult, bool) {
+	checker, ok := opts.capturer().(Checker)
+	if !ok {
+		return CheckResult{}, false
+	}
+	return checker.Check(ctx, opts), true
+}
+
+// CheckWritable 检查目录可创建且可写入
+func CheckWritable(dir string) CheckResult {
+	name := fmt.Sprintf("输出目录 %s", dir)
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return CheckResult{Name: name, Detail: fmt.Sprintf("创建目录失败: %v", err)}
+	}
+
+	file, err := os.CreateTemp(dir, ".sowhp-doctor-*")
+	if err != nil {
+		return CheckResult{Name: name, Detail: fmt.Sprintf("目录不可写: %v", err)}
+	}
+	file.Close()
+	os.Remove(file.Name())
+
+	abs, err := filepath.Abs(dir)
+	if err != nil {
+		abs = dir
+	}
+	return CheckResult{Name: name, OK: true, Detail: abs}
+}
+
+// checkBrowser 在 allocCtx 提供的浏览器中渲染测试页面并截图，返回浏览器版本
+func checkBrowser(allocCtx context.Context, opts *Options) CheckResult {
+	browserCtx, browserCancel := chromedp.NewContext(allocCtx)
+	defer browserCancel()
+	timeoutCtx, cancel := context.WithTimeout(browserCtx, browserCheckTimeout)
+	defer cancel()
+
+	var product, title string
+	var screenshot []byte
+	width, height := opts.viewport()
+	err := chromedp.Run(timeoutCtx,
+		chromedp.ActionFunc(func(ctx context.Context) error {
+			var err error
+			_, product, _, _, _, err = browser.GetVersion().Do(ctx)
+			return err
+		}),
+		chromedp.EmulateViewport(width, height),
+		chromedp.Navigate(checkPage),
+		chromedp.Title(&title),
+		chromedp.CaptureScreenshot(&screenshot),
+	)
+	switch {
+	case err != nil:
+		return CheckResult{Name: "浏览器", Detail: fmt.Sprintf("启动浏览器或渲染测试页面失败: %v", err)}
+	case title != "sowhp" || len(screenshot) == 0:
+		return CheckResult{Name: "浏览器", Detail: "测试页面渲染结果异常"}
+	}
+	return CheckResult{Name: "浏览器", OK: true, Detail: fmt.Sprintf("%s，测试页面渲染与截图正常", product)}
+}
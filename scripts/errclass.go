@@ -0,0 +1,163 @@
+package scripts
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"io"
+	"net"
+	"regexp"
+	"strings"
+	"syscall"
+)
+
+// 错误类型，写入结果的 error_class 字段，浏览器与 HTTP 请求使用同一套分类
+const (
+	ClassTimeout          = "TIMEOUT"
+	ClassDNS              = "DNS_ERROR"
+	ClassConnRefused      = "CONNECTION_REFUSED"
+	ClassConnReset        = "CONNECTION_RESET"
+	ClassUnreachable      = "UNREACHABLE"
+	ClassEmptyResponse    = "EMPTY_RESPONSE"
+	ClassSSL              = "SSL_ERROR"
+	ClassTooManyRedirects = "TOO_MANY_REDIRECTS"
+	ClassBrowser          = "BROWSER_ERROR"
+	ClassOutOfScope       = "OUT_OF_SCOPE"
+	ClassNotProcessed     = "NOT_PROCESSED"
+	ClassError            = "ERROR"
+)
+
+var errTooManyRedirects = errors.New("重定向次数过多")
+
+var chromeErrorPattern = regexp.MustCompile(`net::(ERR_[A-Z0-9_]+)`)
+
+// chromeErrorClasses 将 Chrome 的 net::ERR_* 错误码映射为错误类型，ERR_CERT_* 与 ERR_SSL_* 按前缀处理
+var chromeErrorClasses = map[string]string{
+	"ERR_TIMED_OUT":                ClassTimeout,
+	"ERR_CONNECTION_TIMED_OUT":     ClassTimeout,
+	"ERR_NAME_NOT_RESOLVED":        ClassDNS,
+	"ERR_NAME_RESOLUTION_FAILED":   ClassDNS,
+	"ERR_CONNECTION_REFUSED":       ClassConnRefused,
+	"ERR_CONNECTION_RESET":         ClassConnReset,
+	"ERR_CONNECTION_CLOSED":        ClassConnReset,
+	"ERR_CONNECTION_ABORTED":       ClassConnReset,
+	"ERR_CONNECTION_FAILED":        ClassUnreachable,
+	"ERR_ADDRESS_UNREACHABLE":      ClassUnreachable,
+	"ERR_INTERNET_DISCONNECTED":    ClassUnreachable,
+	"ERR_EMPTY_RESPONSE":           ClassEmptyResponse,
+	"ERR_INVALID_HTTP_RESPONSE":    ClassEmptyResponse,
+	"ERR_TOO_MANY_REDIRECTS":       ClassTooManyRedirects,
+	"ERR_BAD_SSL_CLIENT_AUTH_CERT": ClassSSL,
+	"ERR_BLOCKED_BY_CLIENT":        ClassOutOfScope,
+}
+
+// ClassifyError 将错误归类为上述错误类型之一，依次检查范围拦截、取消、证书、DNS、系统调用错误码、
+// Chrome 错误码与超时，均无法识别时回退到按错误文本匹配
+func ClassifyError(err error) string {
+	if err == nil {
+		return ""
+	}
+
+	switch {
+	case errors.Is(err, ErrOutOfScope):
+		return ClassOutOfScope
+	case errors.Is(err, errTooManyRedirects):
+		return ClassTooManyRedirects
+	case errors.Is(err, context.Canceled):
+		return ClassNotProcessed
+	}
+
+	if isTLSError(err) {
+		return ClassSSL
+	}
+
+	var dnsErr *net.DNSError
+	if errors.As(err, &dnsErr) {
+		if dnsErr.IsTimeout {
+			return ClassTimeout
+		}
+		return ClassDNS
+	}
+
+	switch {
+	case errors.Is(err, syscall.ECONNREFUSED):
+		return ClassConnRefused
+	case errors.Is(err, syscall.ECONNRESET), errors.Is(err, syscall.ECONNABORTED), errors.Is(err, syscall.EPIPE):
+		return ClassConnReset
+	case errors.Is(err, syscall.EHOSTUNREACH), errors.Is(err, syscall.ENETUNREACH):
+		return ClassUnreachable
+	case errors.Is(err, io.EOF), errors.Is(err, io.ErrUnexpectedEOF):
+		return ClassEmptyResponse
+	}
+
+	if class := classifyChromeError(err.Error()); class != "" {
+		return class
+	}
+
+	var netErr net.Error
+	if errors.Is(err, context.DeadlineExceeded) || (errors.As(err, &netErr) && netErr.Timeout()) {
+		return ClassTimeout
+	}
+
+	return classifyErrorText(err.Error())
+}
+
+func isTLSError(err error) bool {
+	var unknownAuthority x509.UnknownAuthorityError
+	var hostname x509.HostnameError
+	var invalid x509.CertificateInvalidError
+	var verification *tls.CertificateVerificationError
+	var recordHeader tls.RecordHeaderError
+	var alert tls.AlertError
+
+	return errors.As(err, &unknownAuthority) ||
+		errors.As(err, &hostname) ||
+		errors.As(err, &invalid) ||
+		errors.As(err, &verification) ||
+		errors.As(err, &recordHeader) ||
+		errors.As(err, &alert)
+}
+
+func classifyChromeError(message string) string {
+	match := chromeErrorPattern.FindStringSubmatch(message)
+	if match == nil {
+		return ""
+	}
+
+	code := match[1]
+	if class, ok := chromeErrorClasses[code]; ok {
+		return class
+	}
+	if strings.HasPrefix(code, "ERR_CERT_") || strings.HasPrefix(code, "ERR_SSL_") {
+		return ClassSSL
+	}
+	return ClassError
+}
+
+// classifyErrorText 处理未携带具体错误类型的错误（如 chromedp 返回的纯文本错误）
+func classifyErrorText(message string) string {
+	message = strings.ToLower(message)
+	switch {
+	case strings.Contains(message, "timeout") || strings.Contains(message, "deadline exceeded"):
+		return ClassTimeout
+	case strings.Contains(message, "connection refused"):
+		return ClassConnRefused
+	case strings.Contains(message, "connection reset"):
+		return ClassConnReset
+	case strings.Contains(message, "no such host"):
+		return ClassDNS
+	case strings.Contains(message, "certificate") || strings.Contains(message, "tls:") || strings.Contains(message, "x509"):
+		return ClassSSL
+	}
+	return ClassError
+}
+
+// retryable 判断该类型的错误是否值得重试
+func retryable(class string) bool {
+	switch class {
+	case "", ClassOutOfScope, ClassNotProcessed, ClassBrowser, ClassTooManyRedirects:
+		return false
+	}
+	return true
+}
@@ -0,0 +1,84 @@
+package scripts
+
+import (
+	"context"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"syscall"
+	"testing"
+	"time"
+)
+
+func TestClassifyError(t *testing.T) {
+	opError := func(err error) error {
+		return &net.OpError{Op: "dial", Net: "tcp", Err: os.NewSyscallError("connect", err)}
+	}
+
+	tests := []struct {
+		name string
+		err  error
+		want string
+	}{
+		{name: "nil", err: nil, want: ""},
+		{name: "范围拦截", err: fmt.Errorf("%w: https://b.example", ErrOutOfScope), want: ClassOutOfScope},
+		{name: "重定向过多", err: fmt.Errorf("Get \"x\": %w", errTooManyRedirects), want: ClassTooManyRedirects},
+		{name: "取消", err: fmt.Errorf("截图失败: %w", context.Canceled), want: ClassNotProcessed},
+		{name: "超时", err: context.DeadlineExceeded, want: ClassTimeout},
+		{name: "DNS", err: &net.DNSError{Err: "no such host", Name: "a.invalid", IsNotFound: true}, want: ClassDNS},
+		{name: "DNS 超时", err: &net.DNSError{Err: "i/o timeout", Name: "a.example", IsTimeout: true}, want: ClassTimeout},
+		{name: "连接被拒绝", err: opError(syscall.ECONNREFUSED), want: ClassConnRefused},
+		{name: "连接被重置", err: opError(syscall.ECONNRESET), want: ClassConnReset},
+		{name: "管道断开", err: opError(syscall.EPIPE), want: ClassConnReset},
+		{name: "主机不可达", err: opError(syscall.EHOSTUNREACH), want: ClassUnreachable},
+		{name: "空响应", err: fmt.Errorf("Get \"x\": %w", io.EOF), want: ClassEmptyResponse},
+		{name: "证书", err: fmt.Errorf("Get \"x\": %w", x509.UnknownAuthorityError{}), want: ClassSSL},
+		{name: "Chrome 超时", err: errors.New("page load error net::ERR_CONNECTION_TIMED_OUT"), want: ClassTimeout},
+		{name: "Chrome DNS", err: errors.New("page load error net::ERR_NAME_NOT_RESOLVED"), want: ClassDNS},
+		{name: "Chrome 证书", err: errors.New("page load error net::ERR_CERT_AUTHORITY_INVALID"), want: ClassSSL},
+		{name: "Chrome SSL", err: errors.New("page load error net::ERR_SSL_PROTOCOL_ERROR"), want: ClassSSL},
+		{name: "Chrome 范围拦截", err: errors.New("page load error net::ERR_BLOCKED_BY_CLIENT"), want: ClassOutOfScope},
+		{name: "Chrome 未知错误", err: errors.New("page load error net::ERR_ABORTED"), want: ClassError},
+		{name: "文本超时", err: errors.New("websocket read timeout"), want: ClassTimeout},
+		{name: "文本证书", err: errors.New("remote error: tls: handshake failure"), want: ClassSSL},
+		{name: "未知错误", err: errors.New("something went wrong"), want: ClassError},
+	}
+	for _, tt := range tests {
+		if got := ClassifyError(tt.err); got != tt.want {
+			t.Errorf("%s: ClassifyError(%v) = %q，应为 %q", tt.name, tt.err, got, tt.want)
+		}
+	}
+}
+
+// TestClassifyNetworkError 使用真实的网络错误，确认标准库返回的错误链能被识别
+func TestClassifyNetworkError(t *testing.T) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	closed := ln.Addr().String()
+	ln.Close()
+	if _, err := net.Dial("tcp", closed); ClassifyError(err) != ClassConnRefused {
+		t.Errorf("连接已关闭的端口: %v 归类为 %q", err, ClassifyError(err))
+	}
+
+	server := httptest.NewTLSServer(http.NotFoundHandler())
+	defer server.Close()
+	if _, err := http.Get(server.URL); ClassifyError(err) != ClassSSL {
+		t.Errorf("自签名证书: %v 归类为 %q", err, ClassifyError(err))
+	}
+
+	slow := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		<-r.Context().Done()
+	}))
+	defer slow.Close()
+	client := &http.Client{Timeout: 20 * time.Millisecond}
+	if _, err := client.Get(slow.URL); ClassifyError(err) != ClassTimeout {
+		t.Errorf("请求超时: %v 归类为 %q", err, ClassifyError(err))
+	}
+}
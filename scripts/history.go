@@ -0,0 +1,171 @@
+package scripts
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// HistoryFileName 为监控模式下记录每个地址历次结果的文件，位于输出目录中
+const HistoryFileName = "history.jsonl"
+
+// 变化类型
+const (
+	ChangeNewService = "new_service"
+	ChangeGone       = "service_gone"
+	ChangeStatus     = "status_changed"
+	ChangeTitle      = "title_changed"
+	ChangeVisual     = "visual_changed"
+)
+
+// ChangeEvent 为同一地址在相邻两次运行之间的一项变化
+type ChangeEvent struct {
+	Type       string   `json:"type"`
+	URL        string   `json:"url"`
+	OldStatus  string   `json:"old_status,omitempty"`
+	NewStatus  string   `json:"new_status,omitempty"`
+	OldTitle   string   `json:"old_title,omitempty"`
+	NewTitle   string   `json:"new_title,omitempty"`
+	Difference *float64 `json:"difference,omitempty"`
+}
+
+// HistoryEntry 为某个地址在一次运行中的结果，Screenshot 为相对于输出目录的路径
+type HistoryEntry struct {
+	URL        string    `json:"url"`
+	Run        string    `json:"run"`
+	CapturedAt time.Time `json:"captured_at"`
+	Status     string    `json:"status,omitempty"`
+	Title      string    `json:"title,omitempty"`
+	ErrorClass string    `json:"error_class,omitempty"`
+	Screenshot string    `json:"screenshot,omitempty"`
+	Changes    []string  `json:"changes,omitempty"`
+}
+
+// Events 将差异展开为按地址的变化列表，同一地址可能同时有多项变化
+func (d *RunDiff) Events() []ChangeEvent {
+	var events []ChangeEvent
+	for _, result := range d.Added {
+		events = append(events, ChangeEvent{Type: ChangeNewService, URL: result.URL, NewStatus: result.Status(), NewTitle: result.Title})
+	}
+	for _, result := range d.Removed {
+		events = append(events, ChangeEvent{Type: ChangeGone, URL: result.URL, OldStatus: result.Status(), OldTitle: result.Title})
+	}
+
+	groups := []struct {
+		kind    string
+		changes []DiffChange
+	}{
+		{ChangeStatus, d.StatusChanged},
+		{ChangeTitle, d.TitleChanged},
+		{ChangeVisual, d.VisualChanged},
+	}
+	for _, group := range groups {
+		for _, change := range group.changes {
+			events = append(events, ChangeEvent{
+				Type:       group.kind,
+				URL:        change.URL,
+				OldStatus:  change.Old.Status(),
+				NewStatus:  change.New.Status(),
+				OldTitle:   change.Old.Title,
+				NewTitle:   change.New.Title,
+				Difference: change.Difference,
+			})
+		}
+	}
+	return events
+}
+
+// AppendHistory 将运行目录中每个地址的结果及其变化追加到 dir 下的历史文件；
+// 本次运行中没有结果但已消失的地址同样记录一条
+func AppendHistory(dir, runDir string, events []ChangeEvent) error {
+	changes := make(map[string][]string)
+	for _, event := range events {
+		changes[event.URL] = append(changes[event.URL], event.Type)
+	}
+
+	writer, err := openJSONL(filepath.Join(dir, HistoryFileName), os.O_APPEND)
+	if err != nil {
+		return fmt.Errorf("打开历史文件失败: %w", err)
+	}
+	defer writer.Close()
+
+	run := filepath.Base(runDir)
+	var entries []interface{}
+	err = ScanResults(runDir, func(result Result) error {
+		if result.NotProcessed() {
+			return nil
+		}
+		entry := HistoryEntry{
+			URL:        result.URL,
+			Run:        run,
+			CapturedAt: result.CapturedAt,
+			Status:     result.Status(),
+			Title:      result.Title,
+			ErrorClass: result.ErrorClass,
+			Changes:    changes[result.URL],
+		}
+		if result.Screenshot != "" {
+			entry.Screenshot = run + "/" + result.Screenshot
+		}
+		delete(changes, result.URL)
+		entries = append(entries, entry)
+		return nil
+	})
+	if err != nil {
+		return fmt.Errorf("读取运行结果失败: %w", err)
+	}
+
+	now := time.Now()
+	for _, event := range events {
+		if types, ok := changes[event.URL]; ok {
+			entries = append(entries, HistoryEntry{URL: event.URL, Run: run, CapturedAt: now, Changes: types})
+			delete(changes, event.URL)
+		}
+	}
+	return writer.append(entries...)
+}
+
+// ScanHistory 按时间顺序读取 url 的历史记录，url 为空时读取全部记录
+func ScanHistory(dir, url string, fn func(HistoryEntry) error) error {
+	return scanJSONL(filepath.Join(dir, HistoryFileName), func(line []byte) error {
+		var entry HistoryEntry
+		if err := json.Unmarshal(line, &entry); err != nil {
+			return nil
+		}
+		if url != "" && entry.URL != url {
+			return nil
+		}
+		return fn(entry)
+	})
+}
+
+// PruneHistory 只保留属于 runs 中运行的历史记录
+func PruneHistory(dir string, runs []string) error {
+	keep := make(map[string]bool, len(runs))
+	for _, run := range runs {
+		keep[run] = true
+	}
+
+	path := filepath.Join(dir, HistoryFileName)
+	tmpPath := path + ".tmp"
+	writer, err := openJSONL(tmpPath, os.O_TRUNC)
+	if err != nil {
+		return fmt.Errorf("整理历史文件失败: %w", err)
+	}
+	err = ScanHistory(dir, "", func(entry HistoryEntry) error {
+		if !keep[entry.Run] {
+			return nil
+		}
+		return writer.append(entry)
+	})
+	if closeErr := writer.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		os.Remove(tmpPath)
+		return fmt.Errorf("整理历史文件失败: %w", err)
+	}
+	return os.Rename(tmpPath, path)
+}
@@ -0,0 +1,464 @@
+package scripts
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// TagsFileName 为运行目录中保存结果标签的文件，键为结果 URL
+const TagsFileName = "tags.json"
+
+const (
+	defaultPageSize = 50
+	maxPageSize     = 500
+)
+
+// IndexedResult 为索引中的单条结果，ID 由运行名称与序号组成
+type IndexedResult struct {
+	ID   string   `json:"id"`
+	Run  string   `json:"run"`
+	Tags []string `json:"tags"`
+	Result
+
+	search string
+}
+
+// ResultSummary 为列表与画廊展示用的结果摘要，不包含响应头与响应体
+type ResultSummary struct {
+	ID         string   `json:"id"`
+	Run        string   `json:"run"`
+	URL        string   `json:"url"`
+	FinalURL   string   `json:"final_url,omitempty"`
+	Title      string   `json:"title"`
+	Status     string   `json:"status"`
+	ErrorClass string   `json:"error_class,omitempty"`
+	Error      string   `json:"error,omitempty"`
+	Server     string   `json:"server,omitempty"`
+	Screenshot string   `json:"screenshot,omitempty"`
+	Tags       []string `json:"tags"`
+}
+
+// ResultQuery 为结果检索条件，Status 可以是具体状态码、错误类型或 2xx 这样的状态码段
+type ResultQuery struct {
+	Run        string
+	Text       string
+	Status     string
+	ErrorClass string
+	Tag        string
+	Page       int
+	Size       int
+}
+
+type ResultPage struct {
+	Total int             `json:"total"`
+	Page  int             `json:"page"`
+	Size  int             `json:"size"`
+	Items []ResultSummary `json:"items"`
+}
+
+// RunSummary 为已索引运行目录的概况
+type RunSummary struct {
+	Name    string    `json:"name"`
+	Dir     string    `json:"dir"`
+	Total   int       `json:"total"`
+	Success int       `json:"success"`
+	Updated time.Time `json:"updated"`
+}
+
+// Facets 为筛选项及其结果数
+type Facets struct {
+	Statuses map[string]int `json:"statuses"`
+	Classes  map[string]int `json:"classes"`
+	Tags     map[string]int `json:"tags"`
+}
+
+type indexedRun struct {
+	name    string
+	dir     string
+	modTime time.Time
+	size    int64
+	results []*IndexedResult
+	tags    map[string][]string
+}
+
+// ResultIndex 为一个或多个运行目录的内存索引，结果文件变化后自动重新加载
+type ResultIndex struct {
+	mu   sync.RWMutex
+	runs []*indexedRun
+}
+
+// FindRunDirs 返回 root 下包含结果文件的运行目录，按名称排序
+func FindRunDirs(root string) ([]string, error) {
+	entries, err := os.ReadDir(root)
+	if err != nil {
+		return nil, fmt.Errorf("读取结果目录失败: %w", err)
+	}
+
+	var dirs []string
+	for _, entry := range entries {
+		if !entry.IsDir() {
+			continue
+		}
+		dir := filepath.Join(root, entry.Name())
+		if _, err := os.Stat(filepath.Join(dir, ResultsFileName)); err == nil {
+			dirs = append(dirs, dir)
+		}
+	}
+	sort.Strings(dirs)
+	return dirs, nil
+}
+
+// NewResultIndex 索引指定的运行目录，运行名称重复时追加序号区分
+func NewResultIndex(dirs []string) (*ResultIndex, error) {
+	index := &ResultIndex{}
+	names := make(map[string]int)
+	for _, dir := range dirs {
+		if _, err := os.Stat(filepath.Join(dir, ResultsFileName)); err != nil {
+			return nil, fmt.Errorf("不是有效的运行目录 %s: %w", dir, err)
+		}
+
+		name := filepath.Base(filepath.Clean(dir))
+		names[name]++
+		if names[name] > 1 {
+			name = fmt.Sprintf("%s-%d", name, names[name])
+		}
+
+		run := &indexedRun{name: name, dir: dir}
+		if err := run.load(); err != nil {
+			return nil, err
+		}
+		index.runs = append(index.runs, run)
+	}
+	return index, nil
+}
+
+// load 读取运行目录的结果与标签，同一 URL 只保留最后一条结果
+func (r *indexedRun) load() error {
+	info, err := os.Stat(filepath.Join(r.dir, ResultsFileName))
+	if err != nil {
+		return err
+	}
+	tags, err := LoadTags(r.dir)
+	if err != nil {
+		return err
+	}
+
+	positions := make(map[string]int)
+	var results []*IndexedResult
+	err = ScanResults(r.dir, func(record Result) error {
+		item := &IndexedResult{Run: r.name, Result: record, Tags: tags[record.URL]}
+		item.search = searchText(&record)
+		if i, ok := positions[record.URL]; ok {
+			results[i] = item
+			return nil
+		}
+		positions[record.URL] = len(results)
+		results = append(results, item)
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	for i, item := range results {
+		item.ID = r.name + ":" + strconv.Itoa(i)
+	}
+	r.results, r.tags = results, tags
+	r.modTime, r.size = info.ModTime(), info.Size()
+	return nil
+}
+
+// searchText 为全文检索拼接 URL、标题、错误、响应头与响应体预览
+func searchText(result *Result) string {
+	var builder strings.Builder
+	builder.WriteString(result.URL)
+	builder.WriteString("\n")
+	builder.WriteString(result.FinalURL)
+	builder.WriteString("\n")
+	builder.WriteString(result.Title)
+	builder.WriteString("\n")
+	builder.WriteString(result.Error)
+	builder.WriteString("\n")
+	for name, values := range result.Headers {
+		for _, value := range values {
+			builder.WriteString(name + ": " + value + "\n")
+		}
+	}
+	builder.WriteString(result.BodyPreview)
+	return strings.ToLower(builder.String())
+}
+
+// Refresh 重新加载结果文件发生变化的运行目录
+func (x *ResultIndex) Refresh() error {
+	x.mu.Lock()
+	defer x.mu.Unlock()
+
+	for _, run := range x.runs {
+		info, err := os.Stat(filepath.Join(run.dir, ResultsFileName))
+		if err != nil {
+			continue
+		}
+		if info.ModTime().Equal(run.modTime) && info.Size() == run.size {
+			continue
+		}
+		if err := run.load(); err != nil {
+			return fmt.Errorf("重新加载运行目录 %s 失败: %w", run.dir, err)
+		}
+	}
+	return nil
+}
+
+func (x *ResultIndex) Runs() []RunSummary {
+	x.mu.RLock()
+	defer x.mu.RUnlock()
+
+	summaries := make([]RunSummary, 0, len(x.runs))
+	for _, run := range x.runs {
+		summary := RunSummary{Name: run.name, Dir: run.dir, Total: len(run.results), Updated: run.modTime}
+		for _, item := range run.results {
+			if item.Success() {
+				summary.Success++
+			}
+		}
+		summaries = append(summaries, summary)
+	}
+	return summaries
+}
+
+// RunDir 返回运行名称对应的目录
+func (x *ResultIndex) RunDir(name string) (string, bool) {
+	x.mu.RLock()
+	defer x.mu.RUnlock()
+
+	for _, run := range x.runs {
+		if run.name == name {
+			return run.dir, true
+		}
+	}
+	return "", false
+}
+
+// Search 按条件检索结果并分页，结果按运行目录与结果文件中的顺序排列
+func (x *ResultIndex) Search(query ResultQuery) ResultPage {
+	x.mu.RLock()
+	defer x.mu.RUnlock()
+
+	if query.Size < 1 {
+		query.Size = defaultPageSize
+	}
+	if query.Size > maxPageSize {
+		query.Size = maxPageSize
+	}
+	if query.Page < 1 {
+		query.Page = 1
+	}
+	terms := strings.Fields(strings.ToLower(query.Text))
+
+	page := ResultPage{Page: query.Page, Size: query.Size, Items: []ResultSummary{}}
+	start := (query.Page - 1) * query.Size
+	x.each(query.Run, func(item *IndexedResult) {
+		if !item.matches(query, terms) {
+			return
+		}
+		if page.Total >= start && len(page.Items) < query.Size {
+			page.Items = append(page.Items, item.summary())
+		}
+		page.Total++
+	})
+	return page
+}
+
+// Facets 统计各状态、错误类型与标签的结果数
+func (x *ResultIndex) Facets(run string) Facets {
+	x.mu.RLock()
+	defer x.mu.RUnlock()
+
+	facets := Facets{Statuses: map[string]int{}, Classes: map[string]int{}, Tags: map[string]int{}}
+	x.each(run, func(item *IndexedResult) {
+		facets.Statuses[item.Status()]++
+		if item.ErrorClass != "" {
+			facets.Classes[item.ErrorClass]++
+		}
+		for _, tag := range item.Tags {
+			facets.Tags[tag]++
+		}
+	})
+	return facets
+}
+
+// Get 按 ID 返回完整结果
+func (x *ResultIndex) Get(id string) (IndexedResult, bool) {
+	x.mu.RLock()
+	defer x.mu.RUnlock()
+
+	item := x.lookup(id)
+	if item == nil {
+		return IndexedResult{}, false
+	}
+	return *item, true
+}
+
+// SetTags 设置结果的标签并写入运行目录的标签文件
+func (x *ResultIndex) SetTags(id string, tags []string) error {
+	x.mu.Lock()
+	defer x.mu.Unlock()
+
+	item := x.lookup(id)
+	if item == nil {
+		return fmt.Errorf("结果不存在: %s", id)
+	}
+
+	tags = normalizeTags(tags)
+	for _, run := range x.runs {
+		if run.name != item.Run {
+			continue
+		}
+		if len(tags) == 0 {
+			delete(run.tags, item.URL)
+		} else {
+			run.tags[item.URL] = tags
+		}
+		if err := SaveTags(run.dir, run.tags); err != nil {
+			return err
+		}
+	}
+	item.Tags = tags
+	return nil
+}
+
+func (x *ResultIndex) each(run string, fn func(*IndexedResult)) {
+	for _, indexed := range x.runs {
+		if run != "" && indexed.name != run {
+			continue
+		}
+		for _, item := range indexed.results {
+			fn(item)
+		}
+	}
+}
+
+func (x *ResultIndex) lookup(id string) *IndexedResult {
+	sep := strings.LastIndex(id, ":")
+	if sep < 0 {
+		return nil
+	}
+	position, err := strconv.Atoi(id[sep+1:])
+	if err != nil {
+		return nil
+	}
+	for _, run := range x.runs {
+		if run.name == id[:sep] && position >= 0 && position < len(run.results) {
+			return run.results[position]
+		}
+	}
+	return nil
+}
+
+func (item *IndexedResult) matches(query ResultQuery, terms []string) bool {
+	if query.Status != "" && !matchStatus(&item.Result, query.Status) {
+		return false
+	}
+	if query.ErrorClass != "" && item.ErrorClass != query.ErrorClass {
+		return false
+	}
+	if query.Tag != "" && !containsString(item.Tags, query.Tag) {
+		return false
+	}
+	for _, term := range terms {
+		if !strings.Contains(item.search, term) {
+			return false
+		}
+	}
+	return true
+}
+
+// matchStatus 匹配具体状态（如 200、TIMEOUT）或状态码段（如 2xx）
+func matchStatus(result *Result, status string) bool {
+	status = strings.ToLower(status)
+	if len(status) == 3 && strings.HasSuffix(status, "xx") {
+		return result.StatusCode > 0 && strconv.Itoa(result.StatusCode/100) == status[:1]
+	}
+	return strings.EqualFold(result.Status(), status)
+}
+
+func (item *IndexedResult) summary() ResultSummary {
+	tags := item.Tags
+	if tags == nil {
+		tags = []string{}
+	}
+	return ResultSummary{
+		ID:         item.ID,
+		Run:        item.Run,
+		URL:        item.URL,
+		FinalURL:   item.FinalURL,
+		Title:      item.Title,
+		Status:     item.Status(),
+		ErrorClass: item.ErrorClass,
+		Error:      item.Error,
+		Server:     item.Server,
+		Screenshot: item.Screenshot,
+		Tags:       tags,
+	}
+}
+
+func normalizeTags(tags []string) []string {
+	var normalized []string
+	for _, tag := range tags {
+		tag = strings.TrimSpace(tag)
+		if tag != "" && !containsString(normalized, tag) {
+			normalized = append(normalized, tag)
+		}
+	}
+	sort.Strings(normalized)
+	return normalized
+}
+
+func containsString(list []string, s string) bool {
+	for _, item := range list {
+		if item == s {
+			return true
+		}
+	}
+	return false
+}
+
+// LoadTags 读取运行目录的标签文件，文件不存在时返回空映射
+func LoadTags(runDir string) (map[string][]string, error) {
+	tags := make(map[string][]string)
+	data, err := os.ReadFile(filepath.Join(runDir, TagsFileName))
+	if errors.Is(err, os.ErrNotExist) {
+		return tags, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("读取标签文件失败: %w", err)
+	}
+	if err := json.Unmarshal(data, &tags); err != nil {
+		return nil, fmt.Errorf("解析标签文件失败: %w", err)
+	}
+	return tags, nil
+}
+
+// SaveTags 先写入临时文件再替换，避免写入中断导致标签文件损坏
+func SaveTags(runDir string, tags map[string][]string) error {
+	data, err := json.MarshalIndent(tags, "", "  ")
+	if err != nil {
+		return fmt.Errorf("序列化标签失败: %w", err)
+	}
+
+	path := filepath.Join(runDir, TagsFileName)
+	if err := os.WriteFile(path+".tmp", data, 0644); err != nil {
+		return fmt.Errorf("写入标签文件失败: %w", err)
+	}
+	if err := os.Rename(path+".tmp", path); err != nil {
+		return fmt.Errorf("写入标签文件失败: %w", err)
+	}
+	return nil
+}
@@ -4,42 +4,50 @@ import (
 	log "Sowhp/concert/logger"
 	"bufio"
 	"context"
-	"crypto/tls"
+	"errors"
 	"fmt"
 	"io"
+	"net"
 	"net/http"
+	"net/url"
 	"os"
 	"regexp"
 	"strconv"
 	"strings"
 	"time"
+	"unicode"
 
+	"github.com/chromedp/cdproto/cdp"
+	"github.com/chromedp/cdproto/fetch"
+	"github.com/chromedp/cdproto/network"
 	"github.com/chromedp/chromedp"
-	"github.com/chromedp/chromedp/device"
+	"golang.org/x/net/idna"
 )
 
-func extractDomainAndIP(url string) (domain string) {
-	s := strings.TrimPrefix(url, "http://")
-	s = strings.TrimPrefix(s, "https://")
-	s = strings.ReplaceAll(s, ":", "_")
-	s = strings.ReplaceAll(s, "/", "_")
-	s = strings.ReplaceAll(s, "..", "__")
-	reg := regexp.MustCompile(`[^a-zA-Z0-9._-]`)
-	s = reg.ReplaceAllString(s, "")
-	
-	return s
+func FindTextUrl(filepath string) []string {
+	urls := []string{}
+	err := ScanTextUrl(filepath, func(url string) error {
+		urls = append(urls, url)
+		return nil
+	})
+	if err != nil {
+		log.Error(err.Error())
+		return []string{}
+	}
+
+	log.Info(fmt.Sprintf("文件成功提取到 %d 个地址, 开始执行...", len(urls)))
+	return urls
 }
 
-func FindTextUrl(filepath string) []string {
+// ScanTextUrl 逐行读取文件并提取地址，每提取到一个地址调用一次 fn，不在内存中保留完整列表
+func ScanTextUrl(filepath string, fn func(url string) error) error {
 	if filepath == "" {
-		log.Error("文件路径不能为空")
-		return []string{}
+		return errors.New("文件路径不能为空")
 	}
 
 	file, err := os.Open(filepath)
 	if err != nil {
-		log.Error(fmt.Sprintf("无法打开文件 %s: %v", filepath, err))
-		return []string{}
+		return fmt.Errorf("无法打开文件 %s: %v", filepath, err)
 	}
 	defer func() {
 		if closeErr := file.Close(); closeErr != nil {
@@ -47,9 +55,8 @@ func FindTextUrl(filepath string) []string {
 		}
 	}()
 
-	urls := []string{}
-
 	scanner := bufio.NewScanner(file)
+	scanner.Buffer(make([]byte, 64*1024), 4*1024*1024)
 	lineNum := 0
 	for scanner.Scan() {
 		lineNum++
@@ -58,269 +65,369 @@ func FindTextUrl(filepath string) []string {
 			continue
 		}
 
-		urlList := extractURL(line)
+		urlList, rejected := extractURL(line)
 		for _, url := range urlList {
 			if url != "" {
-				urls = append(urls, url)
 				log.Debug(fmt.Sprintf("第 %d 行提取到地址: %s", lineNum, url))
+				if err := fn(url); err != nil {
+					return err
+				}
 			}
 		}
+		for _, token := range rejected {
+			log.Debug(fmt.Sprintf("第 %d 行忽略无效地址: %s", lineNum, token))
+		}
 	}
 
 	if err := scanner.Err(); err != nil {
-		log.Error(fmt.Sprintf("读取 %s 时发生错误: %v", filepath, err))
-		return []string{}
+		return fmt.Errorf("读取 %s 时发生错误: %v", filepath, err)
 	}
-
-	log.Info(fmt.Sprintf("文件成功提取到 %d 个地址, 开始执行...", len(urls)))
-	return urls
+	return nil
 }
 
-func extractURL(line string) []string {
-
-	var urlTmpList []string
-	var https string = "https://"
-
-	if strings.Contains(line, "http://") || strings.Contains(line, "https://") {
-		urllist := append(urlTmpList, line)
-		return urllist
-	} else if IsIPAddress(line) || IsDomainName(line) {
-		line1 := https + line
-		urllist := append(urlTmpList, line1)
-		return urllist
-	} else if IsIPAddressWithPort(line) || IsDomainNameWithPort(line) {
-		line1 := https + line
-		urllist := append(urlTmpList, line1)
-		return urllist
+var (
+	urlPattern      = regexp.MustCompile(`(?i)\bhttps?://[^\s<>"'` + "`" + `，。；、“”‘’《》「」【】（）]+`)
+	ipv6Pattern     = regexp.MustCompile(`\[[0-9A-Fa-f:.]+\](:[0-9]+)?(/[^\s<>"'` + "`" + `，。；、“”‘’《》「」【】（）]*)?`)
+	tokenSeparators = regexp.MustCompile(`[\s,;|=<>"'` + "`" + `()\[\]{}，。；、“”‘’《》「」【】（）]+`)
+	fileExtensions  = map[string]bool{
+		"txt": true, "log": true, "md": true, "csv": true, "json": true, "xml": true, "yaml": true, "yml": true,
+		"html": true, "htm": true, "php": true, "asp": true, "aspx": true, "jsp": true, "js": true, "css": true,
+		"png": true, "jpg": true, "jpeg": true, "gif": true, "svg": true, "ico": true, "pdf": true, "doc": true,
+		"docx": true, "xls": true, "xlsx": true, "zip": true, "gz": true, "tar": true, "rar": true, "exe": true,
+		"dll": true, "so": true, "go": true, "py": true, "java": true, "sh": true, "bat": true, "conf": true,
+		"ini": true, "bak": true, "tmp": true,
 	}
+)
 
-	return []string{}
-}
-
-func visitURL(url string) chromedp.Tasks {
-	return chromedp.Tasks{
-		chromedp.Navigate(url),
-	}
-}
-
-func GetTimeStrin() string {
-	currentTime := time.Now()
-	dateString := currentTime.Format("20060102")
-
-	baseDir := "./result"
-	counter := 1
+const trimPunctuation = ".,;:!?'\"`()[]{}<>，。；：！？、“”‘’（）【】《》「」"
 
+// trimToken 去除地址两侧的标点与引号，保留成对出现的右括号
+func trimToken(token string) string {
+	token = strings.TrimLeft(token, trimPunctuation)
 	for {
-		resultName := fmt.Sprintf("%s%04d", dateString, counter)
-		resultPath := fmt.Sprintf("%s/result_%s", baseDir, resultName)
-
-		if _, err := os.Stat(resultPath); os.IsNotExist(err) {
-			return resultName
+		trimmed := strings.TrimRight(token, ".,;:!?'\"`，。；：！？、“”‘’》」")
+		for _, pair := range [][2]string{{"(", ")"}, {"[", "]"}, {"{", "}"}, {"（", "）"}, {"【", "】"}} {
+			if strings.HasSuffix(trimmed, pair[1]) && strings.Count(trimmed, pair[0]) < strings.Count(trimmed, pair[1]) {
+				trimmed = strings.TrimSuffix(trimmed, pair[1])
+			}
 		}
-		counter++
+		if trimmed == token {
+			return token
+		}
+		token = trimmed
 	}
 }
 
-func GetUrlStatusCodeAndResponse(url string) (string, string) {
-	if url == "" {
-		log.Warning("URL为空，无法获取状态码")
-		return "N/A", "URL为空"
+func isHostToken(host string) bool {
+	if IsIPAddress(host) || IsIPAddressWithPort(host) {
+		return true
 	}
-
-	tr := &http.Transport{
-		TLSClientConfig: &tls.Config{InsecureSkipVerify: true},
+	host = asciiHost(host)
+	if !IsDomainName(host) && !IsDomainNameWithPort(host) {
+		return false
 	}
 
-	client := &http.Client{
-		Timeout:   10 * time.Second,
-		Transport: tr,
+	name := host
+	if idx := strings.LastIndex(name, ":"); idx > 0 {
+		name = name[:idx]
 	}
+	tld := strings.ToLower(name[strings.LastIndex(name, ".")+1:])
+	return !fileExtensions[tld]
+}
 
-	resp, err := client.Get(url)
+// asciiHost 将国际化域名转为 punycode 以便按 ASCII 规则校验，无法转换时原样返回
+func asciiHost(host string) string {
+	if !strings.ContainsFunc(host, func(r rune) bool { return r > unicode.MaxASCII }) {
+		return host
+	}
+	name, port := host, ""
+	if idx := strings.LastIndex(host, ":"); idx > 0 {
+		name, port = host[:idx], host[idx:]
+	}
+	ascii, err := idna.Lookup.ToASCII(name)
 	if err != nil {
-		errStr := err.Error()
-		var statusCode, responseContent string
-
-		if strings.Contains(errStr, "timeout") || strings.Contains(errStr, "deadline exceeded") {
-			statusCode = "TIMEOUT"
-			responseContent = fmt.Sprintf("请求超时: %v", err)
-		} else if strings.Contains(errStr, "connection refused") {
-			statusCode = "CONNECTION_REFUSED"
-			responseContent = fmt.Sprintf("连接被拒绝: %v", err)
-		} else if strings.Contains(errStr, "no such host") {
-			statusCode = "DNS_ERROR"
-			responseContent = fmt.Sprintf("DNS解析失败: %v", err)
-		} else if strings.Contains(errStr, "certificate") || strings.Contains(errStr, "tls") {
-			statusCode = "SSL_ERROR"
-			responseContent = fmt.Sprintf("SSL证书错误: %v", err)
-		} else {
-			statusCode = "ERROR"
-			responseContent = fmt.Sprintf("请求失败: %v", err)
-		}
-
-		log.WarningWithContext(fmt.Sprintf("%v", err), url)
-
-		time.Sleep(1 * time.Second)
-		resp, retryErr := client.Get(url)
-		if retryErr != nil {
-			log.ErrorWithContext(fmt.Sprintf("%v", retryErr), url)
-			return statusCode, responseContent
-		}
-		defer resp.Body.Close()
-	} else {
-		defer resp.Body.Close()
+		return host
 	}
+	return ascii + port
+}
 
-	statusCode := strconv.Itoa(resp.StatusCode)
-	responseBuilder := strings.Builder{}
-
-	responseBuilder.WriteString(fmt.Sprintf("%s %d %s\n", resp.Proto, resp.StatusCode, resp.Status))
-
-	for name, values := range resp.Header {
-		for _, value := range values {
-			responseBuilder.WriteString(fmt.Sprintf("%s: %s\n", name, value))
+// ipv6Host 识别 [IPv6]、[IPv6]:端口 与不带端口的裸 IPv6 地址，返回带方括号的主机
+func ipv6Host(token string) (string, bool) {
+	host, port := token, ""
+	if strings.HasPrefix(token, "[") {
+		end := strings.Index(token, "]")
+		if end < 0 {
+			return "", false
 		}
-	}
-
-	body, err := io.ReadAll(io.LimitReader(resp.Body, 1024))
-	if err == nil && len(body) > 0 {
-		responseBuilder.WriteString("\n--- Response Body (Preview) ---\n")
-		responseBuilder.WriteString(string(body))
-		if len(body) == 1024 {
-			responseBuilder.WriteString("\n... (truncated)")
+		host, port = token[1:end], token[end+1:]
+		if port != "" && (!strings.HasPrefix(port, ":") || !isPort(port[1:])) {
+			return "", false
 		}
 	}
-
-	log.Debug(fmt.Sprintf("URL %s 状态码: %s", url, statusCode))
-	return statusCode, responseBuilder.String()
+	ip := net.ParseIP(host)
+	if ip == nil || ip.To4() != nil || ip.IsUnspecified() {
+		return "", false
+	}
+	return "[" + host + "]" + port, true
 }
 
-func GetUrlStatusCode(url string) string {
-	statusCode, _ := GetUrlStatusCodeAndResponse(url)
-	return statusCode
+func isPort(s string) bool {
+	port, err := strconv.Atoi(s)
+	return err == nil && port > 0 && port <= 65535
 }
 
-func SmartScreenshot(URL string, resultName string) []string {
+// extractURL 从任意文本行中提取所有 URL、host:port 以及裸域名/IP（不带协议，由协议探测决定），返回提取结果与被忽略的候选项
+func extractURL(line string) ([]string, []string) {
+	var urls, rejected []string
 
-	result := ChromeScreenshot(URL, resultName)
-	if len(result) > 0 {
-		return result
+	for _, match := range urlPattern.FindAllString(line, -1) {
+		candidate := trimToken(match)
+		if _, err := url.Parse(candidate); err != nil || !strings.Contains(candidate, "://") || strings.HasSuffix(candidate, "://") {
+			rejected = append(rejected, match)
+			continue
+		}
+		urls = append(urls, candidate)
 	}
 
-	if strings.HasPrefix(URL, "https://") {
-		httpURL := strings.Replace(URL, "https://", "http://", 1)
-		log.Info(fmt.Sprintf("访问 %s 失败，正在尝试 HTTP 请求", httpURL))
-		return ChromeScreenshot(httpURL, resultName)
+	rest := urlPattern.ReplaceAllString(line, " ")
+	for _, match := range ipv6Pattern.FindAllString(rest, -1) {
+		candidate := strings.TrimRight(match, ".,;:!?)")
+		host, path := candidate, ""
+		if idx := strings.Index(candidate, "/"); idx > 0 {
+			host, path = candidate[:idx], candidate[idx:]
+		}
+		if ipv6, ok := ipv6Host(host); ok {
+			urls = append(urls, ipv6+path)
+		} else {
+			rejected = append(rejected, match)
+		}
 	}
+	rest = ipv6Pattern.ReplaceAllString(rest, " ")
+	for _, token := range tokenSeparators.Split(rest, -1) {
+		token = trimToken(token)
+		if token == "" || !strings.ContainsAny(token, ".:") {
+			continue
+		}
 
-	return []string{}
-}
-
-func ChromeScreenshot(URL string, resultName string) []string {
-	if URL == "" {
-		log.Error("URL不能为空")
-		return []string{}
-	}
+		host, path := token, ""
+		if idx := strings.Index(token, "/"); idx > 0 {
+			host, path = token[:idx], token[idx:]
+		}
 
-	if resultName == "" {
-		log.Error("结果名称不能为空")
-		return []string{}
+		if ipv6, ok := ipv6Host(host); ok {
+			urls = append(urls, ipv6+path)
+			continue
+		}
+		if !isHostToken(host) {
+			rejected = append(rejected, token)
+			continue
+		}
+		urls = append(urls, host+path)
 	}
 
-	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
-	defer cancel()
+	return urls, rejected
+}
 
-	opts := append(chromedp.DefaultExecAllocatorOptions[:],
-		chromedp.Flag("ignore-certificate-errors", true),
-		chromedp.Flag("ignore-ssl-errors", true),
-		chromedp.Flag("ignore-certificate-errors-spki-list", true),
-		chromedp.Flag("ignore-certificate-errors-skip-list", true),
-		chromedp.Flag("allow-running-insecure-content", true),
-		chromedp.Flag("disable-ssl-verification", true),
-		chromedp.Flag("disable-web-security", true),
-		chromedp.Flag("no-sandbox", true),
-		chromedp.Flag("disable-dev-shm-usage", true),
-		chromedp.Flag("disable-features", "VizDisplayCompositor"),
-		chromedp.Flag("disable-ipc-flooding-protection", true),
-		chromedp.Flag("disable-backgrounding-occluded-windows", true),
-		chromedp.Flag("disable-renderer-backgrounding", true),
-		chromedp.Flag("disable-extensions", true),
-		chromedp.Flag("disable-plugins", true),
-		chromedp.Flag("disable-default-apps", true),
-	)
+func visitURL(url string) chromedp.Tasks {
+	return chromedp.Tasks{
+		chromedp.Navigate(url),
+	}
+}
 
-	allocCtx, allocCancel := chromedp.NewExecAllocator(ctx, opts...)
-	defer allocCancel()
+func GetUrlStatusCodeAndResponse(ctx context.Context, url string, opts *Options) (string, string) {
+	result := NewResult(url, url)
+	FetchResponse(ctx, url, opts, &result)
+	return result.Status(), result.Response()
+}
 
-	browserCtx, browserCancel := chromedp.NewContext(allocCtx)
-	defer browserCancel()
+// FetchResponse 请求目标并将状态码、最终地址、响应头与响应体预览写入 result，失败时记录错误类型
+func FetchResponse(ctx context.Context, url string, opts *Options, result *Result) {
+	if url == "" {
+		opts.logger().Warning("URL为空，无法获取状态码")
+		result.Fail(ClassError, errors.New("URL为空"))
+		return
+	}
 
-	var pageTitle string
-	var screenshot []byte
+	start := time.Now()
+	defer func() {
+		result.Timings.HTTPMs = elapsedMs(start)
+	}()
 
-	executeScreenshot := func() error {
-		return chromedp.Run(browserCtx,
+	scope := opts.scope()
+	client := &http.Client{
+		Timeout:   opts.httpTimeout(),
+		Transport: opts.transport(),
+		CheckRedirect: func(req *http.Request, via []*http.Request) error {
+			if len(via) >= 10 {
+				return errTooManyRedirects
+			}
+			if ok, reason := scope.Check(req.URL.String()); !ok {
+				return fmt.Errorf("%w: %s (%s)", ErrOutOfScope, req.URL, reason)
+			}
+			return nil
+		},
+	}
 
-			chromedp.Emulate(device.Reset),
+	var resp *http.Response
+	var class string
+	var failure error
+	err := opts.retryPolicy().Do(ctx, func(attempt int) (string, error) {
+		release, err := opts.limiter().Acquire(ctx, url)
+		if err != nil {
+			return "", err
+		}
+		req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+		if err != nil {
+			release()
+			return "", err
+		}
+		opts.applyHeaders(req)
+		resp, err = client.Do(req)
+		release()
+		if err == nil {
+			return "", nil
+		}
 
-			chromedp.EmulateViewport(1920, 1080),
+		class = ClassifyError(err)
+		switch class {
+		case ClassOutOfScope:
+			opts.logger().Warning(fmt.Sprintf("%s 的重定向目标超出范围，已停止跟随: %v", url, err))
+			failure = fmt.Errorf("重定向目标超出范围: %w", err)
+			return class, err
+		case ClassTimeout:
+			failure = fmt.Errorf("请求超时: %w", err)
+		case ClassConnRefused:
+			failure = fmt.Errorf("连接被拒绝: %w", err)
+		case ClassConnReset:
+			failure = fmt.Errorf("连接被重置: %w", err)
+		case ClassDNS:
+			failure = fmt.Errorf("DNS解析失败: %w", err)
+		case ClassSSL:
+			failure = fmt.Errorf("SSL证书错误: %w", err)
+		case ClassTooManyRedirects:
+			failure = fmt.Errorf("重定向次数过多: %w", err)
+		default:
+			failure = fmt.Errorf("请求失败: %w", err)
+		}
+		warnURL(opts.logger(), fmt.Sprintf("%v", err), url)
+		return class, err
+	})
+	if err != nil {
+		errorURL(opts.logger(), fmt.Sprintf("%v", err), url)
+		if failure == nil {
+			class, failure = ClassifyError(err), err
+		}
+		result.Fail(class, failure)
+		return
+	}
+	defer resp.Body.Close()
 
-			visitURL(URL),
+	result.StatusCode = resp.StatusCode
+	result.Proto = resp.Proto
+	result.Headers = resp.Header
+	if final := resp.Request.URL.String(); final != url {
+		result.FinalURL = final
+	}
 
-			chromedp.Sleep(3*time.Second),
+	result.Server = resp.Header.Get("Server")
+	result.ContentLength = resp.ContentLength
+	for req := resp.Request; req.Response != nil; req = req.Response.Request {
+		hop := Redirect{URL: req.Response.Request.URL.String(), StatusCode: req.Response.StatusCode}
+		result.RedirectChain = append([]Redirect{hop}, result.RedirectChain...)
+	}
 
-			chromedp.ActionFunc(func(ctx context.Context) error {
+	// 未获取到标题时（如仅 HTTP 模式）读取更多内容用于解析标题
+	readLimit := int64(bodyPreviewLimit)
+	if result.Title == "" {
+		readLimit = titleReadLimit
+	}
+	body, err := io.ReadAll(io.LimitReader(resp.Body, readLimit+1))
+	if len(body) > 0 {
+		complete := err == nil && len(body) <= int(readLimit)
+		if len(body) > int(readLimit) {
+			body = body[:readLimit]
+		}
+		if result.ContentLength < 0 && complete {
+			result.ContentLength = int64(len(body))
+		}
 
-				chromedp.WaitVisible(`body`, chromedp.ByQuery).Do(ctx)
-				return nil
-			}),
+		text := decodeBody(body, resp.Header.Get("Content-Type"))
+		if result.Title == "" {
+			result.Title = extractTitle(text)
+		}
+		result.BodyPreview = truncateUTF8(text, bodyPreviewLimit)
+		result.BodyTruncated = !complete || len(result.BodyPreview) < len(text)
+	}
 
-			chromedp.Evaluate(`document.title || 'No Title'`, &pageTitle),
+	opts.logger().Debug(fmt.Sprintf("URL %s 状态码: %d", url, resp.StatusCode))
+}
 
-			chromedp.CaptureScreenshot(&screenshot),
-		)
-	}
+func GetUrlStatusCode(ctx context.Context, url string, opts *Options) string {
+	statusCode, _ := GetUrlStatusCodeAndResponse(ctx, url, opts)
+	return statusCode
+}
 
-	err := executeScreenshot()
-	if err != nil {
+// SmartScreenshot 先通过协议探测确定目标地址，再逐个截图，返回每个实际访问地址的结果
+func SmartScreenshot(ctx context.Context, URL string, resultName string, opts *Options) []Result {
+	var results []Result
 
-		errStr := err.Error()
-		if strings.Contains(errStr, "certificate") || strings.Contains(errStr, "ssl") {
-			log.WarningWithContext("SSL Certificate Error", URL)
-		} else if strings.Contains(errStr, "deadline exceeded") || strings.Contains(errStr, "timeout") {
-			log.WarningWithContext("Connect Timeout", URL)
-		} else {
-			log.WarningWithContext(fmt.Sprintf("%v", err), URL)
+	capturer := opts.capturer()
+	for _, target := range resolveTargets(ctx, capturer, URL, opts) {
+		if !opts.scope().InScope(target) {
+			opts.logger().Debug(fmt.Sprintf("%s 超出范围，跳过截图", target))
+			continue
 		}
-
-		time.Sleep(2 * time.Second)
-		err = executeScreenshot()
-		if err != nil {
-			log.ErrorWithContext(fmt.Sprintf("%v", err), URL)
-			return []string{}
+		if ctx.Err() != nil {
+			break
 		}
-		log.Info(fmt.Sprintf("访问 %s 重试成功", URL))
+		result := capturer.Capture(ctx, target, resultName, opts)
+		result.Input = URL
+		results = append(results, result)
 	}
 
-	urlName := extractDomainAndIP(URL)
-	if urlName == "" {
-		urlName = "unknown"
-	}
+	return results
+}
 
-	photoName := fmt.Sprintf("data/%s-%s.png", urlName, resultName)
-	resultPath := fmt.Sprintf("./result/%s/%s", resultName, photoName)
+// ChromeScreenshot 使用本地 Chrome 截图，等同于 ChromeCapturer
+func ChromeScreenshot(ctx context.Context, URL string, resultName string, opts *Options) Result {
+	return ChromeCapturer{}.Capture(ctx, URL, resultName, opts)
+}
 
-	if err := os.WriteFile(resultPath, screenshot, 0644); err != nil {
-		log.Error(fmt.Sprintf("保存截图文件失败 %s: %v", resultPath, err))
-		return []string{}
+func enableScopeInterception(scope *Scope) chromedp.Action {
+	if scope == nil {
+		return chromedp.ActionFunc(func(ctx context.Context) error { return nil })
 	}
+	return fetch.Enable().WithPatterns([]*fetch.RequestPattern{
+		{URLPattern: "*", ResourceType: network.ResourceTypeDocument},
+	})
+}
 
-	log.Debug(fmt.Sprintf("截图保存成功: %s", resultPath))
-
-	statusCode, responseContent := GetUrlStatusCodeAndResponse(URL)
+// listenScope 拦截浏览器的文档请求（包括重定向后的请求），阻止访问范围外的目标
+func listenScope(browserCtx context.Context, scope *Scope, URL string, logger Logger) {
+	chromedp.ListenTarget(browserCtx, func(ev interface{}) {
+		e, ok := ev.(*fetch.EventRequestPaused)
+		if !ok {
+			return
+		}
 
-	infoArray := []string{URL, pageTitle, statusCode, photoName, responseContent}
-	log.Debug(fmt.Sprintf("URL %s 处理完成，标题: %s，状态码: %s", URL, pageTitle, statusCode))
+		go func() {
+			c := chromedp.FromContext(browserCtx)
+			if c == nil || c.Target == nil {
+				return
+			}
+			execCtx := cdp.WithExecutor(browserCtx, c.Target)
+
+			if ok, reason := scope.Check(e.Request.URL); !ok {
+				logger.Warning(fmt.Sprintf("%s 的重定向目标 %s 超出范围，已阻止访问: %s", URL, e.Request.URL, reason))
+				if err := fetch.FailRequest(e.RequestID, network.ErrorReasonBlockedByClient).Do(execCtx); err != nil {
+					logger.Debug(fmt.Sprintf("阻止访问 %s 失败: %v", e.Request.URL, err))
+				}
+				return
+			}
 
-	return infoArray
+			if err := fetch.ContinueRequest(e.RequestID).Do(execCtx); err != nil {
+				logger.Debug(fmt.Sprintf("继续请求 %s 失败: %v", e.Request.URL, err))
+			}
+		}()
+	})
 }
@@ -0,0 +1,70 @@
+package scripts
+
+import (
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+)
+
+func TestExtractURL(t *testing.T) {
+	tests := []struct {
+		line     string
+		want     []string
+		rejected []string
+	}{
+		{line: "https://example.com/login?next=/", want: []string{"https://example.com/login?next=/"}},
+		{line: "访问 http://a.example:8080/admin。", want: []string{"http://a.example:8080/admin"}},
+		{line: "见（https://a.example/path）", want: []string{"https://a.example/path"}},
+		{line: "(see https://a.example/wiki/Go_(language))", want: []string{"https://a.example/wiki/Go_(language)"}},
+		{line: "example.com, test.example.org:8443;", want: []string{"example.com", "test.example.org:8443"}},
+		{line: "10.0.0.1 10.0.0.2:8080/status.", want: []string{"10.0.0.1", "10.0.0.2:8080/status"}},
+		{line: "域名: 例子.中国 与 ドメイン.テスト:8443", want: []string{"例子.中国", "ドメイン.テスト:8443"}},
+		{line: "http://[2001:db8::1]:8443/ [2001:db8::2]:9000, [::1]", want: []string{"http://[2001:db8::1]:8443/", "[2001:db8::2]:9000", "[::1]"}},
+		{line: "2001:db8::3", want: []string{"[2001:db8::3]"}},
+		{line: "\"https://quoted.example\"", want: []string{"https://quoted.example"}},
+		{line: "readme.txt v1.2 12:30", rejected: []string{"readme.txt", "v1.2", "12:30"}},
+		{line: "[::]", rejected: []string{"[::]"}},
+	}
+	for _, tt := range tests {
+		got, rejected := extractURL(tt.line)
+		if !slices.Equal(got, tt.want) {
+			t.Errorf("extractURL(%q) = %q，应为 %q", tt.line, got, tt.want)
+		}
+		if tt.rejected != nil && !slices.Equal(rejected, tt.rejected) {
+			t.Errorf("extractURL(%q) 忽略 %q，应为 %q", tt.line, rejected, tt.rejected)
+		}
+	}
+}
+
+func TestScanTextUrl(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "urls.txt")
+	content := strings.Join([]string{
+		"# 注释行会被跳过 https://comment.example",
+		"",
+		"https://a.example",
+		"资产: b.example:8443 与 10.0.0.1",
+		"  # 缩进的注释 c.example",
+		"例子.中国",
+	}, "\n")
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	var urls []string
+	err := ScanTextUrl(path, func(url string) error {
+		urls = append(urls, url)
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := []string{"https://a.example", "b.example:8443", "10.0.0.1", "例子.中国"}; !slices.Equal(urls, want) {
+		t.Errorf("提取结果为 %q，应为 %q", urls, want)
+	}
+
+	if err := ScanTextUrl(filepath.Join(t.TempDir(), "missing.txt"), func(string) error { return nil }); err == nil {
+		t.Error("文件不存在时应报错")
+	}
+}
@@ -0,0 +1,115 @@
+package scripts
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"net/url"
+	"strings"
+	"time"
+)
+
+const defaultAliveTimeout = 3 * time.Second
+
+type AliveResult struct {
+	Target  string
+	Alive   bool
+	Status  string
+	Err     error
+	Elapsed time.Duration
+}
+
+// aliveAddresses 返回目标需要检测的主机与端口，未指定协议且未指定端口时同时检测 443 与 80
+func aliveAddresses(target string, scope *Scope) (string, []string, error) {
+	var hosts []string
+	var host string
+
+	for _, candidate := range SchemeCandidates(target) {
+		if !scope.InScope(candidate) {
+			continue
+		}
+
+		u, err := url.Parse(candidate)
+		if err != nil || u.Hostname() == "" {
+			return "", nil, fmt.Errorf("地址无法解析: %s", target)
+		}
+
+		host = u.Hostname()
+		port := u.Port()
+		if port == "" {
+			port = defaultPorts[strings.ToLower(u.Scheme)]
+		}
+
+		address := net.JoinHostPort(host, port)
+		duplicate := false
+		for _, existing := range hosts {
+			if existing == address {
+				duplicate = true
+				break
+			}
+		}
+		if !duplicate {
+			hosts = append(hosts, address)
+		}
+	}
+
+	if len(hosts) == 0 {
+		return "", nil, ErrOutOfScope
+	}
+	return host, hosts, nil
+}
+
+// CheckAlive 在截图前通过 DNS 解析与 TCP 连接快速判断目标是否存活，不启动浏览器
+func CheckAlive(ctx context.Context, target string, opts *Options) (result AliveResult) {
+	result = AliveResult{Target: target}
+	timeout := opts.aliveTimeout()
+	start := time.Now()
+	defer func() {
+		result.Elapsed = time.Since(start)
+	}()
+
+	host, addresses, err := aliveAddresses(target, opts.scope())
+	if err != nil {
+		result.Status = ClassifyError(err)
+		result.Err = err
+		return result
+	}
+
+	if net.ParseIP(host) == nil {
+		lookupCtx, cancel := context.WithTimeout(ctx, timeout)
+		_, err := net.DefaultResolver.LookupHost(lookupCtx, host)
+		cancel()
+		if err != nil {
+			result.Status = ClassifyError(err)
+			result.Err = err
+			return result
+		}
+	}
+
+	dialer := &net.Dialer{Timeout: timeout}
+	for _, address := range addresses {
+		conn, err := dialAlive(ctx, dialer, address, opts)
+		if err == nil {
+			conn.Close()
+			result.Alive = true
+			result.Status = ""
+			result.Err = nil
+			return result
+		}
+
+		result.Status = ClassifyError(err)
+		result.Err = err
+	}
+
+	return result
+}
+
+// dialAlive 在请求限制下建立 TCP 连接，与截图和协议探测共用同一主机（IP）的并发数与请求间隔
+func dialAlive(ctx context.Context, dialer *net.Dialer, address string, opts *Options) (net.Conn, error) {
+	release, err := opts.limiter().Acquire(ctx, address)
+	if err != nil {
+		return nil, err
+	}
+	defer release()
+	return dialer.DialContext(ctx, "tcp", address)
+}
@@ -0,0 +1,71 @@
+package scripts
+
+import (
+	log "Sowhp/concert/logger"
+	"fmt"
+)
+
+// Logger 为截图过程中的日志输出，通过 Options.Logger 指定；嵌入使用时可接入自己的日志系统，不依赖进程全局的日志级别
+type Logger interface {
+	Debug(msg string)
+	Info(msg string)
+	Warning(msg string)
+	Error(msg string)
+}
+
+// urlLogger 为可以附带目标地址输出警告与错误的 Logger
+type urlLogger interface {
+	WarningWithURL(msg, url string)
+	ErrorWithURL(msg, url string)
+}
+
+// ConsoleLogger 以命令行格式输出日志，级别由 Sowhp/concert/logger 的 LogLevel 控制
+type ConsoleLogger struct{}
+
+func (ConsoleLogger) Debug(msg string) {
+	log.DebugSkip(1, msg)
+}
+
+func (ConsoleLogger) Info(msg string) {
+	log.Info(msg)
+}
+
+func (ConsoleLogger) Warning(msg string) {
+	log.Warning(msg)
+}
+
+func (ConsoleLogger) Error(msg string) {
+	log.Error(msg)
+}
+
+func (ConsoleLogger) WarningWithURL(msg, url string) {
+	log.WarningWithContext(msg, url)
+}
+
+func (ConsoleLogger) ErrorWithURL(msg, url string) {
+	log.ErrorWithContext(msg, url)
+}
+
+// NopLogger 丢弃全部日志
+type NopLogger struct{}
+
+func (NopLogger) Debug(string)   {}
+func (NopLogger) Info(string)    {}
+func (NopLogger) Warning(string) {}
+func (NopLogger) Error(string)   {}
+
+func warnURL(l Logger, msg, url string) {
+	if u, ok := l.(urlLogger); ok {
+		u.WarningWithURL(msg, url)
+		return
+	}
+	l.Warning(fmt.Sprintf("%s: %s", url, msg))
+}
+
+func errorURL(l Logger, msg, url string) {
+	if u, ok := l.(urlLogger); ok {
+		u.ErrorWithURL(msg, url)
+		return
+	}
+	l.Error(fmt.Sprintf("%s: %s", url, msg))
+}
@@ -0,0 +1,190 @@
+package scripts
+
+import (
+	"errors"
+	"fmt"
+	"net/url"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"time"
+)
+
+// 默认命名模板：运行目录与之前的 result_<日期><序号> 保持一致，截图名附带地址哈希以避免不同地址重名
+const (
+	DefaultRunNameTemplate        = "result_{date}{index}"
+	DefaultScreenshotNameTemplate = "{host}_{port}-{hash}"
+)
+
+const (
+	// maxNameAttempts 为生成唯一名称时的最大尝试次数
+	maxNameAttempts = 100000
+	// maxNameLength 为生成名称（不含序号后缀与扩展名）的最大字节数，低于常见文件系统 255 字节的文件名上限
+	maxNameLength = 200
+)
+
+var (
+	placeholderPattern = regexp.MustCompile(`\{([a-z]+)\}`)
+	unsafeNameChars    = regexp.MustCompile(`[^a-zA-Z0-9._-]+`)
+)
+
+// 各模板支持的占位符
+var (
+	runNamePlaceholders        = []string{"date", "time", "index"}
+	screenshotNamePlaceholders = []string{"host", "port", "scheme", "path", "hash", "date", "time", "index", "run"}
+)
+
+// ValidateRunNameTemplate 检查运行目录命名模板，支持 {date} {time} {index}
+func ValidateRunNameTemplate(template string) error {
+	return validateTemplate(template, runNamePlaceholders)
+}
+
+// ValidateScreenshotNameTemplate 检查截图命名模板，支持 {host} {port} {scheme} {path} {hash} {date} {time} {index} {run}
+func ValidateScreenshotNameTemplate(template string) error {
+	return validateTemplate(template, screenshotNamePlaceholders)
+}
+
+func validateTemplate(template string, placeholders []string) error {
+	if strings.TrimSpace(template) == "" {
+		return errors.New("命名模板不能为空")
+	}
+	for _, match := range placeholderPattern.FindAllStringSubmatch(template, -1) {
+		known := false
+		for _, placeholder := range placeholders {
+			if match[1] == placeholder {
+				known = true
+				break
+			}
+		}
+		if !known {
+			return fmt.Errorf("命名模板 %s 包含未知占位符 %s，支持: {%s}", template, match[0], strings.Join(placeholders, "} {"))
+		}
+	}
+	return nil
+}
+
+// renderName 替换模板中的占位符，并将结果中不能用于文件名的字符替换为 _；
+// 超过 maxNameLength 时截断并在末尾附加哈希（优先使用 {hash} 字段），保证不同的长名称截断后仍不相同
+func renderName(template string, fields map[string]string) string {
+	name := placeholderPattern.ReplaceAllStringFunc(template, func(placeholder string) string {
+		return fields[placeholder[1:len(placeholder)-1]]
+	})
+	name = unsafeNameChars.ReplaceAllString(name, "_")
+	name = strings.ReplaceAll(name, "..", "__")
+	if strings.Trim(name, "._-") == "" {
+		return "unnamed"
+	}
+	if len(name) > maxNameLength {
+		hash := fields["hash"]
+		if hash == "" {
+			hash = fmt.Sprintf("%016x", hashKey(name))[:10]
+		}
+		name = strings.TrimRight(name[:maxNameLength-len(hash)-1], "._-") + "-" + hash
+	}
+	return name
+}
+
+// CreateRunDir 在 outputDir 下按模板创建新的运行目录及其 data 子目录，返回运行目录路径。
+// 名称已存在时递增 {index}；模板不含 {index} 时在名称后追加 -2、-3 等后缀
+func CreateRunDir(outputDir, template string) (string, error) {
+	if template == "" {
+		template = DefaultRunNameTemplate
+	}
+	if err := ValidateRunNameTemplate(template); err != nil {
+		return "", err
+	}
+	if err := os.MkdirAll(outputDir, 0755); err != nil {
+		return "", fmt.Errorf("创建输出目录失败: %w", err)
+	}
+
+	now := time.Now()
+	hasIndex := strings.Contains(template, "{index}")
+	for index := 1; index <= maxNameAttempts; index++ {
+		name := renderName(template, map[string]string{
+			"date":  now.Format("20060102"),
+			"time":  now.Format("150405"),
+			"index": fmt.Sprintf("%04d", index),
+		})
+		if !hasIndex && index > 1 {
+			name = fmt.Sprintf("%s-%d", name, index)
+		}
+
+		runDir := filepath.Join(outputDir, name)
+		err := os.Mkdir(runDir, 0755)
+		if errors.Is(err, os.ErrExist) {
+			continue
+		}
+		if err != nil {
+			return "", fmt.Errorf("创建运行目录失败: %w", err)
+		}
+		if err := os.Mkdir(filepath.Join(runDir, "data"), 0755); err != nil {
+			return "", fmt.Errorf("创建截图目录失败: %w", err)
+		}
+		return runDir, nil
+	}
+	return "", errors.New("无法生成唯一的运行目录名称")
+}
+
+// screenshotName 按模板生成截图文件名（不含扩展名），resultName 对应 {run}
+func (o *Options) screenshotName(rawURL, resultName string) string {
+	template := DefaultScreenshotNameTemplate
+	if o != nil && o.ScreenshotName != "" {
+		template = o.ScreenshotName
+	}
+
+	fields := map[string]string{
+		"hash": fmt.Sprintf("%016x", hashKey(rawURL))[:10],
+		"run":  resultName,
+	}
+	if parsed, err := url.Parse(rawURL); err == nil {
+		fields["scheme"] = parsed.Scheme
+		fields["host"] = parsed.Hostname()
+		fields["port"] = parsed.Port()
+		if fields["port"] == "" {
+			fields["port"] = defaultPort(parsed.Scheme)
+		}
+		fields["path"] = strings.Trim(parsed.Path, "/")
+	}
+
+	if strings.Contains(template, "{date}") || strings.Contains(template, "{time}") {
+		now := time.Now()
+		fields["date"] = now.Format("20060102")
+		fields["time"] = now.Format("150405")
+	}
+	if strings.Contains(template, "{index}") && o != nil {
+		fields["index"] = fmt.Sprintf("%05d", o.screenshotIndex.Add(1))
+	}
+	return renderName(template, fields)
+}
+
+func defaultPort(scheme string) string {
+	if scheme == "http" {
+		return "80"
+	}
+	return "443"
+}
+
+// createUniqueFile 在 dir 下以 name+ext 为名独占创建文件，已存在时追加 -2、-3 等后缀，返回文件与实际文件名；
+// dir 不存在时先创建（嵌入使用时 Options.OutputDir 可能是尚未创建的目录）
+func createUniqueFile(dir, name, ext string) (*os.File, string, error) {
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return nil, "", err
+	}
+	for attempt := 1; attempt <= maxNameAttempts; attempt++ {
+		fileName := name + ext
+		if attempt > 1 {
+			fileName = fmt.Sprintf("%s-%d%s", name, attempt, ext)
+		}
+
+		file, err := os.OpenFile(filepath.Join(dir, fileName), os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0644)
+		if errors.Is(err, os.ErrExist) {
+			continue
+		}
+		if err != nil {
+			return nil, "", err
+		}
+		return file, fileName, nil
+	}
+	return nil, "", fmt.Errorf("无法生成唯一的文件名: %s%s", name, ext)
+}
@@ -0,0 +1,250 @@
+package scripts
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// notifyTimeout 为单次发送通知的超时时间
+const notifyTimeout = 10 * time.Second
+
+// 通知渠道类型
+const (
+	NotifyWebhook  = "webhook"
+	NotifyDingTalk = "dingtalk"
+	NotifyFeishu   = "feishu"
+	NotifyWeCom    = "wecom"
+)
+
+// NotifierTypes 为支持的通知渠道类型
+var NotifierTypes = []string{NotifyWebhook, NotifyDingTalk, NotifyFeishu, NotifyWeCom}
+
+// 各机器人单条文本消息的长度上限（字节），超出部分截断
+const (
+	dingTalkTextLimit = 18000
+	feishuTextLimit   = 18000
+	weComTextLimit    = 2000
+)
+
+// Notification 为发送给通知渠道的消息：Title 与 Text 为面向人的摘要，Data 为结构化内容
+type Notification struct {
+	Event string      `json:"event"`
+	Title string      `json:"title"`
+	Text  string      `json:"text"`
+	Data  interface{} `json:"data,omitempty"`
+	Time  time.Time   `json:"time"`
+}
+
+// Notifier 为通知渠道
+type Notifier interface {
+	Notify(ctx context.Context, n Notification) error
+}
+
+// NewNotifier 按类型创建通知渠道，secret 为机器人的加签密钥或通用 Webhook 的签名密钥，可为空
+func NewNotifier(kind, address, secret string) (Notifier, error) {
+	parsed, err := url.Parse(address)
+	if err != nil || (parsed.Scheme != "http" && parsed.Scheme != "https") || parsed.Host == "" {
+		return nil, fmt.Errorf("无效的通知地址: %s", address)
+	}
+
+	switch kind {
+	case NotifyWebhook:
+		return WebhookNotifier{URL: address, Secret: secret}, nil
+	case NotifyDingTalk:
+		return DingTalkNotifier{URL: address, Secret: secret}, nil
+	case NotifyFeishu:
+		return FeishuNotifier{URL: address, Secret: secret}, nil
+	case NotifyWeCom:
+		if secret != "" {
+			return nil, fmt.Errorf("企业微信机器人不支持加签密钥")
+		}
+		return WeComNotifier{URL: address}, nil
+	}
+	return nil, fmt.Errorf("不支持的通知类型: %s（可选: %s）", kind, strings.Join(NotifierTypes, ", "))
+}
+
+// WebhookNotifier 将通知以 JSON 格式 POST 到指定地址；设置 Secret 时在请求头中附带签名：
+// X-Sowhp-Timestamp 为 Unix 秒，X-Sowhp-Signature 为 "sha256=" + hex(HMAC-SHA256(Secret, 时间戳 + "." + 请求体))
+type WebhookNotifier struct {
+	URL     string
+	Headers map[string]string
+	Secret  string
+}
+
+func (w WebhookNotifier) Notify(ctx context.Context, n Notification) error {
+	data, err := json.Marshal(n)
+	if err != nil {
+		return fmt.Errorf("序列化通知失败: %w", err)
+	}
+
+	headers := make(map[string]string, len(w.Headers)+2)
+	for name, value := range w.Headers {
+		headers[name] = value
+	}
+	if w.Secret != "" {
+		timestamp := strconv.FormatInt(time.Now().Unix(), 10)
+		mac := hmac.New(sha256.New, []byte(w.Secret))
+		mac.Write([]byte(timestamp + "."))
+		mac.Write(data)
+		headers["X-Sowhp-Timestamp"] = timestamp
+		headers["X-Sowhp-Signature"] = "sha256=" + hex.EncodeToString(mac.Sum(nil))
+	}
+	_, err = post(ctx, w.URL, headers, data)
+	return err
+}
+
+// DingTalkNotifier 为钉钉自定义机器人，设置 Secret 时按“加签”方式在地址中附带 timestamp 与 sign
+type DingTalkNotifier struct {
+	URL    string
+	Secret string
+}
+
+func (d DingTalkNotifier) Notify(ctx context.Context, n Notification) error {
+	address := d.URL
+	if d.Secret != "" {
+		timestamp := strconv.FormatInt(time.Now().UnixMilli(), 10)
+		sign := hmacBase64([]byte(d.Secret), timestamp+"\n"+d.Secret)
+		var err error
+		if address, err = withQuery(address, map[string]string{"timestamp": timestamp, "sign": sign}); err != nil {
+			return err
+		}
+	}
+
+	body := map[string]interface{}{
+		"msgtype": "text",
+		"text":    map[string]string{"content": notificationText(n, dingTalkTextLimit)},
+	}
+	return postBot(ctx, address, body)
+}
+
+// FeishuNotifier 为飞书自定义机器人，设置 Secret 时按“签名校验”方式在请求体中附带 timestamp 与 sign
+type FeishuNotifier struct {
+	URL    string
+	Secret string
+}
+
+func (f FeishuNotifier) Notify(ctx context.Context, n Notification) error {
+	body := map[string]interface{}{
+		"msg_type": "text",
+		"content":  map[string]string{"text": notificationText(n, feishuTextLimit)},
+	}
+	if f.Secret != "" {
+		timestamp := strconv.FormatInt(time.Now().Unix(), 10)
+		body["timestamp"] = timestamp
+		body["sign"] = hmacBase64([]byte(timestamp+"\n"+f.Secret), "")
+	}
+	return postBot(ctx, f.URL, body)
+}
+
+// WeComNotifier 为企业微信群机器人
+type WeComNotifier struct {
+	URL string
+}
+
+func (w WeComNotifier) Notify(ctx context.Context, n Notification) error {
+	body := map[string]interface{}{
+		"msgtype": "text",
+		"text":    map[string]string{"content": notificationText(n, weComTextLimit)},
+	}
+	return postBot(ctx, w.URL, body)
+}
+
+// notificationText 将标题与正文合并为机器人的文本消息，并截断到 limit 字节
+func notificationText(n Notification, limit int) string {
+	text := n.Title
+	if n.Text != "" {
+		text += "\n" + n.Text
+	}
+	if len(text) > limit {
+		text = truncateUTF8(text, limit-len("\n……")) + "\n……"
+	}
+	return text
+}
+
+func hmacBase64(key []byte, message string) string {
+	mac := hmac.New(sha256.New, key)
+	mac.Write([]byte(message))
+	return base64.StdEncoding.EncodeToString(mac.Sum(nil))
+}
+
+func withQuery(address string, params map[string]string) (string, error) {
+	parsed, err := url.Parse(address)
+	if err != nil {
+		return "", fmt.Errorf("无效的通知地址: %w", err)
+	}
+	query := parsed.Query()
+	for name, value := range params {
+		query.Set(name, value)
+	}
+	parsed.RawQuery = query.Encode()
+	return parsed.String(), nil
+}
+
+// botResponse 为钉钉、企业微信（errcode/errmsg）与飞书（code/msg）机器人的响应
+type botResponse struct {
+	ErrCode *int   `json:"errcode"`
+	ErrMsg  string `json:"errmsg"`
+	Code    *int   `json:"code"`
+	Msg     string `json:"msg"`
+}
+
+// postBot 发送机器人消息，机器人以 HTTP 200 返回的错误码同样视为失败（如签名错误、触发频率限制）
+func postBot(ctx context.Context, address string, body interface{}) error {
+	data, err := json.Marshal(body)
+	if err != nil {
+		return fmt.Errorf("序列化通知失败: %w", err)
+	}
+	resp, err := post(ctx, address, nil, data)
+	if err != nil {
+		return err
+	}
+
+	var result botResponse
+	if json.Unmarshal(resp, &result) != nil {
+		return nil
+	}
+	switch {
+	case result.ErrCode != nil && *result.ErrCode != 0:
+		return fmt.Errorf("发送通知失败: %d %s", *result.ErrCode, result.ErrMsg)
+	case result.Code != nil && *result.Code != 0:
+		return fmt.Errorf("发送通知失败: %d %s", *result.Code, result.Msg)
+	}
+	return nil
+}
+
+// post 发送 JSON 请求并返回响应体，响应状态码不是 2xx 时返回错误
+func post(ctx context.Context, address string, headers map[string]string, data []byte) ([]byte, error) {
+	ctx, cancel := context.WithTimeout(ctx, notifyTimeout)
+	defer cancel()
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, address, bytes.NewReader(data))
+	if err != nil {
+		return nil, fmt.Errorf("创建通知请求失败: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json; charset=utf-8")
+	for name, value := range headers {
+		req.Header.Set(name, value)
+	}
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("发送通知失败: %w", err)
+	}
+	defer resp.Body.Close()
+	body, _ := io.ReadAll(io.LimitReader(resp.Body, 64*1024))
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return nil, fmt.Errorf("发送通知失败: %s %s", resp.Status, strings.TrimSpace(truncateUTF8(string(body), 512)))
+	}
+	return body, nil
+}
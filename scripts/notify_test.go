@@ -0,0 +1,165 @@
+package scripts
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+// request 为测试服务器收到的请求
+type request struct {
+	query  map[string]string
+	header http.Header
+	body   []byte
+}
+
+// newBotServer 创建记录请求并返回 response 的测试服务器
+func newBotServer(t *testing.T, status int, response string) (*httptest.Server, chan request) {
+	t.Helper()
+	requests := make(chan request, 1)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		query := make(map[string]string)
+		for name := range r.URL.Query() {
+			query[name] = r.URL.Query().Get(name)
+		}
+		requests <- request{query: query, header: r.Header.Clone(), body: body}
+		w.WriteHeader(status)
+		io.WriteString(w, response)
+	}))
+	t.Cleanup(server.Close)
+	return server, requests
+}
+
+func testNotification() Notification {
+	return Notification{Event: "test", Title: "标题", Text: "正文", Time: time.Unix(0, 0)}
+}
+
+func sign(key, message string) []byte {
+	mac := hmac.New(sha256.New, []byte(key))
+	mac.Write([]byte(message))
+	return mac.Sum(nil)
+}
+
+func TestWebhookSignature(t *testing.T) {
+	server, requests := newBotServer(t, http.StatusNoContent, "")
+	notifier, err := NewNotifier(NotifyWebhook, server.URL, "secret")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := notifier.Notify(context.Background(), testNotification()); err != nil {
+		t.Fatalf("发送失败: %v", err)
+	}
+
+	req := <-requests
+	timestamp := req.header.Get("X-Sowhp-Timestamp")
+	if timestamp == "" {
+		t.Fatal("缺少 X-Sowhp-Timestamp")
+	}
+	want := "sha256=" + hex.EncodeToString(sign("secret", timestamp+"."+string(req.body)))
+	if got := req.header.Get("X-Sowhp-Signature"); got != want {
+		t.Errorf("X-Sowhp-Signature 为 %q，应为 %q", got, want)
+	}
+	var n Notification
+	if err := json.Unmarshal(req.body, &n); err != nil || n.Event != "test" || n.Title != "标题" {
+		t.Errorf("请求体错误: %s", req.body)
+	}
+}
+
+func TestDingTalkSignature(t *testing.T) {
+	server, requests := newBotServer(t, http.StatusOK, `{"errcode":0,"errmsg":"ok"}`)
+	notifier, err := NewNotifier(NotifyDingTalk, server.URL+"/robot/send?access_token=token", "secret")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := notifier.Notify(context.Background(), testNotification()); err != nil {
+		t.Fatalf("发送失败: %v", err)
+	}
+
+	req := <-requests
+	if req.query["access_token"] != "token" {
+		t.Errorf("地址中原有的参数丢失: %v", req.query)
+	}
+	timestamp := req.query["timestamp"]
+	want := base64.StdEncoding.EncodeToString(sign("secret", timestamp+"\nsecret"))
+	if timestamp == "" || req.query["sign"] != want {
+		t.Errorf("timestamp 为 %q，sign 为 %q，应为 %q", timestamp, req.query["sign"], want)
+	}
+	var body struct {
+		MsgType string `json:"msgtype"`
+		Text    struct {
+			Content string `json:"content"`
+		} `json:"text"`
+	}
+	if err := json.Unmarshal(req.body, &body); err != nil || body.MsgType != "text" || body.Text.Content != "标题\n正文" {
+		t.Errorf("请求体错误: %s", req.body)
+	}
+}
+
+func TestFeishuSignature(t *testing.T) {
+	server, requests := newBotServer(t, http.StatusOK, `{"code":0,"msg":"success"}`)
+	notifier, err := NewNotifier(NotifyFeishu, server.URL, "secret")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := notifier.Notify(context.Background(), testNotification()); err != nil {
+		t.Fatalf("发送失败: %v", err)
+	}
+
+	req := <-requests
+	var body struct {
+		Timestamp string `json:"timestamp"`
+		Sign      string `json:"sign"`
+		MsgType   string `json:"msg_type"`
+		Content   struct {
+			Text string `json:"text"`
+		} `json:"content"`
+	}
+	if err := json.Unmarshal(req.body, &body); err != nil {
+		t.Fatalf("请求体错误: %s", req.body)
+	}
+	want := base64.StdEncoding.EncodeToString(sign(body.Timestamp+"\nsecret", ""))
+	if body.Timestamp == "" || body.Sign != want {
+		t.Errorf("timestamp 为 %q，sign 为 %q，应为 %q", body.Timestamp, body.Sign, want)
+	}
+	if body.MsgType != "text" || body.Content.Text != "标题\n正文" {
+		t.Errorf("请求体错误: %s", req.body)
+	}
+}
+
+func TestNotifyErrors(t *testing.T) {
+	tests := []struct {
+		name     string
+		kind     string
+		status   int
+		response string
+		want     string
+	}{
+		{name: "钉钉错误码", kind: NotifyDingTalk, status: http.StatusOK, response: `{"errcode":310000,"errmsg":"sign not match"}`, want: "310000"},
+		{name: "企业微信错误码", kind: NotifyWeCom, status: http.StatusOK, response: `{"errcode":45009,"errmsg":"api freq out of limit"}`, want: "45009"},
+		{name: "飞书错误码", kind: NotifyFeishu, status: http.StatusOK, response: `{"code":19021,"msg":"sign match fail"}`, want: "19021"},
+		{name: "状态码", kind: NotifyWebhook, status: http.StatusInternalServerError, response: "boom", want: "500"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server, _ := newBotServer(t, tt.status, tt.response)
+			notifier, err := NewNotifier(tt.kind, server.URL, "")
+			if err != nil {
+				t.Fatal(err)
+			}
+			err = notifier.Notify(context.Background(), testNotification())
+			if err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Errorf("应返回包含 %q 的错误，实际为 %v", tt.want, err)
+			}
+		})
+	}
+}
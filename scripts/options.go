@@ -0,0 +1,181 @@
+package scripts
+
+import (
+	"crypto/tls"
+	"fmt"
+	"net/http"
+	"net/url"
+	"sync/atomic"
+	"time"
+)
+
+const (
+	defaultPageTimeout    = 30 * time.Second
+	defaultHTTPTimeout    = 10 * time.Second
+	defaultViewportWidth  = 1920
+	defaultViewportHeight = 1080
+)
+
+type Options struct {
+	Scope        *Scope
+	CaptureBoth  bool
+	ProbeTimeout time.Duration
+	AliveTimeout time.Duration
+	PageTimeout  time.Duration
+	HTTPTimeout  time.Duration
+	Retry        *RetryPolicy
+	Limiter      *RateLimiter
+	// Headers 为附加到每个请求（包括浏览器访问）的请求头
+	Headers map[string]string
+	// Proxy 为 HTTP 请求与本地 Chrome 使用的代理地址，如 http://127.0.0.1:8080 或 socks5://127.0.0.1:1080
+	Proxy string
+	// UserAgent 覆盖 HTTP 请求与浏览器的 User-Agent，浏览器中在每个标签页内设置
+	UserAgent string
+	// ChromeFlags 为启动本地 Chrome 时附加的命令行参数，值为空表示开关参数
+	ChromeFlags    map[string]string
+	ViewportWidth  int
+	ViewportHeight int
+	// Capturer 为截图引擎，为空时使用本地 Chrome
+	Capturer Capturer
+	// OutputDir 为截图保存目录，截图写入其下的 data 子目录；为空时截图只保存在 Result.Image 中
+	OutputDir string
+	// ScreenshotName 为截图文件命名模板，为空时使用 DefaultScreenshotNameTemplate
+	ScreenshotName string
+	// Logger 为日志输出，为空时使用 ConsoleLogger
+	Logger Logger
+
+	screenshotIndex atomic.Int64
+}
+
+func (o *Options) scope() *Scope {
+	if o == nil {
+		return nil
+	}
+	return o.Scope
+}
+
+func (o *Options) logger() Logger {
+	if o == nil || o.Logger == nil {
+		return ConsoleLogger{}
+	}
+	return o.Logger
+}
+
+func (o *Options) probeTimeout() time.Duration {
+	if o == nil || o.ProbeTimeout <= 0 {
+		return defaultProbeTimeout
+	}
+	return o.ProbeTimeout
+}
+
+func (o *Options) aliveTimeout() time.Duration {
+	if o == nil || o.AliveTimeout <= 0 {
+		return defaultAliveTimeout
+	}
+	return o.AliveTimeout
+}
+
+func (o *Options) pageTimeout() time.Duration {
+	if o == nil || o.PageTimeout <= 0 {
+		return defaultPageTimeout
+	}
+	return o.PageTimeout
+}
+
+func (o *Options) httpTimeout() time.Duration {
+	if o == nil || o.HTTPTimeout <= 0 {
+		return defaultHTTPTimeout
+	}
+	return o.HTTPTimeout
+}
+
+func (o *Options) retryPolicy() *RetryPolicy {
+	if o == nil || o.Retry == nil {
+		return DefaultRetryPolicy()
+	}
+	return o.Retry
+}
+
+func (o *Options) limiter() *RateLimiter {
+	if o == nil {
+		return nil
+	}
+	return o.Limiter
+}
+
+func (o *Options) outputDir() string {
+	if o == nil {
+		return ""
+	}
+	return o.OutputDir
+}
+
+func (o *Options) capturer() Capturer {
+	if o == nil || o.Capturer == nil {
+		return ChromeCapturer{}
+	}
+	return o.Capturer
+}
+
+func (o *Options) viewport() (int64, int64) {
+	width, height := defaultViewportWidth, defaultViewportHeight
+	if o != nil && o.ViewportWidth > 0 && o.ViewportHeight > 0 {
+		width, height = o.ViewportWidth, o.ViewportHeight
+	}
+	return int64(width), int64(height)
+}
+
+// headers 返回需要附加的请求头，User-Agent 单独设置时覆盖 Headers 中的同名项
+func (o *Options) headers() map[string]string {
+	if o == nil || (len(o.Headers) == 0 && o.UserAgent == "") {
+		return nil
+	}
+
+	headers := make(map[string]string, len(o.Headers)+1)
+	for name, value := range o.Headers {
+		headers[http.CanonicalHeaderKey(name)] = value
+	}
+	if o.UserAgent != "" {
+		headers["User-Agent"] = o.UserAgent
+	}
+	return headers
+}
+
+func (o *Options) applyHeaders(req *http.Request) {
+	for name, value := range o.headers() {
+		if name == "Host" {
+			req.Host = value
+			continue
+		}
+		req.Header.Set(name, value)
+	}
+}
+
+// transport 返回 HTTP 请求使用的 Transport，忽略证书错误并按需使用代理；代理地址无效时请求直接失败，不会绕过代理直连
+func (o *Options) transport() *http.Transport {
+	tr := &http.Transport{
+		TLSClientConfig: &tls.Config{InsecureSkipVerify: true},
+	}
+	if o != nil && o.Proxy != "" {
+		proxyURL, err := ParseProxy(o.Proxy)
+		if err != nil {
+			tr.Proxy = func(*http.Request) (*url.URL, error) { return nil, err }
+		} else {
+			tr.Proxy = http.ProxyURL(proxyURL)
+		}
+	}
+	return tr
+}
+
+// ParseProxy 解析代理地址，必须包含协议（http、https、socks5、socks5h）与主机，如 http://127.0.0.1:8080
+func ParseProxy(raw string) (*url.URL, error) {
+	proxyURL, err := url.Parse(raw)
+	if err != nil || proxyURL.Host == "" {
+		return nil, fmt.Errorf("代理地址无效，应包含协议与主机，如 http://127.0.0.1:8080: %s", raw)
+	}
+	switch proxyURL.Scheme {
+	case "http", "https", "socks5", "socks5h":
+		return proxyURL, nil
+	}
+	return nil, fmt.Errorf("不支持的代理协议 %q，可选 http、https、socks5、socks5h", proxyURL.Scheme)
+}
@@ -0,0 +1,171 @@
+package scripts
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+
+	"github.com/chromedp/chromedp"
+)
+
+// BrowserPool 预先启动多个本地 Chrome 并在多次截图之间复用，每次截图在新的标签页中进行，
+// 省去每个地址启动浏览器的开销。代理与 Chrome 参数以创建时的 Options 为准，对池中全部截图相同；
+// User-Agent 与请求头在每个标签页中按 Capture 传入的 Options 设置
+type BrowserPool struct {
+	opts     *Options
+	ctx      context.Context
+	cancel   context.CancelFunc
+	mu       sync.Mutex
+	browsers []*pooledBrowser
+	// slots 中每个元素代表一个可用的标签页名额，值为所属浏览器的序号
+	slots chan int
+}
+
+type pooledBrowser struct {
+	ctx    context.Context
+	cancel context.CancelFunc
+}
+
+// NewBrowserPool 启动 size 个本地 Chrome，每个浏览器最多同时打开 tabs 个标签页；任一浏览器启动失败时关闭已启动的浏览器并返回错误
+func NewBrowserPool(size, tabs int, opts *Options) (*BrowserPool, error) {
+	if size < 1 || tabs < 1 {
+		return nil, errors.New("浏览器数量与标签页数量必须大于 0")
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	pool := &BrowserPool{
+		opts:   opts,
+		ctx:    ctx,
+		cancel: cancel,
+		slots:  make(chan int, size*tabs),
+	}
+	for i := 0; i < size; i++ {
+		browser, err := pool.launch()
+		if err != nil {
+			pool.Close()
+			return nil, fmt.Errorf("启动第 %d 个浏览器失败: %w", i+1, err)
+		}
+		pool.browsers = append(pool.browsers, browser)
+		for j := 0; j < tabs; j++ {
+			pool.slots <- i
+		}
+	}
+	opts.logger().Debug(fmt.Sprintf("浏览器池已启动 %d 个浏览器，每个最多 %d 个标签页", size, tabs))
+	return pool, nil
+}
+
+func (p *BrowserPool) launch() (*pooledBrowser, error) {
+	allocCtx, allocCancel := chromedp.NewExecAllocator(p.ctx, chromeAllocatorOptions(p.opts)...)
+	browserCtx, browserCancel := chromedp.NewContext(allocCtx)
+	if err := launchBrowser(browserCtx, browserCancel, p.opts.pageTimeout()); err != nil {
+		browserCancel()
+		allocCancel()
+		return nil, err
+	}
+	return &pooledBrowser{
+		ctx: browserCtx,
+		cancel: func() {
+			browserCancel()
+			allocCancel()
+		},
+	}, nil
+}
+
+// Size 返回可同时进行的截图数
+func (p *BrowserPool) Size() int {
+	return cap(p.slots)
+}
+
+// Capture 等待空闲标签页后截图；浏览器进程已退出时先重新启动
+func (p *BrowserPool) Capture(ctx context.Context, URL string, resultName string, opts *Options) Result {
+	slot, browser, err := p.acquire(ctx)
+	if err != nil {
+		result := NewResult(URL, URL)
+		class := ClassBrowser
+		if ctx.Err() != nil {
+			class = ClassNotProcessed
+		}
+		result.Fail(class, err)
+		return result
+	}
+	defer p.release(slot)
+
+	return browserCapture(ctx, browser.ctx, URL, resultName, opts)
+}
+
+// Check 在池中的浏览器里渲染测试页面
+func (p *BrowserPool) Check(ctx context.Context, opts *Options) CheckResult {
+	slot, browser, err := p.acquire(ctx)
+	if err != nil {
+		return CheckResult{Name: "浏览器", Detail: err.Error()}
+	}
+	defer p.release(slot)
+
+	result := checkBrowser(browser.ctx, opts)
+	result.Detail = fmt.Sprintf("%s（浏览器池，共 %d 个标签页）", result.Detail, p.Size())
+	return result
+}
+
+// acquire 取出一个标签页名额，返回名额所属的浏览器
+func (p *BrowserPool) acquire(ctx context.Context) (int, *pooledBrowser, error) {
+	var slot int
+	select {
+	case slot = <-p.slots:
+	case <-ctx.Done():
+		return 0, nil, ctx.Err()
+	case <-p.ctx.Done():
+		return 0, nil, errors.New("浏览器池已关闭")
+	}
+
+	p.mu.Lock()
+	browser := p.browsers[slot]
+	closed := p.ctx.Err() != nil
+	p.mu.Unlock()
+	if closed {
+		p.slots <- slot
+		return 0, nil, errors.New("浏览器池已关闭")
+	}
+	if browser.ctx.Err() == nil {
+		return slot, browser, nil
+	}
+
+	// 在锁外重新启动，避免启动缓慢时阻塞其他任务与 Close
+	p.opts.logger().Warning("浏览器进程已退出，正在重新启动")
+	replacement, err := p.launch()
+	if err != nil {
+		p.slots <- slot
+		return 0, nil, fmt.Errorf("重新启动浏览器失败: %w", err)
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.ctx.Err() != nil {
+		replacement.cancel()
+		p.slots <- slot
+		return 0, nil, errors.New("浏览器池已关闭")
+	}
+	current := p.browsers[slot]
+	if current != browser && current.ctx.Err() == nil {
+		// 同一浏览器的其他标签页已经完成重新启动
+		replacement.cancel()
+		return slot, current, nil
+	}
+	current.cancel()
+	p.browsers[slot] = replacement
+	return slot, replacement, nil
+}
+
+func (p *BrowserPool) release(slot int) {
+	p.slots <- slot
+}
+
+// Close 关闭池中的全部浏览器
+func (p *BrowserPool) Close() {
+	p.cancel()
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	for _, browser := range p.browsers {
+		browser.cancel()
+	}
+}
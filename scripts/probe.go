@@ -0,0 +1,163 @@
+package scripts
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+)
+
+const defaultProbeTimeout = 5 * time.Second
+
+type schemeProbe struct {
+	URL    string
+	OK     bool
+	Status int
+	// Location 为重定向目标的绝对地址，Title 为页面标题，用于比较两种协议的内容是否相同
+	Location string
+	Title    string
+	Err      error
+}
+
+// SchemeCandidates 返回目标可能的访问地址，已指定协议的地址原样返回，未指定协议时依次为 https 与 http
+func SchemeCandidates(target string) []string {
+	if HasScheme(target) {
+		return []string{target}
+	}
+	return []string{"https://" + target, "http://" + target}
+}
+
+// DetectScheme 在启动浏览器之前分别尝试 TLS 握手与明文 HTTP 请求，确定未指定协议的目标应使用的协议。
+// 默认优先 https；开启 CaptureBoth 且两种协议均有响应且内容不同时同时返回两个地址
+func DetectScheme(ctx context.Context, target string, opts *Options) []string {
+	candidates := SchemeCandidates(target)
+	if len(candidates) == 1 {
+		return candidates
+	}
+
+	scope := opts.scope()
+	timeout := opts.probeTimeout()
+
+	probes := make([]schemeProbe, len(candidates))
+	var wg sync.WaitGroup
+	for i, candidate := range candidates {
+		if !scope.InScope(candidate) {
+			probes[i] = schemeProbe{URL: candidate}
+			continue
+		}
+		wg.Add(1)
+		go func(i int, candidate string) {
+			defer wg.Done()
+			probes[i] = probeScheme(ctx, candidate, timeout, opts)
+		}(i, candidate)
+	}
+	wg.Wait()
+
+	httpsProbe, httpProbe := probes[0], probes[1]
+	switch {
+	case httpsProbe.OK && httpProbe.OK:
+		if opts != nil && opts.CaptureBoth && !sameContent(httpsProbe, httpProbe) {
+			opts.logger().Debug(fmt.Sprintf("%s 的 HTTP 与 HTTPS 响应内容不同，将同时截图", target))
+			return []string{httpsProbe.URL, httpProbe.URL}
+		}
+		return []string{httpsProbe.URL}
+	case httpsProbe.OK:
+		return []string{httpsProbe.URL}
+	case httpProbe.OK:
+		opts.logger().Debug(fmt.Sprintf("%s 未响应 TLS 握手，使用 HTTP 协议", target))
+		return []string{httpProbe.URL}
+	}
+
+	opts.logger().Debug(fmt.Sprintf("%s 协议探测无响应 (https: %v, http: %v)，默认使用 HTTPS", target, httpsProbe.Err, httpProbe.Err))
+	for _, probe := range probes {
+		if scope.InScope(probe.URL) {
+			return []string{probe.URL}
+		}
+	}
+	return []string{candidates[0]}
+}
+
+func probeScheme(ctx context.Context, target string, timeout time.Duration, opts *Options) schemeProbe {
+	probe := schemeProbe{URL: target}
+
+	release, err := opts.limiter().Acquire(ctx, target)
+	if err != nil {
+		probe.Err = err
+		return probe
+	}
+	defer release()
+
+	transport := opts.transport()
+	transport.TLSHandshakeTimeout = timeout
+	transport.DisableKeepAlives = true
+	client := &http.Client{
+		Timeout:   timeout,
+		Transport: transport,
+		CheckRedirect: func(req *http.Request, via []*http.Request) error {
+			return http.ErrUseLastResponse
+		},
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, target, nil)
+	if err != nil {
+		probe.Err = err
+		return probe
+	}
+	opts.applyHeaders(req)
+
+	resp, err := client.Do(req)
+	if err != nil {
+		probe.Err = err
+		return probe
+	}
+	defer resp.Body.Close()
+
+	body, _ := io.ReadAll(io.LimitReader(resp.Body, 64*1024))
+	if strings.HasPrefix(target, "http://") && isPlainHTTPToTLSPort(resp.StatusCode, body) {
+		probe.Err = fmt.Errorf("端口仅接受 HTTPS 请求")
+		return probe
+	}
+
+	probe.OK = true
+	probe.Status = resp.StatusCode
+	if location, err := resp.Location(); err == nil {
+		probe.Location = location.String()
+	}
+	probe.Title = extractTitle(decodeBody(body, resp.Header.Get("Content-Type")))
+	return probe
+}
+
+// sameContent 判断 HTTP 与 HTTPS 是否为同一内容：HTTP 重定向到同一主机的 HTTPS 地址时视为相同，
+// 否则比较状态码、去掉协议后的重定向目标与页面标题，不比较响应体，避免随机数、CSRF 令牌等造成误判
+func sameContent(httpsProbe, httpProbe schemeProbe) bool {
+	if httpProbe.Location != "" {
+		location, err := url.Parse(httpProbe.Location)
+		target, _ := url.Parse(httpProbe.URL)
+		if err == nil && target != nil && location.Scheme == "https" && strings.EqualFold(location.Hostname(), target.Hostname()) {
+			return true
+		}
+	}
+	return httpsProbe.Status == httpProbe.Status && stripScheme(httpsProbe.Location) == stripScheme(httpProbe.Location) &&
+		httpsProbe.Title == httpProbe.Title
+}
+
+func stripScheme(address string) string {
+	if _, rest, ok := strings.Cut(address, "://"); ok {
+		return rest
+	}
+	return address
+}
+
+// isPlainHTTPToTLSPort 识别服务端对"明文请求发送到 HTTPS 端口"的错误响应，避免误判为 HTTP 服务
+func isPlainHTTPToTLSPort(status int, body []byte) bool {
+	if status != http.StatusBadRequest {
+		return false
+	}
+	lower := strings.ToLower(string(body))
+	return strings.Contains(lower, "https port") || strings.Contains(lower, "https server") || strings.Contains(lower, "https scheme") ||
+		strings.Contains(lower, "speaking plain http") || strings.Contains(lower, "ssl port")
+}
@@ -0,0 +1,226 @@
+package scripts
+
+import (
+	"bufio"
+	"context"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"strings"
+	"sync"
+	"testing"
+)
+
+// chanListener 将分流后的连接交给 httptest.Server
+type chanListener struct {
+	addr  net.Addr
+	conns chan net.Conn
+	done  chan struct{}
+	once  sync.Once
+}
+
+func (l *chanListener) Accept() (net.Conn, error) {
+	select {
+	case conn := <-l.conns:
+		return conn, nil
+	case <-l.done:
+		return nil, net.ErrClosed
+	}
+}
+
+func (l *chanListener) Close() error {
+	l.once.Do(func() { close(l.done) })
+	return nil
+}
+
+func (l *chanListener) Addr() net.Addr {
+	return l.addr
+}
+
+// peekConn 为已预读首字节的连接
+type peekConn struct {
+	net.Conn
+	reader *bufio.Reader
+}
+
+func (c *peekConn) Read(b []byte) (int, error) {
+	return c.reader.Read(b)
+}
+
+// newDualServer 在同一端口上同时提供 HTTPS 与 HTTP 服务：首字节为 TLS 握手时交给 httpsHandler，否则交给 httpHandler。
+// handler 为 nil 时对应协议直接关闭连接。返回不带协议的 host:port
+func newDualServer(t *testing.T, httpsHandler, httpHandler http.Handler) string {
+	t.Helper()
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	secure := &chanListener{addr: ln.Addr(), conns: make(chan net.Conn), done: make(chan struct{})}
+	plain := &chanListener{addr: ln.Addr(), conns: make(chan net.Conn), done: make(chan struct{})}
+
+	go func() {
+		for {
+			conn, err := ln.Accept()
+			if err != nil {
+				return
+			}
+			go func() {
+				reader := bufio.NewReader(conn)
+				first, err := reader.Peek(1)
+				target := plain
+				if err == nil && first[0] == 0x16 {
+					target = secure
+				}
+				if err != nil || (target == secure && httpsHandler == nil) || (target == plain && httpHandler == nil) {
+					conn.Close()
+					return
+				}
+				select {
+				case target.conns <- &peekConn{Conn: conn, reader: reader}:
+				case <-target.done:
+					conn.Close()
+				}
+			}()
+		}
+	}()
+
+	for _, s := range []struct {
+		listener *chanListener
+		handler  http.Handler
+		tls      bool
+	}{{secure, httpsHandler, true}, {plain, httpHandler, false}} {
+		if s.handler == nil {
+			continue
+		}
+		server := httptest.NewUnstartedServer(s.handler)
+		server.Listener = s.listener
+		if s.tls {
+			server.StartTLS()
+		} else {
+			server.Start()
+		}
+		t.Cleanup(server.Close)
+	}
+	t.Cleanup(func() { ln.Close() })
+	return ln.Addr().String()
+}
+
+func titleHandler(title string) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/html; charset=utf-8")
+		io.WriteString(w, "<html><head><title>"+title+"</title></head><body>"+r.URL.Path+"</body></html>")
+	})
+}
+
+func TestDetectScheme(t *testing.T) {
+	redirect := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Redirect(w, r, "https://"+r.Host+r.URL.Path, http.StatusMovedPermanently)
+	})
+
+	tests := []struct {
+		name        string
+		https, http http.Handler
+		both        bool
+		want        []string
+	}{
+		{name: "仅 HTTP", http: titleHandler("HTTP"), want: []string{"http"}},
+		{name: "仅 HTTPS", https: titleHandler("HTTPS"), want: []string{"https"}},
+		{name: "均无响应时默认 HTTPS", want: []string{"https"}},
+		{name: "两种协议均有响应时优先 HTTPS", https: titleHandler("A"), http: titleHandler("B"), want: []string{"https"}},
+		{name: "-both 内容相同", https: titleHandler("Same"), http: titleHandler("Same"), both: true, want: []string{"https"}},
+		{name: "-both HTTP 重定向到 HTTPS", https: titleHandler("Same"), http: redirect, both: true, want: []string{"https"}},
+		{name: "-both 内容不同", https: titleHandler("A"), http: titleHandler("B"), both: true, want: []string{"https", "http"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			target := newDualServer(t, tt.https, tt.http)
+			opts := &Options{CaptureBoth: tt.both, Logger: NopLogger{}}
+
+			var want []string
+			for _, scheme := range tt.want {
+				want = append(want, scheme+"://"+target)
+			}
+			if got := DetectScheme(context.Background(), target, opts); !slices.Equal(got, want) {
+				t.Errorf("DetectScheme(%q) = %v，应为 %v", target, got, want)
+			}
+		})
+	}
+}
+
+func TestDetectSchemeExplicit(t *testing.T) {
+	for _, target := range []string{"http://example.invalid", "https://example.invalid:8443/path"} {
+		if got := DetectScheme(context.Background(), target, &Options{Logger: NopLogger{}}); !slices.Equal(got, []string{target}) {
+			t.Errorf("已指定协议的地址应原样返回，实际为 %v", got)
+		}
+	}
+}
+
+func TestDetectSchemeScope(t *testing.T) {
+	target := newDualServer(t, titleHandler("HTTPS"), titleHandler("HTTP"))
+	opts := &Options{Scope: loadTestScope(t, "deny regex:^https://", "allow 127.0.0.1"), Logger: NopLogger{}}
+
+	if got := DetectScheme(context.Background(), target, opts); !slices.Equal(got, []string{"http://" + target}) {
+		t.Errorf("HTTPS 超出范围时应使用 HTTP，实际为 %v", got)
+	}
+}
+
+func TestSameContent(t *testing.T) {
+	tests := []struct {
+		name        string
+		https, http schemeProbe
+		want        bool
+	}{
+		{
+			name:  "状态码与标题相同",
+			https: schemeProbe{URL: "https://a.example", Status: 200, Title: "首页"},
+			http:  schemeProbe{URL: "http://a.example", Status: 200, Title: "首页"},
+			want:  true,
+		},
+		{
+			name:  "标题不同",
+			https: schemeProbe{URL: "https://a.example", Status: 200, Title: "首页"},
+			http:  schemeProbe{URL: "http://a.example", Status: 200, Title: "默认页"},
+		},
+		{
+			name:  "状态码不同",
+			https: schemeProbe{URL: "https://a.example", Status: 200, Title: "首页"},
+			http:  schemeProbe{URL: "http://a.example", Status: 403, Title: "首页"},
+		},
+		{
+			name:  "HTTP 重定向到同一主机的 HTTPS",
+			https: schemeProbe{URL: "https://a.example", Status: 200, Title: "首页"},
+			http:  schemeProbe{URL: "http://a.example", Status: 301, Location: "https://A.example/login"},
+			want:  true,
+		},
+		{
+			name:  "HTTP 重定向到其他主机",
+			https: schemeProbe{URL: "https://a.example", Status: 200, Title: "首页"},
+			http:  schemeProbe{URL: "http://a.example", Status: 302, Location: "https://sso.example/login"},
+		},
+		{
+			name:  "重定向目标只有协议不同",
+			https: schemeProbe{URL: "https://a.example", Status: 302, Location: "https://sso.example/login"},
+			http:  schemeProbe{URL: "http://a.example", Status: 302, Location: "http://sso.example/login"},
+			want:  true,
+		},
+	}
+	for _, tt := range tests {
+		if got := sameContent(tt.https, tt.http); got != tt.want {
+			t.Errorf("%s: sameContent = %v，应为 %v", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestIsPlainHTTPToTLSPort(t *testing.T) {
+	if !isPlainHTTPToTLSPort(http.StatusBadRequest, []byte("Client sent an HTTP request to an HTTPS server.")) {
+		t.Error("应识别 Go 的明文请求错误")
+	}
+	if !isPlainHTTPToTLSPort(http.StatusBadRequest, []byte(strings.ToUpper("The plain HTTP request was sent to HTTPS port"))) {
+		t.Error("应识别 nginx 的明文请求错误")
+	}
+	if isPlainHTTPToTLSPort(http.StatusBadRequest, []byte("bad request")) || isPlainHTTPToTLSPort(http.StatusOK, []byte("https port")) {
+		t.Error("普通响应不应识别为明文请求错误")
+	}
+}
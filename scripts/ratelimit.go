@@ -0,0 +1,329 @@
+package scripts
+
+import (
+	"container/list"
+	"context"
+	"net"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	hostResolveTimeout = 3 * time.Second
+	// hostResolveWorkers 为交错排序时并发解析主机名的数量
+	hostResolveWorkers = 32
+	// hostResolveCacheSize 为主机名解析结果的缓存条目上限
+	hostResolveCacheSize = 4096
+	// minHostSweep 为清理空闲主机状态前 hosts 的最小条目数
+	minHostSweep = 1024
+)
+
+type hostLimit struct {
+	sem chan struct{}
+	// refs 为等待中与进行中的请求数，由 RateLimiter.mu 保护
+	refs int
+	mu   sync.Mutex
+	last time.Time
+}
+
+// RateLimiter 同时限制全局每秒请求数，以及同一主机（按解析后的 IP 归并端口与虚拟主机）的并发数与请求间隔。
+// 只保留有请求进行中或仍需等待间隔的主机状态，解析结果按 LRU 缓存，处理大量主机时内存占用有上限
+type RateLimiter struct {
+	interval    time.Duration
+	hostThreads int
+	hostDelay   time.Duration
+
+	mu       sync.Mutex
+	next     time.Time
+	hosts    map[string]*hostLimit
+	sweepAt  int
+	resolved *resolveCache
+}
+
+func NewRateLimiter(rate float64, hostThreads int, hostDelay time.Duration) *RateLimiter {
+	if rate <= 0 && hostThreads <= 0 && hostDelay <= 0 {
+		return nil
+	}
+
+	limiter := &RateLimiter{
+		hostThreads: hostThreads,
+		hostDelay:   hostDelay,
+		hosts:       make(map[string]*hostLimit),
+		sweepAt:     minHostSweep,
+		resolved:    newResolveCache(hostResolveCacheSize),
+	}
+	if rate > 0 {
+		limiter.interval = time.Duration(float64(time.Second) / rate)
+	}
+	return limiter
+}
+
+// Acquire 等待直到允许向目标发起请求，调用方在请求结束后必须调用返回的 release
+func (l *RateLimiter) Acquire(ctx context.Context, rawURL string) (func(), error) {
+	if l == nil {
+		return func() {}, nil
+	}
+
+	host := l.hostKey(ctx, rawURL)
+	limit := l.host(host)
+
+	if limit.sem != nil {
+		select {
+		case limit.sem <- struct{}{}:
+		case <-ctx.Done():
+			l.done(host, limit)
+			return nil, ctx.Err()
+		}
+	}
+	release := func() {
+		if limit.sem != nil {
+			<-limit.sem
+		}
+		l.done(host, limit)
+	}
+
+	if err := l.waitHost(ctx, limit); err != nil {
+		release()
+		return nil, err
+	}
+
+	if err := l.waitGlobal(ctx); err != nil {
+		release()
+		return nil, err
+	}
+
+	return release, nil
+}
+
+func (l *RateLimiter) waitHost(ctx context.Context, limit *hostLimit) error {
+	if l.hostDelay <= 0 {
+		return nil
+	}
+
+	limit.mu.Lock()
+	now := time.Now()
+	start := limit.last.Add(l.hostDelay)
+	if start.Before(now) {
+		start = now
+	}
+	limit.last = start
+	limit.mu.Unlock()
+
+	return sleepUntil(ctx, start)
+}
+
+func (l *RateLimiter) waitGlobal(ctx context.Context) error {
+	if l.interval <= 0 {
+		return nil
+	}
+
+	l.mu.Lock()
+	now := time.Now()
+	start := l.next
+	if start.Before(now) {
+		start = now
+	}
+	l.next = start.Add(l.interval)
+	l.mu.Unlock()
+
+	return sleepUntil(ctx, start)
+}
+
+func sleepUntil(ctx context.Context, t time.Time) error {
+	wait := time.Until(t)
+	if wait <= 0 {
+		return nil
+	}
+
+	timer := time.NewTimer(wait)
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+func (l *RateLimiter) host(key string) *hostLimit {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	limit, ok := l.hosts[key]
+	if !ok {
+		if len(l.hosts) >= l.sweepAt {
+			l.sweep()
+		}
+		limit = &hostLimit{}
+		if l.hostThreads > 0 {
+			limit.sem = make(chan struct{}, l.hostThreads)
+		}
+		l.hosts[key] = limit
+	}
+	limit.refs++
+	return limit
+}
+
+// done 在请求结束或放弃等待后调用，主机空闲且无需等待间隔时立即移除其状态
+func (l *RateLimiter) done(key string, limit *hostLimit) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	limit.refs--
+	if l.hosts[key] == limit && l.idle(limit, time.Now()) {
+		delete(l.hosts, key)
+	}
+}
+
+// sweep 移除空闲主机的状态，调用方持有 l.mu。设置了 -host-delay 时主机在间隔结束前保留，由此处批量清理
+func (l *RateLimiter) sweep() {
+	now := time.Now()
+	for key, limit := range l.hosts {
+		if l.idle(limit, now) {
+			delete(l.hosts, key)
+		}
+	}
+	l.sweepAt = max(minHostSweep, 2*len(l.hosts))
+}
+
+// idle 判断主机没有等待中或进行中的请求，且距上次请求已超过请求间隔，调用方持有 l.mu
+func (l *RateLimiter) idle(limit *hostLimit, now time.Time) bool {
+	if limit.refs > 0 {
+		return false
+	}
+	limit.mu.Lock()
+	defer limit.mu.Unlock()
+	return !now.Before(limit.last.Add(l.hostDelay))
+}
+
+// hostKey 将同一 IP 上的不同端口与虚拟主机归为同一主机，解析失败时使用主机名
+func (l *RateLimiter) hostKey(ctx context.Context, rawURL string) string {
+	host := HostOf(rawURL)
+	if host == "" || net.ParseIP(host) != nil {
+		return host
+	}
+
+	l.mu.Lock()
+	key, ok := l.resolved.get(host)
+	l.mu.Unlock()
+	if ok {
+		return key
+	}
+
+	key = host
+	lookupCtx, cancel := context.WithTimeout(ctx, hostResolveTimeout)
+	if addrs, err := net.DefaultResolver.LookupHost(lookupCtx, host); err == nil && len(addrs) > 0 {
+		key = addrs[0]
+	}
+	cancel()
+
+	l.mu.Lock()
+	l.resolved.add(host, key)
+	l.mu.Unlock()
+	return key
+}
+
+// resolveCache 为主机名到主机键的 LRU 缓存，超过容量时淘汰最久未使用的条目
+type resolveCache struct {
+	size  int
+	order *list.List
+	items map[string]*list.Element
+}
+
+type resolveEntry struct {
+	host string
+	key  string
+}
+
+func newResolveCache(size int) *resolveCache {
+	return &resolveCache{size: size, order: list.New(), items: make(map[string]*list.Element)}
+}
+
+func (c *resolveCache) get(host string) (string, bool) {
+	elem, ok := c.items[host]
+	if !ok {
+		return "", false
+	}
+	c.order.MoveToFront(elem)
+	return elem.Value.(*resolveEntry).key, true
+}
+
+func (c *resolveCache) add(host, key string) {
+	if elem, ok := c.items[host]; ok {
+		elem.Value.(*resolveEntry).key = key
+		c.order.MoveToFront(elem)
+		return
+	}
+	c.items[host] = c.order.PushFront(&resolveEntry{host: host, key: key})
+	if c.order.Len() > c.size {
+		oldest := c.order.Back()
+		c.order.Remove(oldest)
+		delete(c.items, oldest.Value.(*resolveEntry).host)
+	}
+}
+
+// HostOf 返回地址的主机名，支持未指定协议的地址
+func HostOf(target string) string {
+	if !HasScheme(target) {
+		target = "//" + target
+	}
+	u, err := url.Parse(target)
+	if err != nil {
+		return ""
+	}
+	return strings.ToLower(u.Hostname())
+}
+
+// InterleaveByHost 按主机轮询重新排列地址，避免同一主机的多个地址被连续处理。
+// 指定 limiter 时按其解析后的 IP 分组，同一 IP 上的不同端口与虚拟主机视为同一主机；未指定时按主机名分组
+func InterleaveByHost(ctx context.Context, urls []string, limiter *RateLimiter) []string {
+	keys := limiter.hostKeys(ctx, urls)
+
+	var order []string
+	groups := make(map[string][]string)
+	for i, u := range urls {
+		host := keys[i]
+		if _, ok := groups[host]; !ok {
+			order = append(order, host)
+		}
+		groups[host] = append(groups[host], u)
+	}
+
+	interleaved := make([]string, 0, len(urls))
+	for len(interleaved) < len(urls) {
+		for _, host := range order {
+			if queue := groups[host]; len(queue) > 0 {
+				interleaved = append(interleaved, queue[0])
+				groups[host] = queue[1:]
+			}
+		}
+	}
+	return interleaved
+}
+
+// hostKeys 并发解析每个地址的主机键，limiter 为 nil 时直接使用主机名
+func (l *RateLimiter) hostKeys(ctx context.Context, urls []string) []string {
+	keys := make([]string, len(urls))
+	if l == nil {
+		for i, u := range urls {
+			keys[i] = HostOf(u)
+		}
+		return keys
+	}
+
+	sem := make(chan struct{}, hostResolveWorkers)
+	var wg sync.WaitGroup
+	for i, u := range urls {
+		wg.Add(1)
+		sem <- struct{}{}
+		go func(i int, u string) {
+			defer wg.Done()
+			defer func() { <-sem }()
+			keys[i] = l.hostKey(ctx, u)
+		}(i, u)
+	}
+	wg.Wait()
+	return keys
+}
@@ -0,0 +1,155 @@
+package scripts
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestRateLimiterHostThreads(t *testing.T) {
+	limiter := NewRateLimiter(0, 2, 0)
+
+	var current, peak atomic.Int32
+	var wg sync.WaitGroup
+	for i := 0; i < 6; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			// 同一 IP 的不同端口视为同一主机
+			release, err := limiter.Acquire(context.Background(), fmt.Sprintf("http://127.0.0.1:%d/", 8000+i))
+			if err != nil {
+				t.Error(err)
+				return
+			}
+			n := current.Add(1)
+			for {
+				p := peak.Load()
+				if n <= p || peak.CompareAndSwap(p, n) {
+					break
+				}
+			}
+			time.Sleep(20 * time.Millisecond)
+			current.Add(-1)
+			release()
+		}(i)
+	}
+	wg.Wait()
+
+	if got := peak.Load(); got != 2 {
+		t.Errorf("同一主机的最大并发为 %d，应为 2", got)
+	}
+	if n := len(limiter.hosts); n != 0 {
+		t.Errorf("请求全部结束后仍保留 %d 个主机状态", n)
+	}
+}
+
+func TestRateLimiterHostThreadsCanceled(t *testing.T) {
+	limiter := NewRateLimiter(0, 1, 0)
+	release, err := limiter.Acquire(context.Background(), "http://10.0.0.1")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	if _, err := limiter.Acquire(ctx, "http://10.0.0.1:8080"); err == nil {
+		t.Fatal("主机并发已满时应等待到 ctx 结束并返回错误")
+	}
+	other, err := limiter.Acquire(context.Background(), "http://10.0.0.2")
+	if err != nil {
+		t.Fatalf("其他主机不应受影响: %v", err)
+	}
+	other()
+	release()
+	if n := len(limiter.hosts); n != 0 {
+		t.Errorf("放弃等待的请求未释放主机状态，仍保留 %d 个", n)
+	}
+}
+
+func TestRateLimiterDelay(t *testing.T) {
+	const delay = 30 * time.Millisecond
+	limiter := NewRateLimiter(0, 0, delay)
+
+	start := time.Now()
+	for i := 0; i < 3; i++ {
+		release, err := limiter.Acquire(context.Background(), "http://10.0.0.1")
+		if err != nil {
+			t.Fatal(err)
+		}
+		release()
+	}
+	if elapsed := time.Since(start); elapsed < 2*delay {
+		t.Errorf("同一主机 3 次请求耗时 %s，应至少为 %s", elapsed, 2*delay)
+	}
+
+	start = time.Now()
+	release, err := limiter.Acquire(context.Background(), "http://10.0.0.2")
+	if err != nil {
+		t.Fatal(err)
+	}
+	release()
+	if elapsed := time.Since(start); elapsed >= delay {
+		t.Errorf("其他主机的首次请求等待了 %s", elapsed)
+	}
+
+	// 间隔未结束的主机保留状态，间隔结束后清理
+	if len(limiter.hosts) != 2 {
+		t.Fatalf("间隔未结束时应保留 2 个主机状态，实际为 %d", len(limiter.hosts))
+	}
+	time.Sleep(2 * delay)
+	limiter.mu.Lock()
+	limiter.sweep()
+	remaining := len(limiter.hosts)
+	limiter.mu.Unlock()
+	if remaining != 0 {
+		t.Errorf("间隔结束后仍保留 %d 个主机状态", remaining)
+	}
+}
+
+func TestRateLimiterGlobalRate(t *testing.T) {
+	limiter := NewRateLimiter(50, 0, 0)
+
+	start := time.Now()
+	for i := 0; i < 4; i++ {
+		release, err := limiter.Acquire(context.Background(), fmt.Sprintf("http://10.0.0.%d", i+1))
+		if err != nil {
+			t.Fatal(err)
+		}
+		release()
+	}
+	if elapsed := time.Since(start); elapsed < 60*time.Millisecond {
+		t.Errorf("每秒 50 次时 4 次请求耗时 %s，应至少为 60ms", elapsed)
+	}
+}
+
+func TestResolveCache(t *testing.T) {
+	cache := newResolveCache(2)
+	cache.add("a.example", "10.0.0.1")
+	cache.add("b.example", "10.0.0.2")
+	cache.get("a.example")
+	cache.add("c.example", "10.0.0.3")
+
+	if _, ok := cache.get("b.example"); ok {
+		t.Error("最久未使用的条目应被淘汰")
+	}
+	for host, want := range map[string]string{"a.example": "10.0.0.1", "c.example": "10.0.0.3"} {
+		if key, ok := cache.get(host); !ok || key != want {
+			t.Errorf("%s 的缓存为 %q，应为 %q", host, key, want)
+		}
+	}
+	if len(cache.items) != 2 || cache.order.Len() != 2 {
+		t.Errorf("缓存条目数为 %d，超过上限", len(cache.items))
+	}
+}
+
+func TestInterleaveByHost(t *testing.T) {
+	urls := []string{"https://a.example/1", "https://a.example/2", "https://a.example/3", "https://b.example/1", "c.example"}
+	got := InterleaveByHost(context.Background(), urls, nil)
+	want := []string{"https://a.example/1", "https://b.example/1", "c.example", "https://a.example/2", "https://a.example/3"}
+	if fmt.Sprint(got) != fmt.Sprint(want) {
+		t.Errorf("交错结果为 %v，应为 %v", got, want)
+	}
+}
@@ -0,0 +1,155 @@
+package scripts
+
+import (
+	"fmt"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+// ResultSchemaVersion 为结果文件的结构版本，字段含义发生不兼容变化时递增
+const ResultSchemaVersion = 1
+
+const bodyPreviewLimit = 1024
+
+// Result 为单个地址的处理结果，同时用于结果存储、报告生成与对外输出
+type Result struct {
+	SchemaVersion int         `json:"schema_version"`
+	Input         string      `json:"input"`
+	URL           string      `json:"url"`
+	FinalURL      string      `json:"final_url,omitempty"`
+	Title         string      `json:"title,omitempty"`
+	StatusCode    int         `json:"status_code,omitempty"`
+	ErrorClass    string      `json:"error_class,omitempty"`
+	Error         string      `json:"error,omitempty"`
+	Screenshot    string      `json:"screenshot,omitempty"`
+	Image         []byte      `json:"-"`
+	Proto         string      `json:"proto,omitempty"`
+	Server        string      `json:"server,omitempty"`
+	ContentLength int64       `json:"content_length,omitempty"`
+	RedirectChain []Redirect  `json:"redirect_chain,omitempty"`
+	Headers       http.Header `json:"headers,omitempty"`
+	BodyPreview   string      `json:"body_preview,omitempty"`
+	BodyTruncated bool        `json:"body_truncated,omitempty"`
+	Timings       Timings     `json:"timings"`
+	CapturedAt    time.Time   `json:"captured_at"`
+}
+
+// Redirect 为重定向链中的一跳：请求的地址及其返回的状态码
+type Redirect struct {
+	URL        string `json:"url"`
+	StatusCode int    `json:"status_code"`
+}
+
+// Timings 记录各阶段耗时，单位为毫秒，未执行的阶段为 0
+type Timings struct {
+	AliveMs int64 `json:"alive_ms,omitempty"`
+	PageMs  int64 `json:"page_ms,omitempty"`
+	HTTPMs  int64 `json:"http_ms,omitempty"`
+	TotalMs int64 `json:"total_ms"`
+}
+
+func NewResult(input, url string) Result {
+	return Result{
+		SchemaVersion: ResultSchemaVersion,
+		Input:         input,
+		URL:           url,
+		CapturedAt:    time.Now(),
+	}
+}
+
+func NotProcessedResult(input, url string) Result {
+	result := NewResult(input, url)
+	result.ErrorClass = ClassNotProcessed
+	result.Error = "任务中断，未处理"
+	return result
+}
+
+// Fail 记录失败原因，class 为空时归为 ERROR
+func (r *Result) Fail(class string, err error) {
+	if class == "" {
+		class = ClassError
+	}
+	r.ErrorClass = class
+	if err != nil {
+		r.Error = err.Error()
+	}
+}
+
+// Success 判断是否处理成功：已截图，或仅 HTTP 模式下获取到响应
+func (r *Result) Success() bool {
+	return r.Screenshot != "" || len(r.Image) > 0 || (r.StatusCode > 0 && r.ErrorClass == "")
+}
+
+func (r *Result) NotProcessed() bool {
+	return r.ErrorClass == ClassNotProcessed
+}
+
+// Status 返回用于展示的状态：有响应时为 HTTP 状态码，否则为错误类型
+func (r *Result) Status() string {
+	if r.StatusCode > 0 {
+		return strconv.Itoa(r.StatusCode)
+	}
+	return r.ErrorClass
+}
+
+// Response 将响应头与响应体预览还原为文本形式，用于报告展示
+func (r *Result) Response() string {
+	var builder strings.Builder
+
+	if r.StatusCode > 0 {
+		for _, hop := range r.RedirectChain {
+			builder.WriteString(fmt.Sprintf("%d %s ->\n", hop.StatusCode, hop.URL))
+		}
+		if len(r.RedirectChain) > 0 {
+			builder.WriteString("\n")
+		}
+
+		builder.WriteString(fmt.Sprintf("%s %d %s\n", r.Proto, r.StatusCode, http.StatusText(r.StatusCode)))
+
+		names := make([]string, 0, len(r.Headers))
+		for name := range r.Headers {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		for _, name := range names {
+			for _, value := range r.Headers[name] {
+				builder.WriteString(fmt.Sprintf("%s: %s\n", name, value))
+			}
+		}
+
+		if r.BodyPreview != "" {
+			builder.WriteString("\n--- Response Body (Preview) ---\n")
+			builder.WriteString(r.BodyPreview)
+			if r.BodyTruncated {
+				builder.WriteString("\n... (truncated)")
+			}
+		}
+	}
+
+	if r.Error != "" {
+		if builder.Len() > 0 {
+			builder.WriteString("\n\n--- Error ---\n")
+		}
+		builder.WriteString(r.Error)
+	}
+	return builder.String()
+}
+
+func elapsedMs(start time.Time) int64 {
+	return time.Since(start).Milliseconds()
+}
+
+// truncateUTF8 将字符串截断到不超过 limit 字节，且不截断多字节字符
+func truncateUTF8(s string, limit int) string {
+	if len(s) <= limit {
+		return s
+	}
+	for limit > 0 && !utf8.RuneStart(s[limit]) {
+		limit--
+	}
+	return s[:limit]
+}
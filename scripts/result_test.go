@@ -0,0 +1,106 @@
+package scripts
+
+import (
+	"encoding/json"
+	"errors"
+	"os"
+	"strings"
+	"testing"
+)
+
+func TestResultJSON(t *testing.T) {
+	result := testResult("a.example", "https://a.example", 200, "首页")
+	result.Image = []byte("png")
+	data, err := json.Marshal(result)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var fields map[string]interface{}
+	if err := json.Unmarshal(data, &fields); err != nil {
+		t.Fatal(err)
+	}
+	if fields["schema_version"] != float64(ResultSchemaVersion) {
+		t.Errorf("schema_version 为 %v，应为 %d", fields["schema_version"], ResultSchemaVersion)
+	}
+	for _, name := range []string{"input", "url", "title", "status_code", "timings", "captured_at"} {
+		if _, ok := fields[name]; !ok {
+			t.Errorf("缺少字段 %s: %s", name, data)
+		}
+	}
+	for _, name := range []string{"Image", "image", "error_class", "error", "screenshot", "headers", "redirect_chain"} {
+		if _, ok := fields[name]; ok {
+			t.Errorf("不应输出字段 %s: %s", name, data)
+		}
+	}
+}
+
+func TestResultStatus(t *testing.T) {
+	failed := NewResult("a.example", "https://a.example")
+	failed.Fail("", errors.New("未知错误"))
+	refused := NewResult("a.example", "https://a.example")
+	refused.Fail(ClassConnRefused, errors.New("connection refused"))
+	forbidden := testResult("a.example", "https://a.example", 403, "")
+	captured := testResult("a.example", "https://a.example", 0, "")
+	captured.Screenshot = "data/a.png"
+	shotFailed := testResult("a.example", "https://a.example", 200, "")
+	shotFailed.Fail(ClassTimeout, errors.New("截图超时"))
+
+	tests := []struct {
+		name    string
+		result  Result
+		status  string
+		success bool
+	}{
+		{name: "未分类错误", result: failed, status: ClassError},
+		{name: "连接被拒绝", result: refused, status: ClassConnRefused},
+		{name: "仅 HTTP 响应", result: forbidden, status: "403", success: true},
+		{name: "仅截图", result: captured, status: "", success: true},
+		{name: "截图失败但有响应", result: shotFailed, status: "200"},
+		{name: "未处理", result: NotProcessedResult("a.example", "a.example"), status: ClassNotProcessed},
+	}
+	for _, tt := range tests {
+		if got := tt.result.Status(); got != tt.status {
+			t.Errorf("%s: Status() = %q，应为 %q", tt.name, got, tt.status)
+		}
+		if got := tt.result.Success(); got != tt.success {
+			t.Errorf("%s: Success() = %v，应为 %v", tt.name, got, tt.success)
+		}
+	}
+}
+
+func TestJSONReport(t *testing.T) {
+	failed := NewResult("b.example", "https://b.example")
+	failed.Fail(ClassDNS, errors.New("no such host"))
+	dir := writeRun(t, testResult("a.example", "https://a.example", 200, "A"), failed)
+
+	if err := CreateRunReports(dir, []string{"json"}, ReportOptions{}); err != nil {
+		t.Fatal(err)
+	}
+	data, err := os.ReadFile(dir + ".json")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var report struct {
+		SchemaVersion int            `json:"schema_version"`
+		Summary       map[string]int `json:"summary"`
+		Results       []Result       `json:"results"`
+		OutOfScope    []interface{}  `json:"out_of_scope"`
+	}
+	if err := json.Unmarshal(data, &report); err != nil {
+		t.Fatalf("JSON 报告格式错误: %v\n%s", err, data)
+	}
+	if report.SchemaVersion != ResultSchemaVersion {
+		t.Errorf("报告的 schema_version 为 %d", report.SchemaVersion)
+	}
+	if report.Summary["total"] != 2 || report.Summary["success"] != 1 || report.Summary["failed"] != 1 {
+		t.Errorf("报告摘要错误: %v", report.Summary)
+	}
+	if len(report.Results) != 2 || report.Results[1].ErrorClass != ClassDNS || report.Results[0].SchemaVersion != ResultSchemaVersion {
+		t.Errorf("报告结果错误: %+v", report.Results)
+	}
+	if report.OutOfScope == nil || !strings.Contains(string(data), `"out_of_scope":[]`) {
+		t.Errorf("没有范围外目标时 out_of_scope 应为空数组: %s", data)
+	}
+}
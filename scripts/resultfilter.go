@@ -0,0 +1,103 @@
+package scripts
+
+import (
+	"fmt"
+	"regexp"
+	"slices"
+	"strings"
+)
+
+// ResultFilter 为结果筛选条件，由空格分隔的多个条件组成，全部满足时匹配：
+//
+//	status:2xx        状态码、状态码段或错误类型，多个以逗号分隔
+//	class:TIMEOUT     错误类型，多个以逗号分隔
+//	title:后台|登录    标题匹配正则（不区分大小写），同样支持 url、server、body
+//	admin             其他不带前缀的词在 URL、标题、响应头与响应体中查找（不区分大小写）
+type ResultFilter struct {
+	expr     string
+	statuses []string
+	classes  []string
+	patterns map[string]*regexp.Regexp
+	terms    []string
+}
+
+// filterFields 为支持正则匹配的字段
+var filterFields = map[string]func(*Result) string{
+	"title":  func(r *Result) string { return r.Title },
+	"url":    func(r *Result) string { return r.URL + "\n" + r.FinalURL },
+	"server": func(r *Result) string { return r.Server },
+	"body":   func(r *Result) string { return r.BodyPreview },
+}
+
+// ParseResultFilter 解析筛选表达式
+func ParseResultFilter(expr string) (*ResultFilter, error) {
+	filter := &ResultFilter{expr: strings.TrimSpace(expr), patterns: make(map[string]*regexp.Regexp)}
+	fields := strings.Fields(expr)
+	if len(fields) == 0 {
+		return nil, fmt.Errorf("筛选条件为空")
+	}
+
+	for _, field := range fields {
+		key, value, ok := strings.Cut(field, ":")
+		key = strings.ToLower(key)
+		switch {
+		case ok && key == "status":
+			filter.statuses = append(filter.statuses, splitList(value)...)
+		case ok && key == "class":
+			for _, class := range splitList(value) {
+				filter.classes = append(filter.classes, strings.ToUpper(class))
+			}
+		case ok && filterFields[key] != nil:
+			pattern, err := regexp.Compile("(?i)" + value)
+			if err != nil {
+				return nil, fmt.Errorf("筛选条件 %s 的正则无效: %w", field, err)
+			}
+			filter.patterns[key] = pattern
+		default:
+			filter.terms = append(filter.terms, strings.ToLower(field))
+		}
+	}
+	return filter, nil
+}
+
+func splitList(value string) []string {
+	var items []string
+	for _, item := range strings.Split(value, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
+
+// String 返回筛选表达式
+func (f *ResultFilter) String() string {
+	return f.expr
+}
+
+// Match 判断结果是否满足全部条件，未处理的地址不匹配任何条件
+func (f *ResultFilter) Match(result *Result) bool {
+	if result.NotProcessed() {
+		return false
+	}
+	if len(f.statuses) > 0 && !slices.ContainsFunc(f.statuses, func(status string) bool { return matchStatus(result, status) }) {
+		return false
+	}
+	if len(f.classes) > 0 && !slices.Contains(f.classes, result.ErrorClass) {
+		return false
+	}
+	for key, pattern := range f.patterns {
+		if !pattern.MatchString(filterFields[key](result)) {
+			return false
+		}
+	}
+	if len(f.terms) > 0 {
+		text := searchText(result)
+		for _, term := range f.terms {
+			if !strings.Contains(text, term) {
+				return false
+			}
+		}
+	}
+	return true
+}
@@ -0,0 +1,116 @@
+package scripts
+
+import (
+	"context"
+	"fmt"
+	"math/rand"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	defaultRetries    = 1
+	defaultBackoff    = 2 * time.Second
+	defaultMaxBackoff = 30 * time.Second
+	defaultJitter     = 0.2
+)
+
+type RetryPolicy struct {
+	Retries    int
+	Backoff    time.Duration
+	MaxBackoff time.Duration
+	Jitter     float64
+	Rules      map[string]int
+}
+
+func DefaultRetryPolicy() *RetryPolicy {
+	return &RetryPolicy{
+		Retries:    defaultRetries,
+		Backoff:    defaultBackoff,
+		MaxBackoff: defaultMaxBackoff,
+		Jitter:     defaultJitter,
+		Rules:      map[string]int{},
+	}
+}
+
+// ParseRetryRules 解析按错误类型设置的重试次数，格式为 "DNS_ERROR=0,TIMEOUT=2"
+func ParseRetryRules(spec string) (map[string]int, error) {
+	rules := make(map[string]int)
+	for _, part := range strings.Split(spec, ",") {
+		part = strings.TrimSpace(part)
+		if part == "" {
+			continue
+		}
+
+		kv := strings.SplitN(part, "=", 2)
+		if len(kv) != 2 {
+			return nil, fmt.Errorf("重试规则格式错误: %s", part)
+		}
+
+		retries, err := strconv.Atoi(strings.TrimSpace(kv[1]))
+		if err != nil || retries < 0 {
+			return nil, fmt.Errorf("重试次数无效: %s", part)
+		}
+		rules[strings.ToUpper(strings.TrimSpace(kv[0]))] = retries
+	}
+	return rules, nil
+}
+
+// MaxRetries 返回指定错误类型允许的重试次数，未配置规则的类型使用默认次数
+func (p *RetryPolicy) MaxRetries(class string) int {
+	if p == nil {
+		return defaultRetries
+	}
+	if retries, ok := p.Rules[class]; ok {
+		return retries
+	}
+	return p.Retries
+}
+
+// Delay 返回第 attempt 次重试前的等待时间，按指数退避并附加随机抖动
+func (p *RetryPolicy) Delay(attempt int) time.Duration {
+	backoff, maxBackoff, jitter := defaultBackoff, defaultMaxBackoff, defaultJitter
+	if p != nil {
+		backoff, maxBackoff, jitter = p.Backoff, p.MaxBackoff, p.Jitter
+	}
+	if backoff <= 0 {
+		return 0
+	}
+
+	delay := backoff
+	for i := 1; i < attempt && delay < maxBackoff; i++ {
+		delay *= 2
+	}
+	if maxBackoff > 0 && delay > maxBackoff {
+		delay = maxBackoff
+	}
+
+	if jitter > 0 {
+		delta := float64(delay) * jitter
+		delay = time.Duration(float64(delay) - delta + rand.Float64()*2*delta)
+	}
+	return delay
+}
+
+// Do 执行 fn，失败时按错误类型决定是否重试；fn 返回的 class 为空或属于不可重试的类型时直接返回
+func (p *RetryPolicy) Do(ctx context.Context, fn func(attempt int) (string, error)) error {
+	attempt := 0
+	for {
+		class, err := fn(attempt)
+		if err == nil {
+			return nil
+		}
+
+		attempt++
+		if !retryable(class) || attempt > p.MaxRetries(class) {
+			return err
+		}
+
+		select {
+		case <-ctx.Done():
+			return err
+		case <-time.After(p.Delay(attempt)):
+		}
+	}
+}
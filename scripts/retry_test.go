@@ -0,0 +1,105 @@
+package scripts
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestParseRetryRules(t *testing.T) {
+	rules, err := ParseRetryRules(" dns_error=0, TIMEOUT = 3 ,")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(rules) != 2 || rules[ClassDNS] != 0 || rules[ClassTimeout] != 3 {
+		t.Errorf("解析结果为 %v", rules)
+	}
+
+	for _, spec := range []string{"TIMEOUT", "TIMEOUT=-1", "TIMEOUT=x"} {
+		if _, err := ParseRetryRules(spec); err == nil {
+			t.Errorf("规则 %q 应报错", spec)
+		}
+	}
+}
+
+func TestRetryPolicyDo(t *testing.T) {
+	policy := &RetryPolicy{Retries: 2, Rules: map[string]int{ClassDNS: 0, ClassTimeout: 4}}
+
+	tests := []struct {
+		class string
+		want  int
+	}{
+		{class: ClassConnRefused, want: 3},
+		{class: ClassTimeout, want: 5},
+		{class: ClassDNS, want: 1},
+		{class: ClassOutOfScope, want: 1},
+		{class: ClassBrowser, want: 1},
+		{class: ClassTooManyRedirects, want: 1},
+		{class: ClassNotProcessed, want: 1},
+		{class: "", want: 1},
+	}
+	for _, tt := range tests {
+		attempts := 0
+		err := policy.Do(context.Background(), func(attempt int) (string, error) {
+			if attempt != attempts {
+				t.Errorf("%s: 第 %d 次调用的 attempt 为 %d", tt.class, attempts+1, attempt)
+			}
+			attempts++
+			return tt.class, errors.New("失败")
+		})
+		if err == nil || attempts != tt.want {
+			t.Errorf("%q 执行了 %d 次，应为 %d", tt.class, attempts, tt.want)
+		}
+	}
+
+	attempts := 0
+	err := policy.Do(context.Background(), func(attempt int) (string, error) {
+		attempts++
+		if attempt == 0 {
+			return ClassTimeout, errors.New("超时")
+		}
+		return "", nil
+	})
+	if err != nil || attempts != 2 {
+		t.Errorf("重试成功后应返回 nil，执行了 %d 次: %v", attempts, err)
+	}
+}
+
+func TestRetryPolicyCanceled(t *testing.T) {
+	policy := &RetryPolicy{Retries: 5, Backoff: time.Hour}
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+
+	attempts := 0
+	err := policy.Do(ctx, func(int) (string, error) {
+		attempts++
+		return ClassTimeout, errors.New("超时")
+	})
+	if err == nil || attempts != 1 {
+		t.Errorf("等待重试时 ctx 结束应返回最后一次错误，执行了 %d 次", attempts)
+	}
+}
+
+func TestRetryPolicyDelay(t *testing.T) {
+	policy := &RetryPolicy{Backoff: time.Second, MaxBackoff: 5 * time.Second}
+	for attempt, want := range map[int]time.Duration{1: time.Second, 2: 2 * time.Second, 3: 4 * time.Second, 4: 5 * time.Second, 10: 5 * time.Second} {
+		if got := policy.Delay(attempt); got != want {
+			t.Errorf("第 %d 次重试等待 %s，应为 %s", attempt, got, want)
+		}
+	}
+
+	policy.Jitter = 0.2
+	for i := 0; i < 100; i++ {
+		if got := policy.Delay(2); got < 1600*time.Millisecond || got > 2400*time.Millisecond {
+			t.Fatalf("抖动后的等待时间 %s 超出 ±20%%", got)
+		}
+	}
+
+	if got := (&RetryPolicy{}).Delay(3); got != 0 {
+		t.Errorf("Backoff 为 0 时不应等待，实际为 %s", got)
+	}
+	if got := (&RetryPolicy{Rules: map[string]int{}}).MaxRetries(ClassTimeout); got != 0 {
+		t.Errorf("未配置规则时应使用 Retries，实际为 %d", got)
+	}
+}
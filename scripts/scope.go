@@ -0,0 +1,310 @@
+package scripts
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"net"
+	"net/url"
+	"os"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+var ErrOutOfScope = errors.New("目标超出测试范围")
+
+type portRange struct {
+	start int
+	end   int
+}
+
+type ScopeRule struct {
+	Allow  bool
+	Raw    string
+	Line   int
+	domain string
+	suffix bool
+	cidr   *net.IPNet
+	ip     net.IP
+	regex  *regexp.Regexp
+	ports  []portRange
+}
+
+type Scope struct {
+	rules    []ScopeRule
+	hasAllow bool
+}
+
+type OutOfScopeTarget struct {
+	URL    string `json:"url"`
+	Reason string `json:"reason"`
+}
+
+// LoadScope 读取范围文件，每行格式为 "allow|deny <规则>"，也可使用 "+"/"-" 前缀
+// 规则支持: 域名(*.example.com)、IP、CIDR(10.0.0.0/8)、以上类型附带端口(example.com:80,8000-9000)、
+// 仅端口(port:80-443) 以及 URL 正则(regex:^https://)
+func LoadScope(path string) (*Scope, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("无法打开范围文件 %s: %w", path, err)
+	}
+	defer file.Close()
+
+	scope := &Scope{}
+	scanner := bufio.NewScanner(file)
+	lineNum := 0
+	for scanner.Scan() {
+		lineNum++
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		rule, err := parseScopeLine(line)
+		if err != nil {
+			return nil, fmt.Errorf("范围文件第 %d 行解析失败: %w", lineNum, err)
+		}
+		rule.Line = lineNum
+		if rule.Allow {
+			scope.hasAllow = true
+		}
+		scope.rules = append(scope.rules, rule)
+	}
+
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("读取范围文件 %s 失败: %w", path, err)
+	}
+
+	return scope, nil
+}
+
+func parseScopeLine(line string) (ScopeRule, error) {
+	var rule ScopeRule
+
+	switch {
+	case strings.HasPrefix(line, "+"):
+		rule.Allow = true
+		line = line[1:]
+	case strings.HasPrefix(line, "-"):
+		line = line[1:]
+	default:
+		fields := strings.Fields(line)
+		if len(fields) < 2 {
+			return rule, fmt.Errorf("缺少 allow/deny 前缀: %s", line)
+		}
+		switch strings.ToLower(strings.TrimSuffix(fields[0], ":")) {
+		case "allow", "include":
+			rule.Allow = true
+		case "deny", "exclude":
+		default:
+			return rule, fmt.Errorf("未知的规则类型: %s", fields[0])
+		}
+		line = line[len(fields[0]):]
+	}
+
+	line = strings.TrimSpace(line)
+	rule.Raw = line
+	if line == "" {
+		return rule, errors.New("规则内容为空")
+	}
+
+	if strings.HasPrefix(line, "regex:") {
+		re, err := regexp.Compile(strings.TrimPrefix(line, "regex:"))
+		if err != nil {
+			return rule, fmt.Errorf("正则表达式无效: %w", err)
+		}
+		rule.regex = re
+		return rule, nil
+	}
+
+	if strings.HasPrefix(line, "port:") {
+		ports, err := parsePortRanges(strings.TrimPrefix(line, "port:"))
+		if err != nil {
+			return rule, err
+		}
+		rule.ports = ports
+		return rule, nil
+	}
+
+	host := line
+	if _, cidr, err := net.ParseCIDR(host); err == nil {
+		rule.cidr = cidr
+		return rule, nil
+	}
+	if ip := net.ParseIP(strings.Trim(host, "[]")); ip != nil {
+		rule.ip = ip
+		return rule, nil
+	}
+
+	if idx := strings.LastIndex(host, ":"); idx > 0 {
+		ports, err := parsePortRanges(host[idx+1:])
+		if err != nil {
+			return rule, err
+		}
+		rule.ports = ports
+		host = host[:idx]
+	}
+
+	if _, cidr, err := net.ParseCIDR(host); err == nil {
+		rule.cidr = cidr
+		return rule, nil
+	}
+	if ip := net.ParseIP(strings.Trim(host, "[]")); ip != nil {
+		rule.ip = ip
+		return rule, nil
+	}
+
+	host = strings.ToLower(host)
+	if strings.HasPrefix(host, "*.") {
+		rule.suffix = true
+		host = host[2:]
+	}
+	if host == "*" {
+		rule.suffix = true
+		host = ""
+	}
+	rule.domain = host
+	return rule, nil
+}
+
+func parsePortRanges(spec string) ([]portRange, error) {
+	var ranges []portRange
+	for _, part := range strings.Split(spec, ",") {
+		part = strings.TrimSpace(part)
+		if part == "" {
+			continue
+		}
+
+		bounds := strings.SplitN(part, "-", 2)
+		start, err := strconv.Atoi(bounds[0])
+		if err != nil || start < 1 || start > 65535 {
+			return nil, fmt.Errorf("端口无效: %s", part)
+		}
+		end := start
+		if len(bounds) == 2 {
+			end, err = strconv.Atoi(bounds[1])
+			if err != nil || end < start || end > 65535 {
+				return nil, fmt.Errorf("端口范围无效: %s", part)
+			}
+		}
+		ranges = append(ranges, portRange{start: start, end: end})
+	}
+
+	if len(ranges) == 0 {
+		return nil, fmt.Errorf("端口为空: %s", spec)
+	}
+	return ranges, nil
+}
+
+func (r *ScopeRule) match(u *url.URL, host string, port int) bool {
+	if r.regex != nil {
+		return r.regex.MatchString(u.String())
+	}
+
+	if len(r.ports) > 0 {
+		inRange := false
+		for _, p := range r.ports {
+			if port >= p.start && port <= p.end {
+				inRange = true
+				break
+			}
+		}
+		if !inRange {
+			return false
+		}
+	}
+
+	switch {
+	case r.cidr != nil:
+		ip := net.ParseIP(host)
+		return ip != nil && r.cidr.Contains(ip)
+	case r.ip != nil:
+		ip := net.ParseIP(host)
+		return ip != nil && r.ip.Equal(ip)
+	case r.domain != "" || r.suffix:
+		if r.domain == "" {
+			return true
+		}
+		if r.suffix {
+			return strings.HasSuffix(host, "."+r.domain)
+		}
+		return host == r.domain
+	}
+
+	return len(r.ports) > 0
+}
+
+// Check 判断地址是否在范围内，deny 规则优先；存在 allow 规则时必须至少命中一条
+func (s *Scope) Check(raw string) (bool, string) {
+	if s == nil || len(s.rules) == 0 {
+		return true, ""
+	}
+
+	u, err := url.Parse(raw)
+	if err != nil || u.Hostname() == "" {
+		return false, fmt.Sprintf("地址无法解析: %s", raw)
+	}
+
+	host := strings.TrimSuffix(strings.ToLower(u.Hostname()), ".")
+	port, _ := strconv.Atoi(u.Port())
+	if port == 0 {
+		port, _ = strconv.Atoi(defaultPorts[strings.ToLower(u.Scheme)])
+	}
+
+	allowed := !s.hasAllow
+	for i := range s.rules {
+		rule := &s.rules[i]
+		if !rule.match(u, host, port) {
+			continue
+		}
+		if !rule.Allow {
+			return false, fmt.Sprintf("命中排除规则 %s (第 %d 行)", rule.Raw, rule.Line)
+		}
+		allowed = true
+	}
+
+	if !allowed {
+		return false, "未命中任何允许规则"
+	}
+	return true, ""
+}
+
+func (s *Scope) InScope(raw string) bool {
+	ok, _ := s.Check(raw)
+	return ok
+}
+
+// FilterScope 将地址列表拆分为范围内与范围外两部分，未指定协议的地址只要任一协议在范围内即保留
+func FilterScope(urls []string, scope *Scope) ([]string, []OutOfScopeTarget) {
+	if scope == nil {
+		return urls, nil
+	}
+
+	inScope := make([]string, 0, len(urls))
+	var outOfScope []OutOfScopeTarget
+	for _, u := range urls {
+		if ok, reason := CheckTarget(scope, u); !ok {
+			outOfScope = append(outOfScope, OutOfScopeTarget{URL: u, Reason: reason})
+			continue
+		}
+		inScope = append(inScope, u)
+	}
+	return inScope, outOfScope
+}
+
+// CheckTarget 判断单个目标是否在范围内，未指定协议的目标只要任一候选地址在范围内即可
+func CheckTarget(scope *Scope, target string) (bool, string) {
+	if scope == nil {
+		return true, ""
+	}
+
+	var reason string
+	for _, candidate := range SchemeCandidates(target) {
+		var ok bool
+		if ok, reason = scope.Check(candidate); ok {
+			return true, ""
+		}
+	}
+	return false, reason
+}
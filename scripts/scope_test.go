@@ -0,0 +1,115 @@
+package scripts
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync/atomic"
+	"testing"
+)
+
+// loadTestScope 将规则写入临时范围文件并加载
+func loadTestScope(t *testing.T, rules ...string) *Scope {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "scope.txt")
+	if err := os.WriteFile(path, []byte(strings.Join(rules, "\n")), 0644); err != nil {
+		t.Fatal(err)
+	}
+	scope, err := LoadScope(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return scope
+}
+
+func TestScopeCheck(t *testing.T) {
+	tests := []struct {
+		name  string
+		rules []string
+		url   string
+		want  bool
+	}{
+		{name: "无规则", url: "https://any.example", want: true},
+		{name: "通配子域名", rules: []string{"allow *.example.com"}, url: "https://a.b.example.com", want: true},
+		{name: "通配不含主域名", rules: []string{"allow *.example.com"}, url: "https://example.com", want: false},
+		{name: "通配不匹配相似域名", rules: []string{"allow *.example.com"}, url: "https://badexample.com", want: false},
+		{name: "域名大小写与结尾点", rules: []string{"allow example.com"}, url: "https://EXAMPLE.com./", want: true},
+		{name: "未命中允许规则", rules: []string{"allow example.com"}, url: "https://other.example", want: false},
+		{name: "CIDR", rules: []string{"+10.0.0.0/8"}, url: "http://10.1.2.3:8080", want: true},
+		{name: "CIDR 之外", rules: []string{"+10.0.0.0/8"}, url: "http://192.168.1.1", want: false},
+		{name: "IPv6", rules: []string{"allow 2001:db8::/32"}, url: "https://[2001:db8::1]:8443", want: true},
+		{name: "域名端口", rules: []string{"allow example.com:8000-9000"}, url: "http://example.com:8080", want: true},
+		{name: "域名端口之外", rules: []string{"allow example.com:8000-9000"}, url: "http://example.com:7000", want: false},
+		{name: "默认端口", rules: []string{"allow example.com:443"}, url: "https://example.com/login", want: true},
+		{name: "仅端口", rules: []string{"deny port:22,3389"}, url: "http://10.0.0.1:3389", want: false},
+		{name: "仅端口之外", rules: []string{"deny port:22,3389"}, url: "http://10.0.0.1", want: true},
+		{name: "排除优先", rules: []string{"allow *.example.com", "deny admin.example.com"}, url: "https://admin.example.com", want: false},
+		{name: "排除优先与顺序无关", rules: []string{"deny admin.example.com", "allow *.example.com"}, url: "https://admin.example.com", want: false},
+		{name: "排除其他子域名", rules: []string{"allow *.example.com", "deny admin.example.com"}, url: "https://www.example.com", want: true},
+		{name: "正则", rules: []string{"-regex:/logout"}, url: "https://example.com/logout", want: false},
+		{name: "只有排除规则", rules: []string{"exclude: 192.168.0.0/16"}, url: "https://example.com", want: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			scope := loadTestScope(t, tt.rules...)
+			ok, reason := scope.Check(tt.url)
+			if ok != tt.want {
+				t.Errorf("Check(%q) = %v（%s），应为 %v", tt.url, ok, reason, tt.want)
+			}
+			if !ok && reason == "" {
+				t.Error("范围外地址应返回原因")
+			}
+		})
+	}
+}
+
+func TestScopeRuleErrors(t *testing.T) {
+	for _, rule := range []string{"example.com", "maybe example.com", "allow example.com:0", "allow example.com:90-80", "allow regex:(", "allow port:"} {
+		path := filepath.Join(t.TempDir(), "scope.txt")
+		if err := os.WriteFile(path, []byte("# 注释\n"+rule), 0644); err != nil {
+			t.Fatal(err)
+		}
+		_, err := LoadScope(path)
+		if err == nil || !strings.Contains(err.Error(), "第 2 行") {
+			t.Errorf("规则 %q 应报错并指出行号，实际为 %v", rule, err)
+		}
+	}
+}
+
+func TestCheckTargetSchemeless(t *testing.T) {
+	scope := loadTestScope(t, "allow example.com:80")
+	if ok, _ := CheckTarget(scope, "example.com"); !ok {
+		t.Error("未指定协议的目标只要 http 在范围内即应保留")
+	}
+	if ok, _ := CheckTarget(scope, "https://example.com"); ok {
+		t.Error("指定 https 的目标不应在范围内")
+	}
+}
+
+func TestRedirectOutOfScope(t *testing.T) {
+	var outside atomic.Bool
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if strings.HasPrefix(r.Host, "localhost") {
+			outside.Store(true)
+		}
+		http.Redirect(w, r, "http://localhost"+strings.TrimPrefix(r.Host, "127.0.0.1")+"/", http.StatusFound)
+	}))
+	defer server.Close()
+
+	opts := &Options{Scope: loadTestScope(t, "allow 127.0.0.1"), Retry: &RetryPolicy{Rules: map[string]int{}}, Logger: NopLogger{}}
+	result := NewResult(server.URL, server.URL)
+	FetchResponse(context.Background(), server.URL, opts, &result)
+
+	if result.ErrorClass != ClassOutOfScope {
+		t.Fatalf("重定向到范围外时错误类型为 %q，应为 %s", result.ErrorClass, ClassOutOfScope)
+	}
+	if outside.Load() {
+		t.Error("不应请求范围外的重定向目标")
+	}
+	if !strings.Contains(result.Error, "localhost") {
+		t.Errorf("错误信息应包含范围外的重定向目标: %s", result.Error)
+	}
+}
@@ -0,0 +1,341 @@
+package scripts
+
+import (
+	log "Sowhp/concert/logger"
+	"bufio"
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"image"
+	"image/jpeg"
+	"image/png"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+)
+
+// StandaloneFormat 为独立 HTML 报告格式：截图以 data URI 内嵌，报告可以单独通过邮件或工单发送
+const StandaloneFormat = "standalone"
+
+const (
+	// DefaultStandaloneLimit 为单个独立报告文件的默认大小上限，超出时拆分为多个文件
+	DefaultStandaloneLimit = 15 << 20
+	// 缩略图宽度与最大高度，超出最大高度的长截图只保留页面顶部
+	standaloneThumbWidth     = 480
+	standaloneThumbMaxHeight = 1200
+	standaloneThumbQuality   = 75
+)
+
+// ReportOptions 为生成报告的可选参数
+type ReportOptions struct {
+	// StandaloneLimit 为单个独立报告文件的大小上限（字节），0 表示使用 DefaultStandaloneLimit
+	StandaloneLimit int64
+}
+
+// IsReportFormat 判断是否为支持的报告格式
+func IsReportFormat(format string) bool {
+	return format == StandaloneFormat || isReportFormat(format)
+}
+
+// standaloneWriter 依次写入独立报告的各个文件，当前文件加入下一条结果后超过大小上限时换到下一个文件
+type standaloneWriter struct {
+	name   string
+	base   string
+	head   string
+	limit  int64
+	footer int64
+
+	file    *os.File
+	counter *countingWriter
+	writer  *bufio.Writer
+	items   int
+	paths   []string
+}
+
+// countingWriter 统计已写入的字节数
+type countingWriter struct {
+	w io.Writer
+	n int64
+}
+
+func (c *countingWriter) Write(p []byte) (int, error) {
+	n, err := c.w.Write(p)
+	c.n += int64(n)
+	return n, err
+}
+
+// generateStandaloneReport 生成内嵌截图的独立 HTML 报告：表格中显示 JPEG 缩略图，点击后显示原图。
+// 只有一个文件时为 <运行名称>.standalone.html，拆分时为 <运行名称>.standalone.1.html、.2.html……
+func (rg *ReportGenerator) generateStandaloneReport(stats reportStats, limit int64) error {
+	if limit <= 0 {
+		limit = DefaultStandaloneLimit
+	}
+	base := filepath.Join(rg.resultDir, rg.resultName+"."+StandaloneFormat)
+	if old, _ := filepath.Glob(base + "*.html"); len(old) > 0 {
+		for _, path := range old {
+			os.Remove(path)
+		}
+	}
+
+	w := &standaloneWriter{
+		name:   rg.resultName + "." + StandaloneFormat,
+		base:   base,
+		head:   htmlReportHead(rg.resultName, stats),
+		limit:  limit,
+		footer: int64(len(htmlReportScript)) + 512,
+	}
+	defer w.abort()
+
+	if err := w.open(); err != nil {
+		return err
+	}
+	// 范围外目标只写入第一个文件
+	w.write("\n            outOfScope: ")
+	err := writeJSONArray(w.writer, func(emit func(interface{}) error) error {
+		return ScanOutOfScope(rg.runDir(), func(target OutOfScopeTarget) error {
+			return emit(htmlOutOfScopeItem{URL: target.URL, Reason: target.Reason})
+		})
+	})
+	if err != nil {
+		return err
+	}
+	w.write(",\n            items: [")
+
+	err = ScanResults(rg.runDir(), func(record Result) error {
+		data, err := json.Marshal(rg.standaloneItem(record))
+		if err != nil {
+			return fmt.Errorf("JSON编码失败: %w", err)
+		}
+		return w.add(data)
+	})
+	if err != nil {
+		return err
+	}
+	if err := w.close(false); err != nil {
+		return err
+	}
+
+	paths, err := w.finish()
+	if err != nil {
+		return err
+	}
+	for _, path := range paths {
+		log.Info(fmt.Sprintf("生成独立报告成功: %s", path))
+	}
+	return nil
+}
+
+// standaloneItem 读取截图并生成内嵌缩略图与原图的报告行，截图不存在时不显示截图
+func (rg *ReportGenerator) standaloneItem(record Result) htmlReportItem {
+	item := htmlReportItem{
+		URL:        record.URL,
+		Title:      record.Title,
+		Status:     record.Status(),
+		ErrorClass: record.ErrorClass,
+		Response:   record.Response(),
+	}
+	path := screenshotPath(rg.runDir(), record)
+	if path == "" {
+		return item
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		log.Debug(fmt.Sprintf("读取截图失败 %s: %v", path, err))
+		return item
+	}
+
+	original := "data:image/png;base64," + base64.StdEncoding.EncodeToString(data)
+	thumb, err := thumbnailJPEG(data)
+	switch {
+	case err != nil:
+		log.Debug(fmt.Sprintf("生成缩略图失败 %s: %v", path, err))
+		item.Screenshot = original
+	case thumb == nil:
+		// 截图本身不大于缩略图，直接使用原图
+		item.Screenshot = original
+	default:
+		item.Screenshot = "data:image/jpeg;base64," + base64.StdEncoding.EncodeToString(thumb)
+		item.Image = original
+	}
+	return item
+}
+
+// thumbnailJPEG 将 PNG 截图缩小为 JPEG 缩略图，截图不大于缩略图尺寸时返回 nil
+func thumbnailJPEG(data []byte) ([]byte, error) {
+	img, err := png.Decode(bytes.NewReader(data))
+	if err != nil {
+		return nil, err
+	}
+	bounds := img.Bounds()
+	if bounds.Dx() <= standaloneThumbWidth && bounds.Dy() <= standaloneThumbMaxHeight {
+		return nil, nil
+	}
+
+	var buf bytes.Buffer
+	thumb := thumbnail(toRGBA(img), standaloneThumbWidth, standaloneThumbMaxHeight)
+	if err := jpeg.Encode(&buf, thumb, &jpeg.Options{Quality: standaloneThumbQuality}); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// thumbnail 按区域平均将图片缩放到指定宽度，透明部分以白色填充；缩放后高度超过 maxHeight 时只保留顶部
+func thumbnail(src *image.RGBA, width, maxHeight int) *image.RGBA {
+	srcWidth, srcHeight := src.Rect.Dx(), src.Rect.Dy()
+	width = min(width, srcWidth)
+	height := max(srcHeight*width/srcWidth, 1)
+	cropHeight := srcHeight
+	if height > maxHeight {
+		height = maxHeight
+		cropHeight = min(maxHeight*srcWidth/width, srcHeight)
+	}
+
+	dst := image.NewRGBA(image.Rect(0, 0, width, height))
+	for y := 0; y < height; y++ {
+		y0 := y * cropHeight / height
+		y1 := max((y+1)*cropHeight/height, y0+1)
+		for x := 0; x < width; x++ {
+			x0 := x * srcWidth / width
+			x1 := max((x+1)*srcWidth/width, x0+1)
+
+			var r, g, b int
+			for sy := y0; sy < y1; sy++ {
+				offset := src.PixOffset(x0, sy)
+				for sx := x0; sx < x1; sx++ {
+					// RGBA 为预乘透明度，叠加到白色背景上
+					pixel := src.Pix[offset : offset+4]
+					white := 255 - int(pixel[3])
+					r += int(pixel[0]) + white
+					g += int(pixel[1]) + white
+					b += int(pixel[2]) + white
+					offset += 4
+				}
+			}
+			n := (y1 - y0) * (x1 - x0)
+			offset := dst.PixOffset(x, y)
+			dst.Pix[offset] = uint8(r / n)
+			dst.Pix[offset+1] = uint8(g / n)
+			dst.Pix[offset+2] = uint8(b / n)
+			dst.Pix[offset+3] = 255
+		}
+	}
+	return dst
+}
+
+func (w *standaloneWriter) partPath(index int) string {
+	return w.base + "." + strconv.Itoa(index) + ".html"
+}
+
+func (w *standaloneWriter) partName(index int) string {
+	return w.name + "." + strconv.Itoa(index) + ".html"
+}
+
+// open 创建下一个文件并写入页面开头，文件先以 .tmp 结尾，全部写完后再重命名
+func (w *standaloneWriter) open() error {
+	path := w.partPath(len(w.paths)+1) + ".tmp"
+	file, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0644)
+	if err != nil {
+		return fmt.Errorf("创建独立报告文件失败: %w", err)
+	}
+	w.file = file
+	w.counter = &countingWriter{w: file}
+	w.writer = bufio.NewWriter(w.counter)
+	w.paths = append(w.paths, path)
+	w.items = 0
+	w.write(w.head)
+	return nil
+}
+
+func (w *standaloneWriter) write(s string) {
+	w.writer.WriteString(s)
+}
+
+// size 返回当前文件已写入的字节数
+func (w *standaloneWriter) size() int64 {
+	return w.counter.n + int64(w.writer.Buffered())
+}
+
+// add 写入一条结果，当前文件已有结果且加入后会超过大小上限时先换到下一个文件
+func (w *standaloneWriter) add(data []byte) error {
+	if w.items > 0 && w.size()+int64(len(data))+1+w.footer > w.limit {
+		if err := w.close(true); err != nil {
+			return err
+		}
+		if err := w.open(); err != nil {
+			return err
+		}
+		w.write("\n            outOfScope: [],\n            items: [")
+	}
+	if w.items > 0 {
+		w.write(",")
+	} else if w.size()+int64(len(data))+w.footer > w.limit {
+		log.Warning(fmt.Sprintf("单条结果内嵌截图后超过独立报告大小上限，%s 无法再拆分，将超过上限", filepath.Base(w.partPath(len(w.paths)))))
+	}
+	w.writer.Write(data)
+	w.items++
+	return nil
+}
+
+// close 写入分页信息与页面脚本并关闭当前文件，next 表示之后还有文件
+func (w *standaloneWriter) close(next bool) error {
+	index := len(w.paths)
+	parts := "null"
+	if next || index > 1 {
+		nav := map[string]interface{}{"index": index}
+		if index > 1 {
+			nav["prev"] = w.partName(index - 1)
+		}
+		if next {
+			nav["next"] = w.partName(index + 1)
+		}
+		data, _ := json.Marshal(nav)
+		parts = string(data)
+	}
+	w.write("],\n            parts: " + parts + "\n        };")
+	w.write(htmlReportScript)
+
+	err := w.writer.Flush()
+	if closeErr := w.file.Close(); err == nil {
+		err = closeErr
+	}
+	w.file = nil
+	if err != nil {
+		return fmt.Errorf("写入独立报告失败: %w", err)
+	}
+	return nil
+}
+
+// finish 将临时文件重命名为最终文件名，只有一个文件时不带序号
+func (w *standaloneWriter) finish() ([]string, error) {
+	if len(w.paths) == 1 {
+		path := w.base + ".html"
+		if err := os.Rename(w.paths[0], path); err != nil {
+			return nil, fmt.Errorf("写入独立报告失败: %w", err)
+		}
+		w.paths = nil
+		return []string{path}, nil
+	}
+
+	var paths []string
+	for i, tmp := range w.paths {
+		path := w.partPath(i + 1)
+		if err := os.Rename(tmp, path); err != nil {
+			return nil, fmt.Errorf("写入独立报告失败: %w", err)
+		}
+		paths = append(paths, path)
+	}
+	w.paths = nil
+	return paths, nil
+}
+
+// abort 在生成失败时删除未完成的临时文件
+func (w *standaloneWriter) abort() {
+	if w.file != nil {
+		w.file.Close()
+	}
+	for _, path := range w.paths {
+		os.Remove(path)
+	}
+}
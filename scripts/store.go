@@ -0,0 +1,241 @@
+package scripts
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+)
+
+const (
+	InputFileName      = "input.txt"
+	ResultsFileName    = "results.jsonl"
+	OutOfScopeFileName = "out_of_scope.jsonl"
+)
+
+// jsonlWriter 以 JSONL 形式逐条追加记录，每次追加直接落盘
+type jsonlWriter struct {
+	mu   sync.Mutex
+	file *os.File
+}
+
+func openJSONL(path string, flag int) (*jsonlWriter, error) {
+	file, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|flag, 0644)
+	if err != nil {
+		return nil, err
+	}
+	return &jsonlWriter{file: file}, nil
+}
+
+func (w *jsonlWriter) append(records ...interface{}) error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	var builder strings.Builder
+	for _, record := range records {
+		line, err := json.Marshal(record)
+		if err != nil {
+			return fmt.Errorf("序列化记录失败: %w", err)
+		}
+		builder.Write(line)
+		builder.WriteByte('\n')
+	}
+
+	_, err := w.file.WriteString(builder.String())
+	return err
+}
+
+func (w *jsonlWriter) Close() error {
+	return w.file.Close()
+}
+
+// scanJSONL 逐行读取 JSONL 文件，忽略因进程中断而写入不完整的行
+func scanJSONL(path string, fn func(line []byte) error) error {
+	file, err := os.Open(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	scanner := bufio.NewScanner(file)
+	scanner.Buffer(make([]byte, 64*1024), 16*1024*1024)
+	for scanner.Scan() {
+		if len(scanner.Bytes()) == 0 || !json.Valid(scanner.Bytes()) {
+			continue
+		}
+		if err := fn(scanner.Bytes()); err != nil {
+			return err
+		}
+	}
+	return scanner.Err()
+}
+
+// ResultStore 是运行目录中的结果存储，结果完成后立即追加，报告与断点续跑均基于该文件
+type ResultStore struct {
+	writer *jsonlWriter
+}
+
+func OpenResultStore(runDir string) (*ResultStore, error) {
+	writer, err := openJSONL(filepath.Join(runDir, ResultsFileName), os.O_APPEND)
+	if err != nil {
+		return nil, fmt.Errorf("打开结果文件失败: %w", err)
+	}
+	return &ResultStore{writer: writer}, nil
+}
+
+func (s *ResultStore) Append(records ...Result) error {
+	items := make([]interface{}, len(records))
+	for i := range records {
+		items[i] = records[i]
+	}
+	if err := s.writer.append(items...); err != nil {
+		return fmt.Errorf("写入结果文件失败: %w", err)
+	}
+	return nil
+}
+
+func (s *ResultStore) Close() error {
+	return s.writer.Close()
+}
+
+func ScanResults(runDir string, fn func(Result) error) error {
+	err := scanJSONL(filepath.Join(runDir, ResultsFileName), func(line []byte) error {
+		var record Result
+		if err := json.Unmarshal(line, &record); err != nil {
+			return nil
+		}
+		return fn(record)
+	})
+	if err != nil {
+		return fmt.Errorf("读取结果文件失败: %w", err)
+	}
+	return nil
+}
+
+// CompactResults 移除上次中断时写入的 NOT_PROCESSED 记录，返回已完成地址（输入地址哈希）的集合
+func CompactResults(runDir string) (map[uint64]struct{}, error) {
+	path := filepath.Join(runDir, ResultsFileName)
+	tmpPath := path + ".tmp"
+
+	writer, err := openJSONL(tmpPath, os.O_TRUNC)
+	if err != nil {
+		return nil, fmt.Errorf("整理结果文件失败: %w", err)
+	}
+
+	completed := make(map[uint64]struct{})
+	err = ScanResults(runDir, func(record Result) error {
+		if record.NotProcessed() {
+			return nil
+		}
+		completed[hashKey(record.Input)] = struct{}{}
+		return writer.append(record)
+	})
+	if closeErr := writer.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		os.Remove(tmpPath)
+		return nil, fmt.Errorf("整理结果文件失败: %w", err)
+	}
+
+	if err := os.Rename(tmpPath, path); err != nil {
+		return nil, fmt.Errorf("整理结果文件失败: %w", err)
+	}
+	return completed, nil
+}
+
+func IsCompleted(completed map[uint64]struct{}, input string) bool {
+	_, ok := completed[hashKey(input)]
+	return ok
+}
+
+// InputWriter 逐行保存待处理的地址列表
+type InputWriter struct {
+	file   *os.File
+	writer *bufio.Writer
+}
+
+func CreateInputList(runDir string) (*InputWriter, error) {
+	file, err := os.Create(filepath.Join(runDir, InputFileName))
+	if err != nil {
+		return nil, fmt.Errorf("保存地址列表失败: %w", err)
+	}
+	return &InputWriter{file: file, writer: bufio.NewWriter(file)}, nil
+}
+
+func (w *InputWriter) Add(url string) error {
+	_, err := w.writer.WriteString(url + "\n")
+	return err
+}
+
+func (w *InputWriter) Close() error {
+	if err := w.writer.Flush(); err != nil {
+		w.file.Close()
+		return fmt.Errorf("保存地址列表失败: %w", err)
+	}
+	return w.file.Close()
+}
+
+func ScanInputList(runDir string, fn func(url string) error) error {
+	file, err := os.Open(filepath.Join(runDir, InputFileName))
+	if err != nil {
+		return fmt.Errorf("读取地址列表失败: %w", err)
+	}
+	defer file.Close()
+
+	scanner := bufio.NewScanner(file)
+	scanner.Buffer(make([]byte, 64*1024), 4*1024*1024)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" {
+			continue
+		}
+		if err := fn(line); err != nil {
+			return err
+		}
+	}
+	return scanner.Err()
+}
+
+type OutOfScopeWriter struct {
+	writer *jsonlWriter
+	Count  int
+}
+
+func CreateOutOfScope(runDir string) (*OutOfScopeWriter, error) {
+	writer, err := openJSONL(filepath.Join(runDir, OutOfScopeFileName), os.O_TRUNC)
+	if err != nil {
+		return nil, fmt.Errorf("保存范围外目标失败: %w", err)
+	}
+	return &OutOfScopeWriter{writer: writer}, nil
+}
+
+func (w *OutOfScopeWriter) Add(target OutOfScopeTarget) error {
+	w.Count++
+	return w.writer.append(target)
+}
+
+func (w *OutOfScopeWriter) Close() error {
+	return w.writer.Close()
+}
+
+func ScanOutOfScope(runDir string, fn func(OutOfScopeTarget) error) error {
+	err := scanJSONL(filepath.Join(runDir, OutOfScopeFileName), func(line []byte) error {
+		var target OutOfScopeTarget
+		if err := json.Unmarshal(line, &target); err != nil {
+			return nil
+		}
+		return fn(target)
+	})
+	if err != nil {
+		return fmt.Errorf("读取范围外目标失败: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,174 @@
+package scripts
+
+import (
+	"net/http"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestCompactResults(t *testing.T) {
+	done := testResult("a.example", "https://a.example", 200, "A")
+	failed := NewResult("b.example", "https://b.example")
+	failed.Fail(ClassDNS, nil)
+	dir := writeRun(t, done, failed, NotProcessedResult("c.example", "https://c.example"))
+
+	// 模拟进程在写入过程中退出，最后一行不完整
+	file, err := os.OpenFile(filepath.Join(dir, ResultsFileName), os.O_WRONLY|os.O_APPEND, 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+	file.WriteString(`{"schema_version":1,"input":"d.exam`)
+	file.Close()
+
+	completed, err := CompactResults(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for input, want := range map[string]bool{"a.example": true, "b.example": true, "c.example": false, "d.example": false} {
+		if got := IsCompleted(completed, input); got != want {
+			t.Errorf("%s 是否已完成为 %v，应为 %v", input, got, want)
+		}
+	}
+
+	var inputs []string
+	if err := ScanResults(dir, func(record Result) error {
+		inputs = append(inputs, record.Input)
+		return nil
+	}); err != nil {
+		t.Fatal(err)
+	}
+	if len(inputs) != 2 || inputs[0] != "a.example" || inputs[1] != "b.example" {
+		t.Errorf("整理后的结果为 %v，应只保留已完成的记录", inputs)
+	}
+	if _, err := os.Stat(filepath.Join(dir, ResultsFileName+".tmp")); !os.IsNotExist(err) {
+		t.Error("整理后不应残留临时文件")
+	}
+}
+
+func TestResultStoreRoundTrip(t *testing.T) {
+	full := testResult("a.example", "https://a.example", 302, "登录")
+	full.FinalURL = "https://a.example/login"
+	full.Screenshot = "data/a.example_443-0123456789.png"
+	full.Image = []byte("不写入文件")
+	full.Proto = "HTTP/1.1"
+	full.Server = "nginx"
+	full.ContentLength = 1024
+	full.RedirectChain = []Redirect{{URL: "https://a.example", StatusCode: 302}}
+	full.Headers = http.Header{"Content-Type": {"text/html; charset=utf-8"}}
+	full.BodyPreview = "<html>"
+	full.BodyTruncated = true
+	full.Timings = Timings{AliveMs: 1, PageMs: 2, HTTPMs: 3, TotalMs: 6}
+	full.CapturedAt = full.CapturedAt.Truncate(time.Second)
+
+	dir := t.TempDir()
+	store, err := OpenResultStore(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// 分两次追加，确认追加不会覆盖已有记录
+	if err := store.Append(full); err != nil {
+		t.Fatal(err)
+	}
+	if err := store.Append(NotProcessedResult("b.example", "b.example")); err != nil {
+		t.Fatal(err)
+	}
+	store.Close()
+
+	var records []Result
+	if err := ScanResults(dir, func(record Result) error {
+		records = append(records, record)
+		return nil
+	}); err != nil {
+		t.Fatal(err)
+	}
+	if len(records) != 2 {
+		t.Fatalf("读取到 %d 条记录，应为 2", len(records))
+	}
+
+	want := full
+	want.Image = nil
+	got := records[0]
+	got.CapturedAt = got.CapturedAt.Local()
+	want.CapturedAt = want.CapturedAt.Local()
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("读取的记录与写入的不同:\n%+v\n%+v", got, want)
+	}
+	if !records[1].NotProcessed() || records[1].Input != "b.example" {
+		t.Errorf("第二条记录错误: %+v", records[1])
+	}
+}
+
+func TestScanResultsSkipsBadLines(t *testing.T) {
+	dir := t.TempDir()
+	content := `{"schema_version":1,"input":"a.example","url":"https://a.example","status_code":200}` + "\n" +
+		"not json\n\n" +
+		`{"schema_version":1,"input":"b.example","url":"https://b.example","error_class":"DNS_ERROR"}` + "\n"
+	if err := os.WriteFile(filepath.Join(dir, ResultsFileName), []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	var urls []string
+	if err := ScanResults(dir, func(record Result) error {
+		urls = append(urls, record.URL)
+		return nil
+	}); err != nil {
+		t.Fatal(err)
+	}
+	if len(urls) != 2 || urls[0] != "https://a.example" || urls[1] != "https://b.example" {
+		t.Errorf("读取结果为 %v，应跳过无法解析的行", urls)
+	}
+
+	// 尚未写入任何结果的运行目录视为没有结果
+	count := 0
+	if err := ScanResults(t.TempDir(), func(Result) error { count++; return nil }); err != nil || count != 0 {
+		t.Errorf("结果文件不存在时应返回空结果: %v", err)
+	}
+}
+
+func TestInputAndOutOfScopeLists(t *testing.T) {
+	dir := t.TempDir()
+	inputs, err := CreateInputList(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, url := range []string{"a.example", "https://b.example/path"} {
+		inputs.Add(url)
+	}
+	if err := inputs.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	outOfScope, err := CreateOutOfScope(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	outOfScope.Add(OutOfScopeTarget{URL: "https://c.example", Reason: "命中排除规则 c.example (第 1 行)"})
+	if err := outOfScope.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if outOfScope.Count != 1 {
+		t.Errorf("范围外目标计数为 %d", outOfScope.Count)
+	}
+
+	var urls []string
+	ScanInputList(dir, func(url string) error {
+		urls = append(urls, url)
+		return nil
+	})
+	if len(urls) != 2 || urls[1] != "https://b.example/path" {
+		t.Errorf("地址列表为 %v", urls)
+	}
+
+	var targets []OutOfScopeTarget
+	ScanOutOfScope(dir, func(target OutOfScopeTarget) error {
+		targets = append(targets, target)
+		return nil
+	})
+	if len(targets) != 1 || targets[0].URL != "https://c.example" || !strings.Contains(targets[0].Reason, "第 1 行") {
+		t.Errorf("范围外目标为 %+v", targets)
+	}
+}
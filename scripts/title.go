@@ -0,0 +1,65 @@
+package scripts
+
+import (
+	"bytes"
+	"strings"
+	"unicode/utf8"
+
+	"golang.org/x/net/html"
+	"golang.org/x/net/html/charset"
+	"golang.org/x/text/encoding"
+	"golang.org/x/text/encoding/simplifiedchinese"
+)
+
+// titleReadLimit 为提取标题时最多读取的响应体大小
+const titleReadLimit = 512 * 1024
+
+// decodeBody 按响应头、BOM 与 meta 标签判断编码并转换为 UTF-8；无法确定编码且内容不是合法 UTF-8 时按 GB18030（兼容 GBK/GB2312）解码
+func decodeBody(body []byte, contentType string) string {
+	enc, name, certain := charset.DetermineEncoding(body, contentType)
+	if !certain && utf8.Valid(body) {
+		return string(body)
+	}
+	if !certain && name == "windows-1252" {
+		enc = simplifiedchinese.GB18030
+	}
+	if enc == encoding.Nop {
+		return string(body)
+	}
+
+	decoded, err := enc.NewDecoder().Bytes(body)
+	if err != nil {
+		return string(body)
+	}
+	return string(decoded)
+}
+
+// extractTitle 返回文档中第一个 title 元素的文本，忽略 svg 中的 title
+func extractTitle(document string) string {
+	tokenizer := html.NewTokenizer(strings.NewReader(document))
+	svgDepth := 0
+
+	for {
+		switch tokenizer.Next() {
+		case html.ErrorToken:
+			return ""
+		case html.StartTagToken:
+			name, _ := tokenizer.TagName()
+			switch {
+			case bytes.Equal(name, []byte("svg")):
+				svgDepth++
+			case bytes.Equal(name, []byte("title")) && svgDepth == 0:
+				var title strings.Builder
+				for tokenizer.Next() == html.TextToken {
+					title.Write(tokenizer.Text())
+				}
+				return strings.Join(strings.Fields(title.String()), " ")
+			}
+		case html.EndTagToken:
+			name, _ := tokenizer.TagName()
+			if bytes.Equal(name, []byte("svg")) && svgDepth > 0 {
+				svgDepth--
+			}
+		}
+	}
+}
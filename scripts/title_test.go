@@ -0,0 +1,71 @@
+package scripts
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"golang.org/x/text/encoding"
+	"golang.org/x/text/encoding/japanese"
+	"golang.org/x/text/encoding/simplifiedchinese"
+	"golang.org/x/text/encoding/traditionalchinese"
+)
+
+func encode(t *testing.T, enc encoding.Encoding, s string) []byte {
+	t.Helper()
+	data, err := enc.NewEncoder().Bytes([]byte(s))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return data
+}
+
+func TestDecodeTitle(t *testing.T) {
+	page := func(meta, title string) string {
+		return "<html><head>" + meta + "<title>" + title + "</title></head><body>正文</body></html>"
+	}
+
+	tests := []struct {
+		name        string
+		body        []byte
+		contentType string
+		want        string
+	}{
+		{name: "UTF-8", body: []byte(page("", "管理后台")), contentType: "text/html", want: "管理后台"},
+		{name: "响应头 GBK", body: encode(t, simplifiedchinese.GBK, page("", "管理后台")), contentType: "text/html; charset=GBK", want: "管理后台"},
+		{name: "meta GB2312", body: encode(t, simplifiedchinese.GBK, page(`<meta http-equiv="Content-Type" content="text/html; charset=gb2312">`, "用户登录")), contentType: "text/html", want: "用户登录"},
+		{name: "meta charset Big5", body: encode(t, traditionalchinese.Big5, page(`<meta charset="big5">`, "繁體標題")), want: "繁體標題"},
+		{name: "meta Shift_JIS", body: encode(t, japanese.ShiftJIS, page(`<meta charset="Shift_JIS">`, "ログイン")), contentType: "text/html", want: "ログイン"},
+		{name: "无编码声明的 GBK", body: encode(t, simplifiedchinese.GBK, page("", "路由器设置")), want: "路由器设置"},
+		{name: "UTF-8 BOM", body: append([]byte("\xef\xbb\xbf"), page("", "带 BOM")...), contentType: "text/html; charset=gbk", want: "带 BOM"},
+		{name: "实体与空白", body: []byte(page("", "\n  A &amp; B\t&lt;C&gt;  ")), want: "A & B <C>"},
+		{name: "忽略 svg 中的 title", body: []byte(`<svg><title>图标</title></svg><title>页面</title>`), want: "页面"},
+		{name: "没有 title", body: []byte("<html><body>no title</body></html>"), want: ""},
+	}
+	for _, tt := range tests {
+		if got := extractTitle(decodeBody(tt.body, tt.contentType)); got != tt.want {
+			t.Errorf("%s: 标题为 %q，应为 %q", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestFetchResponseTitle(t *testing.T) {
+	body := encode(t, simplifiedchinese.GBK, `<html><head><meta charset="gbk"><title>设备管理</title></head><body>`+strings.Repeat("填充", 1000)+`</body></html>`)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/html")
+		w.Header().Set("Server", "test")
+		w.Write(body)
+	}))
+	defer server.Close()
+
+	result := NewResult(server.URL, server.URL)
+	FetchResponse(context.Background(), server.URL, &Options{Logger: NopLogger{}}, &result)
+	if result.StatusCode != 200 || result.Title != "设备管理" || result.Server != "test" {
+		t.Fatalf("结果错误: 状态码 %d，标题 %q，Server %q", result.StatusCode, result.Title, result.Server)
+	}
+	if !result.Success() || !result.BodyTruncated || len(result.BodyPreview) > bodyPreviewLimit || !strings.Contains(result.BodyPreview, "设备管理") {
+		t.Errorf("响应体预览应转换为 UTF-8 并截断: %d 字节，截断 %v", len(result.BodyPreview), result.BodyTruncated)
+	}
+}
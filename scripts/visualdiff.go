@@ -0,0 +1,141 @@
+package scripts
+
+import (
+	"fmt"
+	"image"
+	"image/color"
+	"image/draw"
+	"image/png"
+	"os"
+	"path/filepath"
+)
+
+// 截图比较的默认值
+const (
+	// DefaultVisualThreshold 为变化像素占比阈值，超过该值视为截图变化
+	DefaultVisualThreshold = 0.01
+	// DefaultPixelTolerance 为单个像素任一通道的差值容差，用于忽略抗锯齿、图片压缩等细微差异
+	DefaultPixelTolerance = 32
+)
+
+var overlayHighlight = color.RGBA{R: 255, A: 255}
+
+// compareScreenshots 逐像素比较两张截图，返回变化像素占比；尺寸不同时超出重叠区域的像素均视为变化。
+// overlay 为 true 时同时返回差异叠加图：以淡化的新截图为底，变化的像素标为红色
+func compareScreenshots(oldPath, newPath string, tolerance int, overlay bool) (float64, *image.RGBA, error) {
+	oldImg, err := decodePNG(oldPath)
+	if err != nil {
+		return 0, nil, err
+	}
+	newImg, err := decodePNG(newPath)
+	if err != nil {
+		return 0, nil, err
+	}
+
+	width := max(oldImg.Rect.Dx(), newImg.Rect.Dx())
+	height := max(oldImg.Rect.Dy(), newImg.Rect.Dy())
+	if width == 0 || height == 0 {
+		return 0, nil, nil
+	}
+
+	var out *image.RGBA
+	if overlay {
+		out = image.NewRGBA(image.Rect(0, 0, width, height))
+	}
+
+	changed := 0
+	for y := 0; y < height; y++ {
+		for x := 0; x < width; x++ {
+			oldPixel, oldOK := pixelAt(oldImg, x, y)
+			newPixel, newOK := pixelAt(newImg, x, y)
+			diff := oldOK != newOK || (oldOK && pixelDiffers(oldPixel, newPixel, tolerance))
+			if diff {
+				changed++
+			}
+			if out == nil {
+				continue
+			}
+			if diff {
+				out.SetRGBA(x, y, overlayHighlight)
+				continue
+			}
+			base := newPixel
+			if !newOK {
+				base = oldPixel
+			}
+			out.SetRGBA(x, y, fadePixel(base))
+		}
+	}
+	return float64(changed) / float64(width*height), out, nil
+}
+
+func decodePNG(path string) (*image.RGBA, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	img, err := png.Decode(file)
+	if err != nil {
+		return nil, fmt.Errorf("解析截图 %s 失败: %w", path, err)
+	}
+	return toRGBA(img), nil
+}
+
+// toRGBA 将图片转换为左上角位于原点的 RGBA 图片
+func toRGBA(img image.Image) *image.RGBA {
+	if rgba, ok := img.(*image.RGBA); ok && rgba.Rect.Min == (image.Point{}) {
+		return rgba
+	}
+	bounds := img.Bounds()
+	rgba := image.NewRGBA(image.Rect(0, 0, bounds.Dx(), bounds.Dy()))
+	draw.Draw(rgba, rgba.Rect, img, bounds.Min, draw.Src)
+	return rgba
+}
+
+func pixelAt(img *image.RGBA, x, y int) (color.RGBA, bool) {
+	if x >= img.Rect.Dx() || y >= img.Rect.Dy() {
+		return color.RGBA{}, false
+	}
+	return img.RGBAAt(x, y), true
+}
+
+func pixelDiffers(a, b color.RGBA, tolerance int) bool {
+	return absDiff(a.R, b.R) > tolerance || absDiff(a.G, b.G) > tolerance ||
+		absDiff(a.B, b.B) > tolerance || absDiff(a.A, b.A) > tolerance
+}
+
+func absDiff(a, b uint8) int {
+	if a > b {
+		return int(a - b)
+	}
+	return int(b - a)
+}
+
+// fadePixel 将像素转为灰度并淡化，使叠加图中的红色变化区域更醒目
+func fadePixel(c color.RGBA) color.RGBA {
+	gray := (299*int(c.R) + 587*int(c.G) + 114*int(c.B)) / 1000
+	v := uint8(255 - (255-gray)*3/10)
+	return color.RGBA{R: v, G: v, B: v, A: 255}
+}
+
+func writePNG(path string, img image.Image) error {
+	file, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	if err := png.Encode(file, img); err != nil {
+		file.Close()
+		return err
+	}
+	return file.Close()
+}
+
+// screenshotPath 返回结果截图在运行目录中的路径，结果没有截图时返回空字符串
+func screenshotPath(runDir string, result Result) string {
+	if result.Screenshot == "" {
+		return ""
+	}
+	return filepath.Join(runDir, filepath.FromSlash(result.Screenshot))
+}
@@ -0,0 +1,221 @@
+package sowhp
+
+import (
+	"Sowhp/scripts"
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+)
+
+type Client struct {
+	threads      int
+	aliveThreads int
+	noAlive      bool
+	name         string
+	logger       Logger
+	options      *scripts.Options
+}
+
+func New(opts Options) *Client {
+	threads := opts.Threads
+	if threads < 1 {
+		threads = defaultThreads
+	}
+	aliveThreads := opts.AliveThreads
+	if aliveThreads < 1 {
+		aliveThreads = defaultAliveThreads
+	}
+	if aliveThreads < threads {
+		aliveThreads = threads
+	}
+	logger := opts.Logger
+	if logger == nil {
+		logger = NopLogger{}
+	}
+
+	return &Client{
+		threads:      threads,
+		aliveThreads: aliveThreads,
+		noAlive:      opts.NoAlive || opts.Proxy != "",
+		name:         opts.Name,
+		logger:       logger,
+		options: &scripts.Options{
+			Scope:          opts.Scope,
+			CaptureBoth:    opts.CaptureBoth,
+			ProbeTimeout:   opts.ProbeTimeout,
+			AliveTimeout:   opts.AliveTimeout,
+			PageTimeout:    opts.PageTimeout,
+			HTTPTimeout:    opts.HTTPTimeout,
+			Retry:          opts.Retry,
+			Limiter:        opts.Limiter,
+			Headers:        opts.Headers,
+			Proxy:          opts.Proxy,
+			UserAgent:      opts.UserAgent,
+			ChromeFlags:    opts.ChromeFlags,
+			ViewportWidth:  opts.ViewportWidth,
+			ViewportHeight: opts.ViewportHeight,
+			Capturer:       opts.Capturer,
+			OutputDir:      opts.OutputDir,
+			ScreenshotName: opts.ScreenshotName,
+			Logger:         logger,
+		},
+	}
+}
+
+// Check 启动截图引擎并渲染本地测试页面，确认引擎可用；引擎无需检查（如 HTTPCapturer）时返回 nil
+func (c *Client) Check(ctx context.Context) *CheckResult {
+	result, ok := scripts.CheckCapturer(ctx, c.options)
+	if !ok {
+		return nil
+	}
+	return &result
+}
+
+// Capture 处理单个地址并返回第一个结果；处理失败时同时返回 Result 与 *Error，ctx 被取消时返回 ctx.Err()
+func (c *Client) Capture(ctx context.Context, url string) (*Result, error) {
+	if url == "" {
+		return nil, errors.New("URL不能为空")
+	}
+
+	results := c.capture(ctx, url)
+	result := results[0]
+	if ctx.Err() != nil && result.NotProcessed() {
+		return result, ctx.Err()
+	}
+	if !result.Success() {
+		return result, &Error{URL: result.URL, Class: result.ErrorClass, Message: result.Error}
+	}
+	return result, nil
+}
+
+// CaptureAll 并发处理 urls 中的地址，结果处理完成即发送；urls 关闭且全部处理完成后关闭返回的通道。
+// ctx 被取消后，剩余地址以 NOT_PROCESSED 结果返回，调用方应继续读取直到通道关闭
+func (c *Client) CaptureAll(ctx context.Context, urls <-chan string) <-chan *Result {
+	out := make(chan *Result, c.threads)
+	go func() {
+		defer close(out)
+		for results := range c.CaptureBatches(ctx, urls) {
+			for _, result := range results {
+				out <- result
+			}
+		}
+	}()
+	return out
+}
+
+// CaptureBatches 与 CaptureAll 相同，但每次发送同一输入地址的全部结果（同时截图 HTTP 与 HTTPS 时可能有多个）
+func (c *Client) CaptureBatches(ctx context.Context, urls <-chan string) <-chan []*Result {
+	aliveChan := make(chan scripts.AliveResult, c.threads*2)
+	resultChan := make(chan []*Result, c.threads*2)
+
+	var aliveWg sync.WaitGroup
+	for i := 0; i < c.aliveThreads; i++ {
+		aliveWg.Add(1)
+		go c.aliveWorker(ctx, &aliveWg, urls, aliveChan, resultChan)
+	}
+
+	var wg sync.WaitGroup
+	for i := 0; i < c.threads; i++ {
+		wg.Add(1)
+		go c.screenshotWorker(ctx, &wg, aliveChan, resultChan)
+	}
+
+	go func() {
+		aliveWg.Wait()
+		close(aliveChan)
+		wg.Wait()
+		close(resultChan)
+	}()
+
+	return resultChan
+}
+
+// capture 依次完成存活检测、协议探测与截图，始终返回至少一个结果
+func (c *Client) capture(ctx context.Context, url string) []*Result {
+	if ctx.Err() != nil {
+		return notProcessed(url)
+	}
+
+	alive := scripts.AliveResult{Target: url, Alive: true}
+	if !c.noAlive {
+		alive = scripts.CheckAlive(ctx, url, c.options)
+	}
+	if !alive.Alive {
+		return c.aliveFailed(ctx, alive)
+	}
+	return c.screenshot(ctx, alive)
+}
+
+func (c *Client) aliveFailed(ctx context.Context, alive scripts.AliveResult) []*Result {
+	if ctx.Err() != nil {
+		return notProcessed(alive.Target)
+	}
+
+	c.logger.Debug(fmt.Sprintf("%s 存活检测失败: %s %v", alive.Target, alive.Status, alive.Err))
+	result := scripts.NewResult(alive.Target, alive.Target)
+	result.Fail(alive.Status, fmt.Errorf("存活检测失败: %w", alive.Err))
+	result.Timings.AliveMs = alive.Elapsed.Milliseconds()
+	result.Timings.TotalMs = result.Timings.AliveMs
+	return []*Result{&result}
+}
+
+func (c *Client) screenshot(ctx context.Context, alive scripts.AliveResult) []*Result {
+	url := alive.Target
+	if ctx.Err() != nil {
+		return notProcessed(url)
+	}
+
+	var results []*Result
+	for _, result := range scripts.SmartScreenshot(ctx, url, c.name, c.options) {
+		result := result
+		result.Timings.AliveMs = alive.Elapsed.Milliseconds()
+		result.Timings.TotalMs += result.Timings.AliveMs
+		results = append(results, &result)
+	}
+
+	if len(results) == 0 && ctx.Err() != nil {
+		return notProcessed(url)
+	} else if len(results) == 0 {
+		result := scripts.NewResult(url, url)
+		result.Fail(ClassOutOfScope, errors.New("目标超出范围"))
+		results = append(results, &result)
+	}
+	return results
+}
+
+func (c *Client) aliveWorker(ctx context.Context, wg *sync.WaitGroup, urls <-chan string, aliveChan chan<- scripts.AliveResult, resultChan chan<- []*Result) {
+	defer wg.Done()
+
+	for url := range urls {
+		if ctx.Err() != nil {
+			resultChan <- notProcessed(url)
+			continue
+		}
+
+		if c.noAlive {
+			aliveChan <- scripts.AliveResult{Target: url, Alive: true}
+			continue
+		}
+
+		alive := scripts.CheckAlive(ctx, url, c.options)
+		if alive.Alive {
+			aliveChan <- alive
+			continue
+		}
+		resultChan <- c.aliveFailed(ctx, alive)
+	}
+}
+
+func (c *Client) screenshotWorker(ctx context.Context, wg *sync.WaitGroup, aliveChan <-chan scripts.AliveResult, resultChan chan<- []*Result) {
+	defer wg.Done()
+
+	for alive := range aliveChan {
+		resultChan <- c.screenshot(ctx, alive)
+	}
+}
+
+func notProcessed(url string) []*Result {
+	result := scripts.NotProcessedResult(url, url)
+	return []*Result{&result}
+}
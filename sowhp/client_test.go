@@ -0,0 +1,158 @@
+package sowhp
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// testRetry 为不等待的重试策略
+func testRetry(retries int) *RetryPolicy {
+	return &RetryPolicy{Retries: retries, Rules: map[string]int{}}
+}
+
+func captureAll(t *testing.T, client *Client, urls ...string) map[string]*Result {
+	t.Helper()
+	in := make(chan string)
+	go func() {
+		defer close(in)
+		for _, url := range urls {
+			in <- url
+		}
+	}()
+
+	results := make(map[string]*Result)
+	for result := range client.CaptureAll(context.Background(), in) {
+		if _, ok := results[result.Input]; ok {
+			t.Fatalf("%s 返回了多个结果", result.Input)
+		}
+		results[result.Input] = result
+	}
+	if len(results) != len(urls) {
+		t.Fatalf("结果数为 %d，应为 %d", len(results), len(urls))
+	}
+	return results
+}
+
+func TestCaptureAllRetries(t *testing.T) {
+	fake := &FakeCapturer{
+		Flaky: map[string]int{
+			"https://flaky.example":    2,
+			"https://tooflaky.example": 3,
+		},
+		Errors: map[string]string{
+			"https://dns.example":     ClassDNS,
+			"https://browser.example": ClassBrowser,
+		},
+	}
+	client := New(Options{Threads: 3, NoAlive: true, Capturer: fake, Retry: testRetry(2)})
+
+	results := captureAll(t, client, "ok.example", "flaky.example", "tooflaky.example", "dns.example", "browser.example")
+
+	for _, input := range []string{"ok.example", "flaky.example"} {
+		result := results[input]
+		if !result.Success() {
+			t.Errorf("%s 应成功: %s %s", input, result.ErrorClass, result.Error)
+		}
+		if result.URL != "https://"+input || result.Title != "Fake https://"+input {
+			t.Errorf("%s 的地址或标题错误: %s %q", input, result.URL, result.Title)
+		}
+		if len(result.Image) == 0 {
+			t.Errorf("%s 未设置 OutputDir 时应返回截图内容", input)
+		}
+	}
+
+	for input, class := range map[string]string{
+		"tooflaky.example": ClassTimeout,
+		"dns.example":      ClassDNS,
+		"browser.example":  ClassBrowser,
+	} {
+		if got := results[input].ErrorClass; got != class {
+			t.Errorf("%s 的错误类型为 %q，应为 %q", input, got, class)
+		}
+	}
+}
+
+func TestCaptureRetryRules(t *testing.T) {
+	fake := &FakeCapturer{Flaky: map[string]int{"https://flaky.example": 1}}
+	retry := testRetry(3)
+	retry.Rules[ClassTimeout] = 0
+	client := New(Options{NoAlive: true, Capturer: fake, Retry: retry})
+
+	result, err := client.Capture(context.Background(), "flaky.example")
+	var captureErr *Error
+	if !errors.As(err, &captureErr) || captureErr.Class != ClassTimeout {
+		t.Fatalf("TIMEOUT 设置为不重试时应返回 TIMEOUT 错误，实际为 %v", err)
+	}
+	if result.Success() {
+		t.Fatal("结果不应成功")
+	}
+
+	// 第二次访问时模拟超时次数已用完
+	if _, err := client.Capture(context.Background(), "flaky.example"); err != nil {
+		t.Fatalf("第二次访问应成功: %v", err)
+	}
+}
+
+func TestCaptureCreatesOutputDir(t *testing.T) {
+	dir := filepath.Join(t.TempDir(), "fresh", "shots")
+	client := New(Options{NoAlive: true, Capturer: &FakeCapturer{}, OutputDir: dir})
+
+	result, err := client.Capture(context.Background(), "https://example.com:8443/")
+	if err != nil {
+		t.Fatalf("截图失败: %v", err)
+	}
+	if result.Screenshot == "" || len(result.Image) != 0 {
+		t.Fatalf("设置 OutputDir 时应写入截图文件: %+v", result)
+	}
+	if _, err := os.Stat(filepath.Join(dir, filepath.FromSlash(result.Screenshot))); err != nil {
+		t.Fatalf("截图文件不存在: %v", err)
+	}
+}
+
+func TestCaptureAllCanceled(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	in := make(chan string, 2)
+	in <- "a.example"
+	in <- "b.example"
+	close(in)
+
+	client := New(Options{NoAlive: true, Capturer: &FakeCapturer{}})
+	count := 0
+	for result := range client.CaptureAll(ctx, in) {
+		count++
+		if !result.NotProcessed() {
+			t.Errorf("%s 应为 NOT_PROCESSED，实际为 %q", result.Input, result.ErrorClass)
+		}
+	}
+	if count != 2 {
+		t.Fatalf("结果数为 %d，应为 2", count)
+	}
+}
+
+func TestCaptureLongScreenshotName(t *testing.T) {
+	dir := t.TempDir()
+	client := New(Options{NoAlive: true, Capturer: &FakeCapturer{}, OutputDir: dir, ScreenshotName: "{host}_{path}"})
+
+	base := "https://example.com/" + strings.Repeat("segment/", 60)
+	names := make(map[string]bool)
+	for _, url := range []string{base + "a", base + "b"} {
+		result, err := client.Capture(context.Background(), url)
+		if err != nil {
+			t.Fatalf("长路径截图失败: %v", err)
+		}
+		name := filepath.Base(result.Screenshot)
+		if len(name) > 255 {
+			t.Errorf("截图文件名长度为 %d，超过 255", len(name))
+		}
+		names[name] = true
+	}
+	if len(names) != 2 {
+		t.Fatalf("不同地址截断后的文件名应不同: %v", names)
+	}
+}
@@ -0,0 +1,136 @@
+// Package sowhp 提供可嵌入其他 Go 程序的网站截图接口。
+//
+// 该包不会输出横幅、不会调用 os.Exit，只有设置 Options.OutputDir 时才会写入截图文件；
+// 日志通过 Options.Logger 输出，默认丢弃，不依赖进程全局的日志设置。
+package sowhp
+
+import (
+	"Sowhp/scripts"
+	"fmt"
+	"time"
+)
+
+type (
+	Result      = scripts.Result
+	Timings     = scripts.Timings
+	Scope       = scripts.Scope
+	RetryPolicy = scripts.RetryPolicy
+	RateLimiter = scripts.RateLimiter
+
+	Capturer       = scripts.Capturer
+	TargetResolver = scripts.TargetResolver
+	Checker        = scripts.Checker
+	CheckResult    = scripts.CheckResult
+	ChromeCapturer = scripts.ChromeCapturer
+	RemoteCapturer = scripts.RemoteCapturer
+	HTTPCapturer   = scripts.HTTPCapturer
+	FakeCapturer   = scripts.FakeCapturer
+	BrowserPool    = scripts.BrowserPool
+
+	Logger        = scripts.Logger
+	ConsoleLogger = scripts.ConsoleLogger
+	NopLogger     = scripts.NopLogger
+)
+
+// 错误类型，对应 Result.ErrorClass
+const (
+	ClassTimeout          = scripts.ClassTimeout
+	ClassDNS              = scripts.ClassDNS
+	ClassConnRefused      = scripts.ClassConnRefused
+	ClassConnReset        = scripts.ClassConnReset
+	ClassUnreachable      = scripts.ClassUnreachable
+	ClassEmptyResponse    = scripts.ClassEmptyResponse
+	ClassSSL              = scripts.ClassSSL
+	ClassTooManyRedirects = scripts.ClassTooManyRedirects
+	ClassBrowser          = scripts.ClassBrowser
+	ClassOutOfScope       = scripts.ClassOutOfScope
+	ClassNotProcessed     = scripts.ClassNotProcessed
+	ClassError            = scripts.ClassError
+)
+
+const (
+	defaultThreads      = 5
+	defaultAliveThreads = 50
+)
+
+type Options struct {
+	// Threads 为截图并发数，默认 5
+	Threads int
+	// AliveThreads 为 TCP 存活检测并发数，默认 50，小于 Threads 时使用 Threads
+	AliveThreads int
+	// NoAlive 跳过截图前的 TCP 存活检测
+	NoAlive bool
+
+	AliveTimeout time.Duration
+	ProbeTimeout time.Duration
+	PageTimeout  time.Duration
+	HTTPTimeout  time.Duration
+
+	// CaptureBoth 未指定协议的目标同时响应 HTTP 与 HTTPS 且内容不同时两者都截图
+	CaptureBoth bool
+	Scope       *Scope
+	Retry       *RetryPolicy
+	Limiter     *RateLimiter
+
+	// Headers 为附加到每个请求（包括浏览器访问）的请求头
+	Headers map[string]string
+	// Proxy 为 HTTP 请求与本地 Chrome 使用的代理；设置后不再进行 TCP 存活检测
+	Proxy string
+	// UserAgent 覆盖请求头与页面中的 navigator.userAgent，使用浏览器池时同样按每次截图生效
+	UserAgent string
+	// ChromeFlags 为启动本地 Chrome 时附加的命令行参数，值为空表示开关参数
+	ChromeFlags    map[string]string
+	ViewportWidth  int
+	ViewportHeight int
+
+	// Capturer 为截图引擎，为空时使用本地 Chrome（ChromeCapturer）
+	Capturer Capturer
+
+	// OutputDir 为截图保存目录（写入其下的 data 子目录），为空时截图只保存在 Result.Image 中
+	OutputDir string
+	// Name 为批次名称，对应截图命名模板中的 {run}
+	Name string
+	// ScreenshotName 为截图文件命名模板，支持 {host} {port} {scheme} {path} {hash} {date} {time} {index} {run}，
+	// 为空时使用 "{host}_{port}-{hash}"；文件名重复时自动追加序号
+	ScreenshotName string
+
+	// Logger 为日志输出，为空时丢弃日志；ConsoleLogger 以命令行格式输出，级别由 Sowhp/concert/logger 的 LogLevel 控制
+	Logger Logger
+}
+
+// Error 表示单个地址处理失败，详细信息见同时返回的 Result
+type Error struct {
+	URL     string
+	Class   string
+	Message string
+}
+
+func (e *Error) Error() string {
+	return fmt.Sprintf("%s %s: %s", e.URL, e.Class, e.Message)
+}
+
+func LoadScope(path string) (*Scope, error) {
+	return scripts.LoadScope(path)
+}
+
+func DefaultRetryPolicy() *RetryPolicy {
+	return scripts.DefaultRetryPolicy()
+}
+
+func ParseRetryRules(spec string) (map[string]int, error) {
+	return scripts.ParseRetryRules(spec)
+}
+
+func NewRateLimiter(rate float64, hostThreads int, hostDelay time.Duration) *RateLimiter {
+	return scripts.NewRateLimiter(rate, hostThreads, hostDelay)
+}
+
+func CanonicalizeURL(raw string) (string, error) {
+	return scripts.CanonicalizeURL(raw)
+}
+
+// NewBrowserPool 启动 size 个本地 Chrome 组成浏览器池，每个浏览器最多同时打开 tabs 个标签页；
+// 代理、User-Agent 与 Chrome 参数取自 opts，可将返回值作为 Options.Capturer 在多个 Client 间共享，使用完毕后调用 Close
+func NewBrowserPool(size, tabs int, opts Options) (*BrowserPool, error) {
+	return scripts.NewBrowserPool(size, tabs, New(opts).options)
+}